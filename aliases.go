@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// An alias renames the binaries whose path matches re in diagrams.
+type alias struct {
+	re *regexp.Regexp
+
+	// label is the name of the node standing for the matching binaries,
+	// where $1 and the like expand to the submatches of re.
+	label string
+}
+
+// aliases renames binaries after the first alias matching them.
+type aliases []alias
+
+// readAliases reads the aliases listed in path, one per line as a regular
+// expression matching whole paths followed by a label, which may contain
+// spaces.  Empty lines and lines starting with # are skipped.
+//
+//	.*/(Qt[A-Za-z]*)\.framework/.*  Qt
+func readAliases(path string) (aliases, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var as aliases
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		where := fmt.Sprintf("%s:%d", path, n)
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s: expected a regular expression followed by a label", where)
+		}
+		re, err := regexp.Compile("^(?:" + fields[0] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", where, err)
+		}
+		label := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		as = append(as, alias{re: re, label: label})
+	}
+	return as, s.Err()
+}
+
+// lookup returns the label of the first alias matching path, if any.
+func (as aliases) lookup(path string) (string, bool) {
+	for _, a := range as {
+		if m := a.re.FindStringSubmatchIndex(path); m != nil {
+			return string(a.re.ExpandString(nil, a.label, path, m)), true
+		}
+	}
+	return "", false
+}
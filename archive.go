@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// manifestName is the name of the manifest in archives.
+const manifestName = "manifest.json"
+
+// archiveManifest describes the content of an archive.  It is a JSON
+// snapshot of the walked graph that -json input accepts, along with the list
+// of archived files.
+type archiveManifest struct {
+	jsonGraph
+	Files []archivedFile `json:"files"`
+}
+
+// archivedFile is a binary stored in an archive.
+type archivedFile struct {
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// archiveMain implements the archive subcommand, writing the binaries given
+// after the archive and the dependencies of theirs that do not ship with macOS
+// into a zip file, a tarball or a gzipped tarball as named.  Binaries are
+// stored under their absolute paths, without the leading slash, after a
+// manifest.
+func archiveMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) < 2 {
+		return usagef("usage: totool archive out.zip|out.tar|out.tar.gz file...")
+	}
+	out := args[0]
+	var newWriter func(w io.Writer) archiveWriter
+	switch {
+	case strings.HasSuffix(out, ".zip"):
+		newWriter = newZipWriter
+	case strings.HasSuffix(out, ".tar"):
+		newWriter = newTarWriter
+	case strings.HasSuffix(out, ".tar.gz") || strings.HasSuffix(out, ".tgz"):
+		newWriter = newTarGzWriter
+	default:
+		return fmt.Errorf("%s: unknown archive format, want .zip, .tar, .tar.gz or .tgz", out)
+	}
+
+	bins, deps, err := walkClosure(ctx, args[1:], opts)
+	if err != nil {
+		return err
+	}
+	files, err := closureFiles(bins, false)
+	if err != nil {
+		return err
+	}
+
+	var m archiveManifest
+	for _, d := range bins {
+		if d.Depth == 0 {
+			m.Roots = append(m.Roots, d.Bin)
+		}
+		m.Nodes = append(m.Nodes, jsonNode{Path: d.Bin, Info: d.Info, Depth: d.Depth, Class: d.Origin.String(), Parent: d.Parent, Truncated: d.Truncated})
+		for _, dep := range deps[d.Bin] {
+			m.Edges = append(m.Edges, jsonEdge{From: d.Bin, To: dep.Bin})
+		}
+	}
+	for _, bin := range files {
+		h, err := totool.SHA256(bin)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, archivedFile{Path: bin, Name: strings.TrimPrefix(bin, "/"), SHA256: h})
+	}
+	manifest, err := json.MarshalIndent(&m, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	err = writeArchive(newWriter(f), append(manifest, '\n'), m.Files)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out)
+		return fmt.Errorf("%s: %v", out, err)
+	}
+	fmt.Printf("%s: %d binaries\n", out, len(files))
+	return nil
+}
+
+// writeArchive writes manifest then files with w and closes it unless it
+// fails.
+func writeArchive(w archiveWriter, manifest []byte, files []archivedFile) error {
+	if err := w.add(manifestName, 0644, int64(len(manifest)), strings.NewReader(string(manifest))); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := addFile(w, file); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// addFile adds file to w.
+func addFile(w archiveWriter, file archivedFile) error {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return w.add(file.Name, fi.Mode().Perm(), fi.Size(), f)
+}
+
+// An archiveWriter adds files to an archive.
+type archiveWriter interface {
+	// add adds a file called name with permissions perm and size bytes
+	// read from r.
+	add(name string, perm os.FileMode, size int64, r io.Reader) error
+
+	// Close finishes writing the archive, without closing the underlying
+	// writer.
+	Close() error
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func newZipWriter(w io.Writer) archiveWriter {
+	return zipWriter{zip.NewWriter(w)}
+}
+
+func (w zipWriter) add(name string, perm os.FileMode, size int64, r io.Reader) error {
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	h.SetMode(perm)
+	fw, err := w.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+type tarWriter struct {
+	*tar.Writer
+
+	// gz compresses the tarball if not nil.
+	gz *gzip.Writer
+}
+
+func newTarWriter(w io.Writer) archiveWriter {
+	return tarWriter{Writer: tar.NewWriter(w)}
+}
+
+func newTarGzWriter(w io.Writer) archiveWriter {
+	gz := gzip.NewWriter(w)
+	return tarWriter{Writer: tar.NewWriter(gz), gz: gz}
+}
+
+func (w tarWriter) add(name string, perm os.FileMode, size int64, r io.Reader) error {
+	if err := w.WriteHeader(&tar.Header{Name: name, Mode: int64(perm), Size: size, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w.Writer, r, size)
+	return err
+}
+
+func (w tarWriter) Close() error {
+	err := w.Writer.Close()
+	if w.gz != nil {
+		if gerr := w.gz.Close(); err == nil {
+			err = gerr
+		}
+	}
+	return err
+}
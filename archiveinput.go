@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// archiveExts lists the extensions of the archives whose binaries
+// expandBundles walks.
+var archiveExts = []string{".xcarchive", ".ipa", ".zip", ".dmg", ".pkg"}
+
+// isArchiveInput reports whether path is an archive whose binaries
+// expandBundles walks: an .xcarchive directory, an .ipa, .zip or .dmg file
+// or a .pkg installer.
+func isArchiveInput(path string) bool {
+	ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(path, "/")))
+	return containsString(archiveExts, ext)
+}
+
+// containsBundle reports whether any of args is an app bundle or an archive
+// input, which expandBundles expands.
+func containsBundle(args []string) bool {
+	for _, arg := range args {
+		if isArchiveInput(arg) || totool.IsAppBundle(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBundles replaces the app bundles among args with their BundleRoots and
+// the archives with the binaries they hold, extracting .ipa and .zip files below tmp, mounting .dmg files there
+// with hdiutil and expanding the payloads of .pkg installers there with
+// pkgutil.  It also returns the function turning the paths of these binaries
+// into paths relative to their archive, prefixed with the archive and a
+// colon, and the function unmounting disk images, to call once done.
+//
+//	/tmp/x/Payload/A.app/A -> A.ipa:Payload/A.app/A
+func expandBundles(ctx context.Context, args []string, tmp string, skipped *[]totool.SkippedFile) (bins []string, relabel func(string) string, unmount func(), err error) {
+	var mounted []string
+	detach := func() {
+		for _, dir := range mounted {
+			if out, err := exec.Command("hdiutil", "detach", "-quiet", dir).CombinedOutput(); err != nil {
+				log.Printf("cannot unmount %s: %v: %s", dir, err, bytes.TrimSpace(out))
+			}
+		}
+	}
+	defer func() {
+		if err != nil {
+			detach()
+		}
+	}()
+	prefixes := make(map[string]string)
+	for i, arg := range args {
+		if totool.IsAppBundle(arg) {
+			roots, left, err := totool.BundleRootsSkipped(arg)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			bins = append(bins, roots...)
+			*skipped = append(*skipped, left...)
+			continue
+		}
+		if !isArchiveInput(arg) {
+			bins = append(bins, arg)
+			continue
+		}
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fi, err := os.Stat(dir)
+		if os.IsNotExist(err) {
+			return nil, nil, nil, fmt.Errorf("%s: %w", arg, totool.ErrMissingFile)
+		} else if err != nil {
+			return nil, nil, nil, err
+		}
+		if !fi.IsDir() {
+			dir = filepath.Join(tmp, fmt.Sprintf("%d-%s", i, filepath.Base(arg)))
+			switch strings.ToLower(filepath.Ext(arg)) {
+			case ".dmg":
+				err = runQuiet(ctx, "hdiutil", "attach", "-readonly", "-nobrowse", "-noautoopen", "-mountpoint", dir, arg)
+				if err == nil {
+					mounted = append(mounted, dir)
+				}
+			case ".pkg":
+				// pkgutil creates the directory itself.
+				err = runQuiet(ctx, "pkgutil", "--expand-full", arg, dir)
+			default:
+				err = extractZip(arg, dir)
+			}
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("%s: %v", arg, err)
+			}
+		}
+		prefixes[dir+"/"] = strings.TrimSuffix(arg, "/") + ":"
+		found, err := archiveBins(dir)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %v", arg, err)
+		}
+		if len(found) == 0 {
+			return nil, nil, nil, fmt.Errorf("%s: no binaries in archive", arg)
+		}
+		bins = append(bins, found...)
+	}
+	relabel = func(path string) string {
+		for prefix, label := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return label + strings.TrimPrefix(path, prefix)
+			}
+		}
+		return path
+	}
+	return bins, relabel, detach, nil
+}
+
+// runQuiet runs name with args, returning what it printed as the error if
+// it fails.
+func runQuiet(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if out := bytes.TrimSpace(out); len(out) > 0 {
+			return fmt.Errorf("%s: %s", name, out)
+		}
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// archiveBins returns the binaries below dir, leaving out debug symbols and
+// the resource forks zip files made on macOS hold.
+func archiveBins(dir string) ([]string, error) {
+	var bins []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && (strings.HasSuffix(path, ".dSYM") || fi.Name() == "__MACOSX") {
+			return filepath.SkipDir
+		}
+		if fi.Mode().IsRegular() {
+			if _, err := totool.DetectFormat(path); err == nil {
+				bins = append(bins, path)
+			}
+		}
+		return nil
+	})
+	return bins, err
+}
+
+// extractZip extracts the zip file path into dir, symbolic links included.
+// Paths are resolved within dir.
+func extractZip(path, dir string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		name, ok := layerPath(f.Name)
+		if !ok {
+			continue
+		}
+		parent, ok := totool.InRoot(dir, filepath.Join(dir, filepath.Dir(name)))
+		if !ok {
+			log.Printf("%s: %s: skipped, too many levels of symbolic links", path, name)
+			continue
+		}
+		dest := filepath.Join(parent, filepath.Base(name))
+		mode := f.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(dest, mode.Perm()|0700); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return err
+		}
+		if err := extractZipFile(f, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile writes f, a regular file or a symbolic link, at dest.
+func extractZipFile(f *zip.File, dest string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	os.RemoveAll(dest)
+	if f.Mode()&os.ModeSymlink != 0 {
+		target, err := ioutil.ReadAll(io.LimitReader(r, 4096))
+		if err != nil {
+			return err
+		}
+		return os.Symlink(string(target), dest)
+	}
+	return writeFileFrom(dest, f.Mode().Perm()|0600, r)
+}
+
+// relabelPrinter prints the binaries its printer prints under the names
+// relabel gives them.
+type relabelPrinter struct {
+	pt      totool.Printer
+	relabel func(string) string
+}
+
+func (p relabelPrinter) PrintPrologue() { p.pt.PrintPrologue() }
+func (p relabelPrinter) PrintEpilogue() { p.pt.PrintEpilogue() }
+
+func (p relabelPrinter) PrintRootBin(bin string) { p.pt.PrintRootBin(p.relabel(bin)) }
+
+func (p relabelPrinter) PrintDepBin(d *totool.Dependency) {
+	c := *d
+	c.Bin, c.Parent = p.relabel(d.Bin), p.relabel(d.Parent)
+	p.pt.PrintDepBin(&c)
+}
+
+func (p relabelPrinter) PrintDep(from, to string) { p.pt.PrintDep(p.relabel(from), p.relabel(to)) }
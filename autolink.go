@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// autolinkMain implements the autolink subcommand, printing the libraries and
+// frameworks the object files given on their own or in static libraries make
+// the linker add, each one followed by the object files asking for it, so that
+// what a link pulls in is known before it happens.
+func autolinkMain(args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: totool autolink file.o|lib.a...")
+	}
+	for _, arg := range args {
+		links, err := totool.AutoLinks(arg)
+		if err != nil {
+			return fmt.Errorf("%s: %v", arg, err)
+		}
+		// opts lists the options in the order first found and by the
+		// members asking for each one.
+		var opts []string
+		by := make(map[string][]string)
+		seen := make(map[string]bool)
+		for _, l := range links {
+			for _, opt := range l.Options {
+				if !seen[opt] {
+					seen[opt] = true
+					opts = append(opts, opt)
+				}
+				if l.Member != "" && !containsString(by[opt], l.Member) {
+					by[opt] = append(by[opt], l.Member)
+				}
+			}
+		}
+		fmt.Printf("%s:\n", arg)
+		if len(opts) == 0 {
+			fmt.Printf("\tno auto-link directives\n")
+		}
+		for _, opt := range opts {
+			if len(by[opt]) > 0 {
+				fmt.Printf("\t%s (%s)\n", opt, strings.Join(by[opt], ", "))
+			} else {
+				fmt.Printf("\t%s\n", opt)
+			}
+		}
+	}
+	return nil
+}
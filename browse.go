@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// browseMain implements the browse subcommand, letting users explore on the
+// terminal the graph of a root, walked once: expanding and collapsing
+// dependencies, searching for libraries and listing what pulls one in.
+func browseMain(ctx context.Context, args []string, opts totool.Options, colorMode string) error {
+	if len(args) != 1 {
+		return usagef("usage: totool browse file|graph.json")
+	}
+	g, err := loadGraph(ctx, args[0], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("browse needs a terminal: %v", err)
+	}
+	defer tty.Close()
+	color, err := useColor(colorMode, tty)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	restore, err := rawMode(tty)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	b := &browser{g: g, tty: tty, w: bufio.NewWriter(tty), color: color, expanded: make(map[string]bool)}
+	for _, root := range g.Roots() {
+		b.expanded[root] = true
+	}
+	b.layout()
+	return b.run()
+}
+
+// rawMode puts tty in raw mode with stty, switching to the alternate screen,
+// and returns the function restoring it.
+func rawMode(tty *os.File) (restore func(), err error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read terminal settings: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("cannot set terminal in raw mode: %v", err)
+	}
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	return func() {
+		fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+		stty(saved)
+	}, nil
+}
+
+// A browser is the state of the browse subcommand.
+type browser struct {
+	g     *totool.Graph
+	tty   *os.File
+	w     *bufio.Writer
+	color bool
+
+	// expanded records the expanded rows by key.
+	expanded map[string]bool
+
+	// rows lists the visible rows of the tree, cur being selected and top
+	// shown first.
+	rows     []browseRow
+	cur, top int
+
+	// of, when set, is the binary whose dependents lists in place of the
+	// tree, sel being selected.
+	of         string
+	dependents []string
+	sel        int
+
+	// query is the last search and status the message of the bottom line.
+	query, status string
+}
+
+// A browseRow is a binary shown in the tree, under the chain of binaries
+// depending on one another from a root.
+type browseRow struct {
+	bin string
+
+	// key is the chain leading to bin, paths separated by NUL bytes.
+	key   string
+	depth int
+
+	// deps is how many direct dependencies bin has and cycle is set when
+	// bin is already in the chain, which is then not expanded.
+	deps  int
+	cycle bool
+}
+
+// browseHelp is the bottom line of the browser.
+const browseHelp = "arrows/hjkl move, enter expand/collapse, / search, n next, w dependents, q quit"
+
+// layout computes the visible rows from what is expanded.
+func (b *browser) layout() {
+	b.rows = b.rows[:0]
+	var visit func(chain []string)
+	visit = func(chain []string) {
+		bin := chain[len(chain)-1]
+		r := browseRow{bin: bin, key: strings.Join(chain, "\x00"), depth: len(chain) - 1}
+		deps := b.g.Dependencies(bin)
+		r.deps = len(deps)
+		for _, c := range chain[:len(chain)-1] {
+			r.cycle = r.cycle || c == bin
+		}
+		b.rows = append(b.rows, r)
+		if r.cycle || !b.expanded[r.key] {
+			return
+		}
+		for _, d := range deps {
+			visit(append(chain[:len(chain):len(chain)], d))
+		}
+	}
+	for _, root := range b.g.Roots() {
+		visit([]string{root})
+	}
+	if b.cur >= len(b.rows) {
+		b.cur = len(b.rows) - 1
+	}
+}
+
+// reveal expands the shortest chain from a root to bin and selects bin.
+func (b *browser) reveal(bin string) {
+	chain := b.g.Chain(bin)
+	if len(chain) == 0 {
+		return
+	}
+	for i := 1; i < len(chain); i++ {
+		b.expanded[strings.Join(chain[:i], "\x00")] = true
+	}
+	b.layout()
+	key := strings.Join(chain, "\x00")
+	for i, r := range b.rows {
+		if r.key == key {
+			b.cur = i
+		}
+	}
+}
+
+// run handles keys until the user quits.
+func (b *browser) run() error {
+	buf := make([]byte, 16)
+	for {
+		b.render()
+		n, err := b.tty.Read(buf)
+		if err != nil {
+			return err
+		}
+		b.status = ""
+		key := string(buf[:n])
+		if key == "q" || key == "\x03" {
+			if b.of == "" || key == "\x03" {
+				return nil
+			}
+			b.of = ""
+			continue
+		}
+		if b.of != "" {
+			b.dependentsKey(key)
+		} else {
+			b.treeKey(key)
+		}
+	}
+}
+
+// treeKey handles key while the tree is shown.
+func (b *browser) treeKey(key string) {
+	r := b.rows[b.cur]
+	switch key {
+	case "\x1b[A", "k":
+		b.move(-1)
+	case "\x1b[B", "j":
+		b.move(1)
+	case "\x1b[5~":
+		b.move(-b.height())
+	case "\x1b[6~", " ":
+		b.move(b.height())
+	case "\x1b[C", "l":
+		if r.deps > 0 && !r.cycle && !b.expanded[r.key] {
+			b.expanded[r.key] = true
+			b.layout()
+		} else if b.expanded[r.key] && !r.cycle {
+			b.move(1)
+		}
+	case "\x1b[D", "h":
+		if b.expanded[r.key] && !r.cycle {
+			delete(b.expanded, r.key)
+			b.layout()
+			break
+		}
+		for i := b.cur - 1; i >= 0; i-- {
+			if b.rows[i].depth < r.depth {
+				b.cur = i
+				break
+			}
+		}
+	case "\r", "\n":
+		if r.deps > 0 && !r.cycle {
+			b.expanded[r.key] = !b.expanded[r.key]
+			b.layout()
+		}
+	case "/":
+		if q, ok := b.prompt("/"); ok && q != "" {
+			b.query = q
+			b.search(q, "")
+		}
+	case "n":
+		if b.query == "" {
+			b.status = "no search yet"
+			break
+		}
+		b.search(b.query, r.bin)
+	case "w":
+		b.of, b.dependents, b.sel = r.bin, b.g.Dependents(r.bin), 0
+		sort.Strings(b.dependents)
+	}
+}
+
+// dependentsKey handles key while the dependents of a binary are listed.
+func (b *browser) dependentsKey(key string) {
+	switch key {
+	case "\x1b[A", "k":
+		if b.sel > 0 {
+			b.sel--
+		}
+	case "\x1b[B", "j":
+		if b.sel < len(b.dependents)-1 {
+			b.sel++
+		}
+	case "\x1b", "\x1b[D", "h":
+		b.of = ""
+	case "\r", "\n", "\x1b[C", "l":
+		if len(b.dependents) > 0 {
+			b.reveal(b.dependents[b.sel])
+		}
+		b.of = ""
+	}
+}
+
+// search selects the first binary whose path contains q, after the binary
+// after, if set, wrapping around.
+func (b *browser) search(q, after string) {
+	bins := b.g.Bins()
+	start := 0
+	for i, bin := range bins {
+		if bin == after {
+			start = i + 1
+		}
+	}
+	lower := strings.ToLower(q)
+	for i := range bins {
+		bin := bins[(start+i)%len(bins)]
+		if strings.Contains(strings.ToLower(bin), lower) {
+			b.reveal(bin)
+			return
+		}
+	}
+	b.status = fmt.Sprintf("%s: not found", q)
+}
+
+// prompt reads a line after prefix on the bottom line.  It returns false if
+// the user escapes.
+func (b *browser) prompt(prefix string) (string, bool) {
+	var line []byte
+	buf := make([]byte, 16)
+	for {
+		b.status = prefix + string(line)
+		b.render()
+		n, err := b.tty.Read(buf)
+		if err != nil {
+			return "", false
+		}
+		switch c := buf[0]; {
+		case c == '\r' || c == '\n':
+			b.status = ""
+			return string(line), true
+		case c == 0x1b || c == 0x03:
+			b.status = ""
+			return "", false
+		case c == 0x7f || c == 0x08:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case c >= ' ':
+			line = append(line, buf[:n]...)
+		}
+	}
+}
+
+// move moves the selection by n rows.
+func (b *browser) move(n int) {
+	b.cur += n
+	if b.cur >= len(b.rows) {
+		b.cur = len(b.rows) - 1
+	}
+	if b.cur < 0 {
+		b.cur = 0
+	}
+}
+
+// size returns the size of the terminal, defaulting to 24x80.
+func (b *browser) size() (rows, cols int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = b.tty
+	out, err := cmd.Output()
+	if err != nil {
+		return 24, 80
+	}
+	if _, err := fmt.Sscan(string(out), &rows, &cols); err != nil || rows < 2 || cols < 1 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// height returns how many rows of the tree fit on the terminal.
+func (b *browser) height() int {
+	rows, _ := b.size()
+	return rows - 1
+}
+
+// render draws the tree or the dependents and the bottom line.
+func (b *browser) render() {
+	rows, cols := b.size()
+	height := rows - 1
+	fmt.Fprint(b.w, "\x1b[H\x1b[2J")
+	line := func(s, style string, selected bool) {
+		if r := []rune(s); len(r) > cols {
+			s = string(r[:cols])
+		}
+		if !b.color {
+			style = ""
+		}
+		if selected {
+			style = ansiReverse + style
+		}
+		fmt.Fprintf(b.w, "%s\r\n", paint(style != "", style, s))
+	}
+	if b.of != "" {
+		line(fmt.Sprintf("%d binaries depend on %s:", len(b.dependents), b.of), ansiBold, false)
+		top := 0
+		if b.sel >= height-1 {
+			top = b.sel - height + 2
+		}
+		for i := top; i < len(b.dependents) && i-top < height-1; i++ {
+			line("  "+b.dependents[i], "", i == b.sel)
+		}
+	} else {
+		if b.cur < b.top {
+			b.top = b.cur
+		} else if b.cur >= b.top+height {
+			b.top = b.cur - height + 1
+		}
+		for i := b.top; i < len(b.rows) && i-b.top < height; i++ {
+			s, style := b.rowText(b.rows[i])
+			line(s, style, i == b.cur)
+		}
+	}
+	status := b.status
+	if status == "" {
+		status = browseHelp
+	}
+	fmt.Fprintf(b.w, "\x1b[%d;1H%s", rows, paint(b.color, ansiDim, status))
+	b.w.Flush()
+}
+
+// rowText formats r and returns its style, roots in bold and dependencies as
+// in the text format.
+func (b *browser) rowText(r browseRow) (string, string) {
+	marker := "  "
+	switch {
+	case r.cycle:
+		marker = "↺ "
+	case b.expanded[r.key]:
+		marker = "▾ "
+	case r.deps > 0:
+		marker = "▸ "
+	}
+	name, style := r.bin, ansiBold
+	if r.depth > 0 {
+		name = filepath.Base(r.bin) + "  " + filepath.Dir(r.bin)
+		n, _ := b.g.Node(r.bin)
+		style = binStyle(&totool.Dependency{Bin: r.bin, Origin: n.Origin, Truncated: n.Truncated})
+	}
+	s := strings.Repeat("  ", r.depth) + marker + name
+	if r.deps > 0 && !b.expanded[r.key] && !r.cycle {
+		s += fmt.Sprintf(" (%d)", r.deps)
+	}
+	return s, style
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// bundleMain implements the bundle subcommand, copying the dependencies of the
+// binaries of an app bundle that neither ship with macOS nor live in the
+// bundle into its Contents/Frameworks directory.  Frameworks are copied whole.
+// Binaries are then made to refer to the copies through @rpath, executables
+// searching Contents/Frameworks, those of Contents/MacOS through
+// @executable_path and others through @loader_path, and modified binaries are
+// signed ad hoc.
+// The bundle is then signed inside out with identity, or the codesign commands
+// doing it are printed without identity.  With dryRun set, what would be done
+// is only printed, then summed up, without touching anything.
+func bundleMain(ctx context.Context, args []string, opts totool.Options, dryRun bool, identity string) error {
+	if len(args) != 1 {
+		return usagef("usage: totool bundle app")
+	}
+	app, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	macOS := filepath.Join(app, "Contents", "MacOS")
+	if fi, err := os.Stat(macOS); err != nil || !fi.IsDir() {
+		return fmt.Errorf("%s: not an app bundle", app)
+	}
+	frameworks := filepath.Join(app, "Contents", "Frameworks")
+	roots, err := totool.ScanDir(app)
+	if err != nil {
+		return err
+	}
+
+	// deps lists the dependencies recorded by each binary, under the install
+	// names to change.
+	deps := make(map[string][]totool.Dependency)
+	seen := make(map[[2]string]bool)
+	vopts := opts
+	vopts.Filter = func(d *totool.Dependency) bool {
+		if opts.Filter != nil && !opts.Filter(d) {
+			return false
+		}
+		if k := [2]string{d.Parent, d.Name}; !seen[k] {
+			seen[k] = true
+			deps[d.Parent] = append(deps[d.Parent], *d)
+		}
+		return true
+	}
+	var missing []string
+	for _, root := range roots {
+		_, err := totool.VisitContext(ctx, root, &totool.Visitor{
+			OnError: func(bin string, err error) error {
+				if ctx.Err() != nil {
+					return err
+				}
+				if errors.Is(err, totool.ErrMissingFile) && !totool.IsSystemBin(bin) {
+					missing = append(missing, bin)
+				}
+				return nil
+			},
+		}, vopts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", root, err)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: cannot bundle %s", totool.ErrMissingFile, strings.Join(missing, ", "))
+	}
+
+	// copies maps the binaries to bundle to their copies, dirs the
+	// directories to copy, frameworks or single libraries, to theirs.
+	copies := make(map[string]string)
+	dirs := make(map[string]string)
+	for _, ds := range deps {
+		for _, d := range ds {
+			if _, ok := copies[d.Bin]; ok || strings.HasPrefix(d.Bin, app+"/") || totool.IsSystemBin(d.Bin) {
+				continue
+			}
+			// Absent weak dependencies are left for the loader to skip.
+			if !fileExists(d.Bin) {
+				continue
+			}
+			src := d.Bin
+			if i := strings.LastIndex(d.Bin, ".framework/"); i >= 0 {
+				src = d.Bin[:i+len(".framework")]
+			}
+			dest := filepath.Join(frameworks, filepath.Base(src))
+			if prev, ok := dirs[src]; ok {
+				dest = prev
+			} else {
+				for _, other := range dirs {
+					if other == dest {
+						return fmt.Errorf("cannot bundle both %s and another %s", src, filepath.Base(src))
+					}
+				}
+				if fi, err := os.Stat(dest); err == nil {
+					if sfi, err := os.Stat(src); err != nil || !os.SameFile(fi, sfi) {
+						return fmt.Errorf("cannot bundle %s, the bundle already has another %s", src, dest)
+					}
+				}
+				dirs[src] = dest
+			}
+			copies[d.Bin] = dest + strings.TrimPrefix(d.Bin, src)
+		}
+	}
+
+	srcs := make([]string, 0, len(dirs))
+	for src := range dirs {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	var files int
+	var size int64
+	for _, src := range srcs {
+		fmt.Printf("copy %s to %s\n", src, dirs[src])
+		n, s, err := treeSize(src)
+		if err != nil {
+			return err
+		}
+		files += n
+		size += s
+		if dryRun {
+			continue
+		}
+		if err := os.MkdirAll(frameworks, 0755); err != nil {
+			return err
+		}
+		if err := copyTree(src, dirs[src]); err != nil {
+			return err
+		}
+	}
+
+	// rpathName returns how binaries should refer to dup.
+	rpathName := func(dup string) string {
+		return "@rpath/" + strings.TrimPrefix(dup, frameworks+"/")
+	}
+	p := newPatcher(ctx, dryRun, false)
+	bins := make([]string, 0, len(deps)+len(copies))
+	for bin := range deps {
+		bins = append(bins, bin)
+	}
+	for bin := range copies {
+		if _, ok := deps[bin]; !ok {
+			bins = append(bins, bin)
+		}
+	}
+	sort.Strings(bins)
+	for _, bin := range bins {
+		target := bin
+		if dup, ok := copies[bin]; ok {
+			target = dup
+			if err := p.setInstallID(target, rpathName(dup)); err != nil {
+				return err
+			}
+		} else if strings.HasPrefix(bin, macOS+"/") {
+			if err := p.addRpath(target, frameworksRpath); err != nil {
+				return err
+			}
+		}
+		rewritten := false
+		for _, d := range deps[bin] {
+			dup, ok := copies[d.Bin]
+			if !ok || d.Name == rpathName(dup) {
+				continue
+			}
+			if err := p.changeInstallName(target, d.Name, rpathName(dup)); err != nil {
+				return err
+			}
+			rewritten = true
+		}
+		// Helpers, XPC services and login items run as processes of their
+		// own, with run paths of their own.
+		if _, ok := copies[bin]; !ok && rewritten && !strings.HasPrefix(bin, macOS+"/") && totool.IsExecutable(bin) {
+			rel, err := filepath.Rel(filepath.Dir(bin), frameworks)
+			if err != nil {
+				return err
+			}
+			if err := p.addRpath(target, "@loader_path/"+rel); err != nil {
+				return err
+			}
+		}
+	}
+
+	n, err := p.sign()
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("dry run, nothing changed: %d files (%d bytes) to copy, %s\n", files, size, p.summary())
+	} else {
+		fmt.Printf("%d files (%d bytes) copied, %s\n", files, size, p.summary())
+	}
+	if n == 0 {
+		return nil
+	}
+	// Copies only exist once made.
+	var extra []string
+	if dryRun {
+		for _, src := range srcs {
+			extra = append(extra, dirs[src])
+		}
+	}
+	return signBundle(ctx, app, extra, identity, dryRun)
+}
+
+// treeSize returns how many regular files src, a file or a directory, holds
+// and their total size.
+func treeSize(src string) (files int, size int64, err error) {
+	err = filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			files++
+			size += fi.Size()
+		}
+		return err
+	})
+	return files, size, err
+}
+
+// frameworksRpath is the run path search path of executables of app bundles
+// leading to the Contents/Frameworks directory.
+const frameworksRpath = "@executable_path/../Frameworks"
+
+// copyTree copies src, a file or a directory, to dest, preserving symbolic
+// links.  Copied files are made writable by their owner for install names to
+// be changed.
+func copyTree(src, dest string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		to := dest + strings.TrimPrefix(path, src)
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(to)
+			return os.Symlink(link, to)
+		case fi.IsDir():
+			return os.MkdirAll(to, fi.Mode().Perm()|0700)
+		default:
+			return copyFile(path, to, fi.Mode().Perm()|0200)
+		}
+	})
+}
+
+// copyFile copies the contents of src to dest, created with mode perm.
+func copyFile(src, dest string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// bundleDiff lists the differences between the dependencies of two releases
+// of an app bundle, binaries of the bundles being identified relative to them.
+type bundleDiff struct {
+	added, removed []bundleBin
+	changed        []versionChange
+	relocated      []relocation
+}
+
+// bundleBin is a binary of the graph of a bundle.
+type bundleBin struct {
+	key, version string
+
+	// by is the first binary depending on it, if any.
+	by string
+}
+
+// relocation is a dependency found at another path in the new release.
+type relocation struct {
+	old, new bundleBin
+}
+
+// diffBundleMain implements the diff-bundle subcommand, printing what changed
+// in the dependencies of the main executable and the embedded binaries of an
+// app bundle from one release to the next.
+func diffBundleMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) != 2 {
+		return usagef("usage: totool diff-bundle Old.app New.app")
+	}
+	var bins [2]map[string]bundleBin
+	for i, app := range args {
+		if !totool.IsAppBundle(app) {
+			return fmt.Errorf("%s: not an app bundle", app)
+		}
+		var err error
+		if bins[i], err = walkBundle(ctx, app, opts); err != nil {
+			return fmt.Errorf("%s: %v", app, err)
+		}
+	}
+	d := diffBundles(bins[0], bins[1])
+	fmt.Printf("%s -> %s:\n", args[0], args[1])
+	printBundleDiff(d)
+	return nil
+}
+
+// walkBundle walks the binaries of app into a single graph and returns its
+// binaries by key, missing ones included.
+func walkBundle(ctx context.Context, app string, opts totool.Options) (map[string]bundleBin, error) {
+	roots, err := totool.BundleRoots(app)
+	if err != nil {
+		return nil, err
+	}
+	app, err = filepath.Abs(app)
+	if err != nil {
+		return nil, err
+	}
+	opts.KeepGoing = true
+	w := totool.NewWalker(nullPrinter{}, opts)
+	for _, root := range roots {
+		if err := w.WalkContext(ctx, root); err != nil {
+			return nil, err
+		}
+	}
+	g := w.Graph()
+	key := func(bin string) string {
+		if strings.HasPrefix(bin, app+"/") {
+			return strings.TrimPrefix(bin, app+"/")
+		}
+		return bin
+	}
+	infos := g.FirstInfos()
+	bins := make(map[string]bundleBin)
+	for _, bin := range g.Bins() {
+		b := bundleBin{key: key(bin), version: version(infos[bin])}
+		if deps := g.Dependents(bin); len(deps) > 0 {
+			b.by = key(deps[0])
+		}
+		bins[b.key] = b
+	}
+	return bins, nil
+}
+
+// diffBundles compares the binaries of the old and new releases of a bundle.
+// A binary removed and another added under the same file name are taken for a
+// relocation of the same library, if no other binary has that name.
+func diffBundles(old, new map[string]bundleBin) bundleDiff {
+	var d bundleDiff
+	for k, nb := range new {
+		if ob, ok := old[k]; !ok {
+			d.added = append(d.added, nb)
+		} else if ob.version != nb.version {
+			d.changed = append(d.changed, versionChange{k, ob.version, nb.version})
+		}
+	}
+	for k, ob := range old {
+		if _, ok := new[k]; !ok {
+			d.removed = append(d.removed, ob)
+		}
+	}
+
+	byName := func(bins []bundleBin) map[string][]int {
+		m := make(map[string][]int)
+		for i, b := range bins {
+			m[filepath.Base(b.key)] = append(m[filepath.Base(b.key)], i)
+		}
+		return m
+	}
+	addedByName, removedByName := byName(d.added), byName(d.removed)
+	moved := make(map[string]bool)
+	for name, is := range removedByName {
+		if js := addedByName[name]; len(is) == 1 && len(js) == 1 {
+			ob, nb := d.removed[is[0]], d.added[js[0]]
+			d.relocated = append(d.relocated, relocation{ob, nb})
+			moved[ob.key], moved[nb.key] = true, true
+		}
+	}
+	keep := func(bins []bundleBin) []bundleBin {
+		var kept []bundleBin
+		for _, b := range bins {
+			if !moved[b.key] {
+				kept = append(kept, b)
+			}
+		}
+		sort.Slice(kept, func(i, j int) bool { return kept[i].key < kept[j].key })
+		return kept
+	}
+	d.added, d.removed = keep(d.added), keep(d.removed)
+	sort.Slice(d.changed, func(i, j int) bool { return d.changed[i].bin < d.changed[j].bin })
+	sort.Slice(d.relocated, func(i, j int) bool { return d.relocated[i].old.key < d.relocated[j].old.key })
+	return d
+}
+
+// printBundleDiff prints d as a changelog.
+func printBundleDiff(d bundleDiff) {
+	if len(d.added)+len(d.removed)+len(d.changed)+len(d.relocated) == 0 {
+		fmt.Printf("\tno dependency changes\n")
+		return
+	}
+	describe := func(b bundleBin) string {
+		s := b.key
+		if b.version != "" {
+			s += " " + b.version
+		}
+		if b.by != "" {
+			s += ", needed by " + b.by
+		}
+		return s
+	}
+	if len(d.added) > 0 {
+		fmt.Printf("\tadded:\n")
+		for _, b := range d.added {
+			fmt.Printf("\t\t%s\n", describe(b))
+		}
+	}
+	if len(d.removed) > 0 {
+		fmt.Printf("\tremoved:\n")
+		for _, b := range d.removed {
+			fmt.Printf("\t\t%s\n", describe(b))
+		}
+	}
+	if len(d.changed) > 0 {
+		fmt.Printf("\tre-versioned:\n")
+		for _, c := range d.changed {
+			fmt.Printf("\t\t%s: %s -> %s\n", c.bin, c.old, c.new)
+		}
+	}
+	if len(d.relocated) > 0 {
+		fmt.Printf("\trelocated:\n")
+		for _, r := range d.relocated {
+			fmt.Printf("\t\t%s -> %s", r.old.key, r.new.key)
+			if r.old.version != r.new.version {
+				fmt.Printf(" (%s -> %s)", r.old.version, r.new.version)
+			}
+			fmt.Println()
+		}
+	}
+}
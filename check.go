@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// checkOptions configures the optional checks of the check subcommand.
+type checkOptions struct {
+	// policy lists rules dependencies must obey.
+	policy policy
+
+	// distributable flags dependencies installed by package managers, which
+	// customer machines lack.
+	distributable bool
+
+	// fix prints the changes to the binaries of app bundles fixing their
+	// install names and run paths, and makes them if apply is set before
+	// checking again.
+	fix, apply bool
+
+	// identity signs the fixed app bundles, which are otherwise left signed
+	// ad hoc along with the codesign commands to run.
+	identity string
+}
+
+// checkMain implements the check subcommand, walking each of bins to report
+// the dependencies that do not exist, cannot be read, are referred to by
+// non-portable install names, lie outside of the app bundle of the root, lack
+// architectures of the root, require a later OS than it, are older than their
+// dependents require, are embedded copies of libraries also loaded from
+// elsewhere or fail the checks enabled by copts along with the chain that
+// brings them in.  The error it returns, if any,
+// wraps the first problem found.
+func checkMain(ctx context.Context, bins []string, opts totool.Options, copts checkOptions) error {
+	if len(bins) == 0 {
+		return usagef("usage: totool check file...")
+	}
+	var first error
+	total := 0
+	p := newPatcher(ctx, !copts.apply, true)
+	var fixed []string
+	for _, root := range bins {
+		problems := make(map[string][]error)
+		var archs []string
+		var minOS map[string]string
+		var app string
+		var deps []totool.Dependency
+
+		// unresolved lists the edges to @rpath-prefixed paths no run path
+		// search path resolved.
+		var unresolved []totool.Dependency
+
+		var edges []totool.Dependency
+
+		// Install names are checked on all edges rather than only on the
+		// first one leading to each binary.
+		vopts := opts
+		vopts.Filter = func(d *totool.Dependency) bool {
+			if opts.Filter != nil && !opts.Filter(d) {
+				return false
+			}
+			edges = append(edges, *d)
+			if isNonPortable(d.Name) {
+				problems[d.Bin] = append(problems[d.Bin], fmt.Errorf("non-portable install name %s in %s", d.Name, d.Parent))
+			}
+			if isUnresolvedRpath(d.Bin) {
+				unresolved = append(unresolved, *d)
+			}
+			return true
+		}
+		g, err := totool.VisitContext(ctx, root, &totool.Visitor{
+			OnNode: func(d *totool.Dependency) {
+				if d.Depth == 0 {
+					app = totool.AppBundle(d.Bin)
+				} else {
+					deps = append(deps, *d)
+					if copts.distributable && totool.IsPackageBin(d.Bin) {
+						problems[d.Bin] = append(problems[d.Bin], errPackageBin)
+					}
+					if app != "" && d.Origin != totool.OriginEmbedded && d.Origin != totool.OriginSystem && !isUnresolvedRpath(d.Bin) {
+						problems[d.Bin] = append(problems[d.Bin], fmt.Errorf("outside of %s", app))
+					}
+				}
+				if isUnresolvedRpath(d.Bin) {
+					return
+				}
+				if err := checkReadable(d.Bin); err != nil {
+					if !(totool.IsWeak(d.Info) && errors.Is(err, totool.ErrMissingFile)) {
+						problems[d.Bin] = append(problems[d.Bin], err)
+					}
+					return
+				}
+				if d.Depth == 0 {
+					archs, _ = totool.Archs(d.Bin)
+					minOS, _ = totool.MinOS(d.Bin)
+					return
+				}
+				if err := checkArchs(d.Bin, archs); err != nil {
+					problems[d.Bin] = append(problems[d.Bin], err)
+				}
+				if err := checkMinOS(d.Bin, archs, minOS); err != nil {
+					problems[d.Bin] = append(problems[d.Bin], err)
+				}
+			},
+			OnError: func(bin string, err error) error {
+				if ctx.Err() != nil {
+					return err
+				}
+				if errors.Is(err, totool.ErrMissingFile) && (totool.IsSystemBin(bin) || isUnresolvedRpath(bin)) {
+					return nil
+				}
+				if len(problems[bin]) == 0 {
+					problems[bin] = append(problems[bin], err)
+				}
+				return nil
+			},
+		}, vopts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", root, err)
+		}
+
+		for _, m := range g.VersionMismatches() {
+			problems[m.To] = append(problems[m.To], errors.New(m.String()))
+		}
+		for _, c := range g.Collisions() {
+			problems[c.Embedded] = append(problems[c.Embedded], fmt.Errorf("loaded along with %s copy %s", c.Origin, c.Other))
+		}
+		var failed []totool.Dependency
+		for _, d := range unresolved {
+			if err := checkRpath(g, d); err != nil {
+				problems[d.Bin] = append(problems[d.Bin], err)
+				failed = append(failed, d)
+			}
+		}
+		for i := range deps {
+			if err := copts.policy.denied(&deps[i]); err != nil {
+				problems[deps[i].Bin] = append(problems[deps[i].Bin], err)
+			}
+		}
+
+		fmt.Printf("%s:\n", g.Name())
+		// Xcode shows errors in build logs along with the notes after them.
+		prefix, note, hint := "\t", "\t\t", "\t"
+		if xcodeLog {
+			prefix, note, hint = root+": error: ", root+": note: ", root+": note: "
+		}
+		n := 0
+		for _, node := range g.Nodes() {
+			errs := problems[node.Path]
+			if len(errs) == 0 {
+				continue
+			}
+			n += len(errs)
+			total += len(errs)
+			if first == nil {
+				first = errs[0]
+			}
+			for _, err := range errs {
+				fmt.Printf("%s%s: %v\n", prefix, node.Path, err)
+			}
+			if chain := g.Chain(node.Path); len(chain) > 1 {
+				fmt.Printf("%s%s\n", note, strings.Join(chain, " -> "))
+			}
+			if deps := g.Dependents(node.Path); len(deps) > 1 {
+				fmt.Printf("%sneeded by %s\n", note, strings.Join(deps, ", "))
+			}
+		}
+		for _, r := range copts.policy.missing(deps) {
+			n++
+			total++
+			if first == nil {
+				first = fmt.Errorf("policy violation: %s (%s)", r.text, r.where)
+			}
+			fmt.Printf("%sno dependency matches %s (%s)\n", prefix, r.text, r.where)
+		}
+		for _, s := range suggestRpaths(g, app, failed) {
+			fmt.Printf("%ssuggestion: %v\n", hint, s)
+		}
+		if n == 0 {
+			fmt.Printf("\tall %d binaries found\n", g.Size())
+		}
+		if copts.fix && n > 0 {
+			if app == "" {
+				fmt.Printf("\tnot in an app bundle, nothing to fix\n")
+			} else if err := fixBundle(p, app, root, edges, unresolved); err != nil {
+				return err
+			} else if !containsString(fixed, app) {
+				fixed = append(fixed, app)
+			}
+		}
+	}
+	if copts.fix {
+		n, err := p.sign()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			for _, app := range fixed {
+				if err := signBundle(ctx, app, nil, copts.identity, !copts.apply); err != nil {
+					return err
+				}
+			}
+		}
+		switch {
+		case n > 0 && !copts.apply:
+			fmt.Printf("%s, run again with -apply to make these changes\n", p.summary())
+		case n > 0:
+			fmt.Printf("checking again:\n")
+			copts.fix = false
+			return checkMain(ctx, bins, opts, copts)
+		}
+	}
+	if first != nil {
+		return checkError{fmt.Errorf("%d problems found: %w", total, first)}
+	}
+	return nil
+}
+
+// buildDirPrefixes and buildDirParts identify paths into home directories,
+// temporary and build directories and CI workspaces, which install names
+// should not point into.
+var (
+	buildDirPrefixes = []string{
+		"/Users/", "/home/", "/tmp/", "/private/tmp/", "/var/folders/",
+		"/private/var/folders/", "/builds/", "/var/lib/jenkins/", "/Volumes/workspace/",
+	}
+	buildDirParts = []string{"/DerivedData/", "/.build/", "/build/", "/workspace/"}
+)
+
+// isNonPortable reports whether name, an install name, is an absolute path
+// that only exists where the binary was built.
+func isNonPortable(name string) bool {
+	for _, prefix := range buildDirPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, part := range buildDirParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnresolvedRpath reports whether bin is a dependency path the resolver left
+// relative to the run path search paths as none yields an existing file.
+func isUnresolvedRpath(bin string) bool {
+	return strings.HasPrefix(bin, "@rpath/")
+}
+
+// checkRpath returns an error if the unresolved @rpath-prefixed dependency d
+// cannot be found through the run path search paths of any binary along the
+// chain that loads it, as the dynamic loader searches them all.
+func checkRpath(g *totool.Graph, d totool.Dependency) error {
+	chain := g.Chain(d.Parent)
+	loaders := make([]string, len(chain))
+	for i, bin := range chain {
+		loaders[len(chain)-1-i] = bin
+	}
+	var executable string
+	if len(chain) > 0 {
+		executable = chain[0]
+	}
+	tried := totool.RpathCandidates(d.Bin, loaders, executable)
+	for _, p := range tried {
+		if checkReadable(p) == nil {
+			return nil
+		}
+	}
+	if len(tried) == 0 {
+		return fmt.Errorf("%w: loaded by %s, no LC_RPATH along the load chain", totool.ErrMissingFile, d.Parent)
+	}
+	return fmt.Errorf("%w: loaded by %s, tried %s", totool.ErrMissingFile, d.Parent, strings.Join(tried, ", "))
+}
+
+// errPackageBin reports dependencies installed by package managers in the
+// closure of binaries meant to be distributed.
+var errPackageBin = errors.New("installed by a package manager, not found on customer machines")
+
+// checkReadable returns why bin cannot be read, if it cannot.  Missing system
+// binaries are fine as macOS ships them in the dyld shared cache only.
+func checkReadable(bin string) error {
+	f, err := os.Open(bin)
+	if os.IsNotExist(err) {
+		if totool.IsSystemBin(bin) {
+			return nil
+		}
+		return totool.ErrMissingFile
+	} else if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// checkArchs returns an error if bin lacks any of the architectures in archs.
+// Binaries that cannot be parsed are left to the walk to report.
+func checkArchs(bin string, archs []string) error {
+	have, err := totool.Archs(bin)
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	for _, a := range archs {
+		found := false
+		for _, h := range have {
+			found = found || a == h
+		}
+		if !found {
+			missing = append(missing, a)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing architecture %s, has %s", strings.Join(missing, ", "), strings.Join(have, ", "))
+	}
+	return nil
+}
+
+// checkMinOS returns an error if bin requires a later OS version than minOS,
+// the minimum versions of the root by architecture, for any of archs.
+func checkMinOS(bin string, archs []string, minOS map[string]string) error {
+	have, err := totool.MinOS(bin)
+	if err != nil {
+		return nil
+	}
+	var later []string
+	for _, arch := range archs {
+		root, ok := minOS[arch]
+		if !ok {
+			continue
+		}
+		if v, ok := have[arch]; ok && totool.CompareVersions(v, root) > 0 {
+			later = append(later, fmt.Sprintf("%s %s (root targets %s)", arch, v, root))
+		}
+	}
+	if len(later) > 0 {
+		return fmt.Errorf("requires a later OS: %s", strings.Join(later, ", "))
+	}
+	return nil
+}
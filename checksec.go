@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printCheckSec prints the exploit mitigations each binary of g was built
+// with.  System binaries living in the dyld shared cache only are left out.
+func printCheckSec(g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	for _, bin := range g.Bins() {
+		if _, err := os.Stat(bin); err != nil && totool.IsSystemBin(bin) {
+			continue
+		}
+		h, err := totool.CheckSec(bin)
+		if err != nil {
+			log.Printf("%s: %v", bin, err)
+			continue
+		}
+		fmt.Printf("\t%s: PIE=%s canary=%s ARC=%s restrict=%s encrypted=%s\n", bin,
+			yesNo(h.PIE), yesNo(h.StackCanary), yesNo(h.ARC), yesNo(h.Restrict), yesNo(h.Encrypted))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nthery/totool/totool"
+)
+
+// ANSI escape sequences of the styles of colored output.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiReverse = "\x1b[7m"
+	ansiRed     = "\x1b[31m"
+	ansiYellow  = "\x1b[33m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// colorModes lists the values of -color.
+var colorModes = []string{"never", "auto", "always"}
+
+// useColor reports whether output to f is colored under mode, one of
+// colorModes.  In auto mode, output is colored when f is a terminal and
+// neither NO_COLOR is set nor TERM is dumb.
+func useColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case "never":
+		return false, nil
+	case "always":
+		return true, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("-color %s: want never, auto or always", mode)
+	}
+}
+
+// paint returns s in style if on is set, s as is otherwise.
+func paint(on bool, style, s string) string {
+	if !on || style == "" {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+// binStyle returns the style of a dependency: red if missing, yellow if
+// truncated, dim for system binaries and cyan for those shipped along with
+// the root.  System binaries are not checked for existence as macOS keeps
+// most of them in the dyld shared cache only.
+func binStyle(d *totool.Dependency) string {
+	switch {
+	case d.Origin == totool.OriginSystem:
+		return ansiDim
+	case !fileExists(d.Bin):
+		return ansiRed
+	case d.Truncated:
+		return ansiYellow
+	case d.Origin == totool.OriginEmbedded:
+		return ansiCyan
+	default:
+		return ""
+	}
+}
+
+// fileExists reports whether path names an existing file.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A subcommand is a command of totool other than walking files.
+type subcommand struct {
+	name string
+
+	// args describes the arguments of the command in usage messages.
+	args string
+
+	// flagsAfter is set for commands taking flags after their name.
+	flagsAfter bool
+
+	// interactive is set for commands using the terminal themselves, which
+	// output is not paged.
+	interactive bool
+
+	// early is set for commands run before anything is set up, as they need
+	// neither the walk options nor a context.
+	early bool
+
+	// main runs the command on its arguments, nil for render, which walks
+	// graphs as totool walks files.
+	main func(ctx context.Context, args []string, env *cmdEnv) error
+}
+
+// cmdEnv is what subcommands get from the command line besides their
+// arguments.
+type cmdEnv struct {
+	fs       *flag.FlagSet
+	opts     totool.Options
+	resolver *totool.AutoResolver
+	cacheDir string
+
+	colorMode  string
+	dryRun     bool
+	flatten    bool
+	identity   string
+	runTimeout time.Duration
+
+	// check holds the options of check but its policy, read from
+	// policyFile if not empty.
+	check      checkOptions
+	policyFile string
+}
+
+// subcommands lists the commands of totool, in the order of usage messages.
+// It is filled in by init as completion refers to it.
+var subcommands []subcommand
+
+func init() {
+	subcommands = []subcommand{
+		{name: "diff", args: "old_file|old.json|old.gob new_file|new.json|new.gob", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return diffMain(ctx, args, env.opts)
+		}},
+		{name: "diff-bundle", args: "Old.app New.app", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return diffBundleMain(ctx, args, env.opts)
+		}},
+		{name: "check", args: "file...", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			copts := env.check
+			if env.policyFile != "" {
+				var err error
+				if copts.policy, err = readPolicy(env.policyFile); err != nil {
+					return usageError(err.Error())
+				}
+			}
+			return checkMain(ctx, args, env.opts, copts)
+		}},
+		{name: "wheel", args: "file.whl|site-packages...", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return wheelMain(ctx, args, env.opts)
+		}},
+		{name: "autolink", args: "file.o|lib.a...", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return autolinkMain(args)
+		}},
+		{name: "provides", args: "file|graph.json symbol", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return providesMain(ctx, args, env.opts)
+		}},
+		{name: "browse", args: "file|graph.json", interactive: true, main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return browseMain(ctx, args, env.opts, env.colorMode)
+		}},
+		{name: "repl", args: "file|graph.json", interactive: true, main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return replMain(ctx, args, env.opts)
+		}},
+		{name: "serve", args: "[addr]", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return serveMain(ctx, args, env.opts)
+		}},
+		{name: "daemon", args: "[socket]", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			// The daemon runs until interrupted.
+			if err := daemonMain(ctx, args, env.opts); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		}},
+		{name: "run", args: "file [arg...]", interactive: true, main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return runMain(ctx, args, env.opts, env.runTimeout)
+		}},
+		{name: "bundle", args: "app", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return bundleMain(ctx, args, env.opts, env.dryRun, env.identity)
+		}},
+		{name: "export", args: "dir file...", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return exportMain(ctx, args, env.opts, env.dryRun, env.flatten)
+		}},
+		{name: "archive", args: "out.zip|out.tar|out.tar.gz file...", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return archiveMain(ctx, args, env.opts)
+		}},
+		{name: "image", args: "image.tar|docker:name [path...]", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return imageMain(ctx, args, env.opts, env.resolver)
+		}},
+		{name: "render", args: "graph.json|graph.gob...", flagsAfter: true},
+		{name: "cache-clear", main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return totool.ClearCache(env.cacheDir)
+		}},
+		{name: "completion", args: "bash|zsh|fish", early: true, main: func(ctx context.Context, args []string, env *cmdEnv) error {
+			return completionMain(args, env.fs)
+		}},
+	}
+}
+
+// findSubcommand returns the subcommand named name if any.
+func findSubcommand(name string) (subcommand, bool) {
+	for _, c := range subcommands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return subcommand{}, false
+}
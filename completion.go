@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printUsage prints the usage message of totool, listing the flags of fs.
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: totool [flags] file...\n")
+	fmt.Fprintf(w, "       totool [flags] -scan dir...\n")
+	fmt.Fprintf(w, "       totool [flags] -r dir [file...]\n")
+	fmt.Fprintf(w, "       find ... -print0 | totool [flags] -0 -\n")
+	for _, c := range subcommands {
+		line := "totool [flags] " + c.name
+		if c.flagsAfter {
+			line = "totool " + c.name + " [flags]"
+		}
+		if c.args != "" {
+			line += " " + c.args
+		}
+		fmt.Fprintf(w, "       %s\n", line)
+	}
+	fmt.Fprintf(w, "Flags default to the environment variables named after them: %s for -cache-dir.\n", envName("cache-dir"))
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// flagValues lists the values flags accept when there are few of them.
+func flagValues() map[string][]string {
+	return map[string][]string{
+		"format":  totool.Formats(),
+		"backend": totool.Backends,
+		"color":   colorModes,
+		"log":     logFormats,
+	}
+}
+
+// A flagInfo describes a flag for completion scripts.
+type flagInfo struct {
+	name, usage string
+
+	// arg is the kind of argument the flag takes: "" for boolean flags,
+	// "file" or "dir" for paths, "value" for others.
+	arg string
+
+	// values lists the arguments the flag accepts, if known.
+	values []string
+}
+
+// flagInfos describes the flags of fs, sorted by name.
+func flagInfos(fs *flag.FlagSet) []flagInfo {
+	values := flagValues()
+	var infos []flagInfo
+	fs.VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		info := flagInfo{name: f.Name, usage: usage, values: values[f.Name]}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			switch name {
+			case "file", "dir":
+				info.arg = name
+			default:
+				info.arg = "value"
+			}
+		}
+		infos = append(infos, info)
+	})
+	sort.Slice(infos, func(i, j int) bool { return infos[i].name < infos[j].name })
+	return infos
+}
+
+// completionMain implements the completion subcommand, printing the script
+// completing the subcommands, flags and flag values of totool in shell.
+func completionMain(args []string, fs *flag.FlagSet) error {
+	if len(args) != 1 {
+		return usagef("usage: totool completion bash|zsh|fish")
+	}
+	infos := flagInfos(fs)
+	var names []string
+	for _, c := range subcommands {
+		names = append(names, c.name)
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout, infos, names)
+	case "zsh":
+		writeZshCompletion(os.Stdout, infos, names)
+	case "fish":
+		writeFishCompletion(os.Stdout, infos, names)
+	default:
+		return usagef("%s: unknown shell, want bash, zsh or fish", args[0])
+	}
+	return nil
+}
+
+func writeBashCompletion(w io.Writer, infos []flagInfo, commands []string) {
+	var all, withArg []string
+	for _, f := range infos {
+		all = append(all, "-"+f.name)
+		if f.arg != "" {
+			withArg = append(withArg, "-"+f.name)
+		}
+	}
+	fmt.Fprintf(w, "# bash completion for totool, generated by totool completion bash.\n")
+	fmt.Fprintf(w, "_totool() {\n")
+	fmt.Fprintf(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}\n")
+	fmt.Fprintf(w, "\tcase $prev in\n")
+	for _, f := range infos {
+		switch {
+		case len(f.values) > 0:
+			fmt.Fprintf(w, "\t-%[1]s|--%[1]s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n", f.name, shellQuote(strings.Join(f.values, " ")))
+		case f.arg == "file":
+			fmt.Fprintf(w, "\t-%[1]s|--%[1]s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", f.name)
+		case f.arg == "dir":
+			fmt.Fprintf(w, "\t-%[1]s|--%[1]s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", f.name)
+		case f.arg == "value":
+			fmt.Fprintf(w, "\t-%[1]s|--%[1]s) return ;;\n", f.name)
+		}
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tif [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(all, " ")))
+	fmt.Fprintf(w, "\t\treturn\n")
+	fmt.Fprintf(w, "\tfi\n")
+	fmt.Fprintf(w, "\t# Complete subcommands in place of the first argument.\n")
+	fmt.Fprintf(w, "\tlocal i withArg=%s\n", shellQuote(" "+strings.Join(withArg, " ")+" "))
+	fmt.Fprintf(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "\t\tcase ${COMP_WORDS[i]} in\n")
+	fmt.Fprintf(w, "\t\t-*=*) ;;\n")
+	fmt.Fprintf(w, "\t\t-*) [[ $withArg == *\" ${COMP_WORDS[i]} \"* ]] && ((i++)) ;;\n")
+	fmt.Fprintf(w, "\t\t*) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n")
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %s -- \"$cur\") $(compgen -f -- \"$cur\"))\n", shellQuote(strings.Join(commands, " ")))
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o filenames -F _totool totool\n")
+}
+
+func writeZshCompletion(w io.Writer, infos []flagInfo, commands []string) {
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+	fmt.Fprintf(w, "#compdef totool\n")
+	fmt.Fprintf(w, "# zsh completion for totool, generated by totool completion zsh.\n")
+	fmt.Fprintf(w, "_totool() {\n")
+	fmt.Fprintf(w, "\tlocal state\n")
+	fmt.Fprintf(w, "\t_arguments \\\n")
+	for _, f := range infos {
+		spec := "-" + f.name + "[" + escape.Replace(f.usage) + "]"
+		switch {
+		case len(f.values) > 0:
+			spec += ":" + f.name + ":(" + strings.Join(f.values, " ") + ")"
+		case f.arg == "file":
+			spec += ":file:_files"
+		case f.arg == "dir":
+			spec += ":dir:_files -/"
+		case f.arg == "value":
+			spec += ":" + f.name + ": "
+		}
+		fmt.Fprintf(w, "\t\t'%s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "\t\t'1: :->first' \\\n")
+	fmt.Fprintf(w, "\t\t'*:file:_files'\n")
+	fmt.Fprintf(w, "\tif [[ $state == first ]]; then\n")
+	fmt.Fprintf(w, "\t\t_alternative 'commands:command:(%s)' 'files:file:_files'\n", strings.Join(commands, " "))
+	fmt.Fprintf(w, "\tfi\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "_totool \"$@\"\n")
+}
+
+func writeFishCompletion(w io.Writer, infos []flagInfo, commands []string) {
+	fmt.Fprintf(w, "# fish completion for totool, generated by totool completion fish.\n")
+	for _, f := range infos {
+		line := "complete -c totool -o " + f.name + " -d " + shellQuote(f.usage)
+		switch {
+		case len(f.values) > 0:
+			line += " -x -a " + shellQuote(strings.Join(f.values, " "))
+		case f.arg == "file":
+			line += " -r -F"
+		case f.arg == "dir":
+			line += " -x -a '(__fish_complete_directories)'"
+		case f.arg == "value":
+			line += " -x"
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "complete -c totool -n __fish_use_subcommand -a %s\n", shellQuote(strings.Join(commands, " ")))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/csv"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+func init() {
+	totool.RegisterFormat("csv", func(opts totool.PrinterOptions) totool.Printer {
+		return &csvPrinter{edges: !opts.Nodes, hashes: opts.Hashes, licenses: opts.Licenses}
+	})
+}
+
+// csvPrinter prints one CSV record per binary, or per direct dependency if
+// edges is set.
+type csvPrinter struct {
+	edges bool
+
+	// hashes adds the SHA-256 digest of binaries to their records.
+	hashes bool
+
+	// licenses adds the license of binaries to their records.
+	licenses bool
+
+	w *csv.Writer
+
+	// root is the root being walked.
+	root string
+}
+
+func (p *csvPrinter) PrintPrologue() {
+	if p.w == nil {
+		p.w = csv.NewWriter(os.Stdout)
+		if p.edges {
+			p.write("root", "from", "to")
+		} else {
+			p.writeBin("", "root", "path", "depth", "parent", "class", "info", "truncated", "name", "compat_version", "current_version", "weak", "upward", "attrs", "size")
+		}
+	}
+}
+
+func (p *csvPrinter) PrintEpilogue() {
+	p.w.Flush()
+	if err := p.w.Error(); err != nil {
+		log.Printf("cannot write CSV: %v", err)
+	}
+}
+
+func (p *csvPrinter) PrintRootBin(bin string) {
+	p.root = bin
+	if !p.edges {
+		p.writeBin(bin, bin, bin, "0", "", totool.Classify(bin, bin).String(), "", "false", "", "", "", "false", "false", "", strconv.FormatInt(totool.FileSize(bin), 10))
+	}
+}
+
+func (p *csvPrinter) PrintDepBin(d *totool.Dependency) {
+	if !p.edges {
+		li := totool.ParseInfo(d.Info)
+		p.writeBin(d.Bin, p.root, d.Bin, strconv.Itoa(d.Depth), d.Parent, d.Origin.String(), d.Info, strconv.FormatBool(d.Truncated), d.Name,
+			li.Compat, li.Current, strconv.FormatBool(li.Weak), strconv.FormatBool(li.Upward), strings.Join(li.Attrs, " "), strconv.FormatInt(d.BinSize(), 10))
+	}
+}
+
+func (p *csvPrinter) PrintDep(from, to string) {
+	if p.edges {
+		p.write(p.root, from, to)
+	}
+}
+
+// write writes a single record, errors being reported when flushing.
+func (p *csvPrinter) write(fields ...string) {
+	p.w.Write(fields)
+}
+
+// writeBin writes the record of bin, or the header if bin is empty, adding its
+// digest and license if enabled.
+func (p *csvPrinter) writeBin(bin string, fields ...string) {
+	if p.hashes {
+		h := "sha256"
+		if bin != "" {
+			h, _ = totool.SHA256(bin)
+		}
+		fields = append(fields, h)
+	}
+	if p.licenses {
+		l := "license"
+		if bin != "" {
+			l = totool.License(bin)
+		}
+		fields = append(fields, l)
+	}
+	p.write(fields...)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// daemonMain implements the daemon subcommand, answering JSON-RPC requests on
+// the Unix socket path, totool.sock in the temporary directory unless given,
+// until ctx is done.  The daemon keeps the graphs it walks and the output of
+// the backends in memory, so that editors and build systems query it without
+// starting a process each time.  The methods of Daemon are its API.
+func daemonMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) > 1 {
+		return usagef("usage: totool daemon [socket]")
+	}
+	path := filepath.Join(os.TempDir(), "totool.sock")
+	if len(args) == 1 {
+		path = args[0]
+	}
+	d := &Daemon{ctx: ctx, opts: opts, graphs: make(map[string]daemonGraph)}
+	srv := rpc.NewServer()
+	if err := srv.Register(d); err != nil {
+		return err
+	}
+	// A socket left behind by a daemon that did not exit cleanly is replaced.
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: a daemon already listens there", path)
+	}
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	log.Printf("listening on %s", path)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Daemon is the JSON-RPC service of the daemon subcommand.  Requests are
+// served one at a time as walks share caches and inspectors.
+type Daemon struct {
+	ctx  context.Context
+	opts totool.Options
+
+	mu sync.Mutex
+
+	// graphs holds the graphs walked so far by root.
+	graphs map[string]daemonGraph
+}
+
+// daemonGraph is a graph the daemon keeps along with the modification time
+// of its root when it was walked.
+type daemonGraph struct {
+	g       *totool.Graph
+	modTime time.Time
+}
+
+// WalkArgs are the arguments of Daemon.Walk.
+type WalkArgs struct {
+	// Path is the root to walk.
+	Path string
+
+	// Fresh walks again roots walked before.
+	Fresh bool
+}
+
+// WalkReply is the reply of Daemon.Walk, a graph as the JSON document render
+// reads.
+type WalkReply struct{ jsonGraph }
+
+// Walk replies with the graph of args.Path.
+func (d *Daemon) Walk(args WalkArgs, reply *WalkReply) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g, err := d.graph(args.Path, args.Fresh)
+	if err != nil {
+		return err
+	}
+	reply.jsonGraph = jsonGraphOf(g)
+	return nil
+}
+
+// WhyArgs are the arguments of Daemon.Why.
+type WhyArgs struct {
+	// Path is the root and Lib the library of its graph, given by path or
+	// by a unique part of it.
+	Path, Lib string
+}
+
+// WhyReply is the reply of Daemon.Why.
+type WhyReply struct {
+	// Lib is the path of the library.
+	Lib string
+
+	// Chain is the shortest chain from the root to Lib and Dependents the
+	// binaries depending on Lib directly.
+	Chain      []string
+	Dependents []string
+}
+
+// Why replies with what brings args.Lib in the graph of args.Path.
+func (d *Daemon) Why(args WhyArgs, reply *WhyReply) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g, err := d.graph(args.Path, false)
+	if err != nil {
+		return err
+	}
+	bin, err := findBin(g, args.Lib)
+	if err != nil {
+		return err
+	}
+	*reply = WhyReply{Lib: bin, Chain: g.Chain(bin), Dependents: g.Dependents(bin)}
+	return nil
+}
+
+// CheckArgs are the arguments of Daemon.Check.
+type CheckArgs struct {
+	// Paths are the roots to check.
+	Paths []string
+}
+
+// CheckReply is the reply of Daemon.Check.
+type CheckReply struct {
+	// Output is what the check subcommand prints and Problem the first
+	// problem found, if any.
+	Output  string
+	Problem string
+}
+
+// Check replies with what the check subcommand reports about args.Paths.
+func (d *Daemon) Check(args CheckArgs, reply *CheckReply) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var err error
+	reply.Output, err = captureStdout(func() error {
+		return checkMain(d.ctx, args.Paths, d.opts, checkOptions{})
+	})
+	var ce checkError
+	if errors.As(err, &ce) {
+		reply.Problem = ce.Error()
+		return nil
+	}
+	return err
+}
+
+// graph returns the graph of root, walking it unless walked since root was
+// last modified or unless fresh is set.
+func (d *Daemon) graph(root string, fresh bool) (*totool.Graph, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(root)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", root, totool.ErrMissingFile)
+	} else if err != nil {
+		return nil, err
+	}
+	if dg, ok := d.graphs[root]; ok {
+		if !fresh && dg.modTime.Equal(fi.ModTime()) {
+			return dg.g, nil
+		}
+		forgetGraph(dg.g)
+	}
+	g, err := totool.WalkContext(d.ctx, root, nullPrinter{}, d.opts)
+	if err != nil {
+		return nil, err
+	}
+	d.graphs[root] = daemonGraph{g, fi.ModTime()}
+	return g, nil
+}
+
+// forgetGraph drops what was memoized about the binaries of g, for walking it
+// again to notice binaries rebuilt, created or removed since.  The otool output
+// cached on disk is still used for binaries left as they were.
+func forgetGraph(g *totool.Graph) {
+	for _, n := range g.Nodes() {
+		totool.Forget(n.Path)
+	}
+}
+
+// captureStdout returns what f prints on the standard output along with the
+// error it returns.
+func captureStdout(f func() error) (string, error) {
+	tmp, err := ioutil.TempFile("", "totool-output")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	stdout := os.Stdout
+	os.Stdout = tmp
+	err = f()
+	os.Stdout = stdout
+	out, rerr := ioutil.ReadFile(tmp.Name())
+	if rerr != nil && err == nil {
+		err = rerr
+	}
+	return string(out), err
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// graphDiff lists the differences between the dependencies of two graphs.
+type graphDiff struct {
+	added   []string
+	removed []string
+	changed []versionChange
+}
+
+// versionChange is a dependency present in both graphs with different versions.
+type versionChange struct {
+	bin      string
+	old, new string
+}
+
+// empty reports whether d contains no difference.
+func (d *graphDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+// diffGraphs compares the dependencies of old and new graphs.
+func diffGraphs(old, new *totool.Graph) graphDiff {
+	oldVersions, newVersions := diffVersions(old), diffVersions(new)
+
+	var d graphDiff
+	for bin, nv := range newVersions {
+		ov, ok := oldVersions[bin]
+		if !ok {
+			d.added = append(d.added, bin)
+		} else if ov != nv {
+			d.changed = append(d.changed, versionChange{bin, ov, nv})
+		}
+	}
+	for bin := range oldVersions {
+		if _, ok := newVersions[bin]; !ok {
+			d.removed = append(d.removed, bin)
+		}
+	}
+
+	sort.Strings(d.added)
+	sort.Strings(d.removed)
+	sort.Slice(d.changed, func(i, j int) bool { return d.changed[i].bin < d.changed[j].bin })
+	return d
+}
+
+// printDiff prints d like a patch: added dependencies are prefixed with "+",
+// removed ones with "-" and the ones whose version changed with "~".
+func printDiff(d graphDiff) {
+	for _, bin := range d.added {
+		fmt.Printf("+ %s\n", bin)
+	}
+	for _, bin := range d.removed {
+		fmt.Printf("- %s\n", bin)
+	}
+	for _, c := range d.changed {
+		fmt.Printf("~ %s: %s -> %s\n", c.bin, c.old, c.new)
+	}
+}
+
+// diffVersions maps the dependencies of g, as identified by diffKey, to their
+// versions.
+func diffVersions(g *totool.Graph) map[string]string {
+	var rootDir string
+	if len(g.Roots()) > 0 {
+		rootDir = filepath.Dir(g.Roots()[0])
+	}
+
+	infos := g.FirstInfos()
+	versions := make(map[string]string)
+	for _, bin := range g.Bins() {
+		if !g.IsRoot(bin) {
+			versions[diffKey(rootDir, bin)] = version(infos[bin])
+		}
+	}
+	return versions
+}
+
+// diffKey returns how bin is identified when comparing graphs.  Binaries below
+// the directory of the root are identified relative to it so that builds
+// located in different directories can be compared.
+func diffKey(rootDir, bin string) string {
+	if rootDir != "" && strings.HasPrefix(bin, rootDir+string(filepath.Separator)) {
+		return "@executable_path/" + strings.TrimPrefix(bin, rootDir+string(filepath.Separator))
+	}
+	return bin
+}
+
+// version returns the current version a binary was linked against given the
+// additional data otool printed about it, or that data if it contains no
+// version.
+func version(info string) string {
+	if _, current, ok := totool.ParseVersions(info); ok {
+		return current
+	}
+	return info
+}
+
+// diffMain implements the diff subcommand, comparing the dependencies of the
+// old and new binaries, either of which may be a snapshot saved with -json.
+func diffMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) != 2 {
+		return usagef("usage: totool diff old_binary|old.json new_binary|new.json")
+	}
+	old, err := loadGraph(ctx, args[0], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	new, err := loadGraph(ctx, args[1], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[1], err)
+	}
+	d := diffGraphs(old, new)
+	printDiff(d)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printDominators prints each direct dependency of g followed by the binaries
+// that would leave the graph if it was removed.
+func printDominators(g *totool.Graph) {
+	dominated := g.Dominated()
+	fmt.Printf("%s:\n", g.Name())
+	for _, d := range g.DirectDeps() {
+		fmt.Printf("\t%s\n", d)
+		for _, bin := range dominated[d] {
+			fmt.Printf("\t\t%s\n", bin)
+		}
+	}
+}
+
+// printContributions prints the direct dependencies of g sorted by decreasing
+// number of binaries they bring in on their own.
+func printContributions(g *totool.Graph) {
+	contribs := g.Contributions()
+	deps := g.DirectDeps()
+	sort.SliceStable(deps, func(i, j int) bool { return contribs[deps[i]] > contribs[deps[j]] })
+
+	fmt.Printf("%s:\n", g.Name())
+	for _, d := range deps {
+		fmt.Printf("\t%d %s\n", contribs[d], d)
+	}
+}
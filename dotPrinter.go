@@ -1,26 +1,122 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// dotColors are the colors used to tell roots apart in merged graphs.
+var dotColors = []string{"red", "blue", "darkgreen", "orange", "purple", "brown", "magenta", "cyan4"}
+
+func init() {
+	totool.RegisterFormat("dot", func(opts totool.PrinterOptions) totool.Printer {
+		return &dotPrinter{colors: opts.Merged, label: opts.Label, alias: opts.Alias}
+	})
+}
 
 // dotPrinter prints the dependency graph in dot format.
-type dotPrinter struct{}
+type dotPrinter struct {
+	// colors enables coloring nodes and edges after the root that reached them.
+	colors bool
+
+	// roots counts the roots printed so far.
+	roots int
+
+	// label returns the labels of nodes, nil to leave dot label them with
+	// their path.
+	label func(string) string
+
+	// alias returns the names of the nodes merging binaries, if not nil.
+	// merged and edges record the merged nodes and the edges printed.
+	alias  func(string) (string, bool)
+	merged map[string]bool
+	edges  map[[2]string]bool
+}
 
-func (p dotPrinter) printPrologue() {
+func (p *dotPrinter) PrintPrologue() {
 	// TODO: hardcoding the graph name will break when called with several files.
 	fmt.Println("digraph G {")
 }
 
-func (p dotPrinter) printEpilogue() {
+func (p *dotPrinter) PrintEpilogue() {
 	fmt.Println("}")
 }
 
-func (p dotPrinter) printRootBin(bin string) {
-	// nop
+func (p *dotPrinter) PrintRootBin(bin string) {
+	p.roots++
+	var attrs []string
+	if p.colors {
+		attrs = append(attrs, "color="+p.color(), "style=bold")
+	}
+	p.printNode(bin, attrs)
+}
+
+func (p *dotPrinter) PrintDepBin(d *totool.Dependency) {
+	var attrs []string
+	if p.colors {
+		attrs = append(attrs, "color="+p.color())
+	}
+	if d.Truncated {
+		attrs = append(attrs, "style=dashed", `xlabel="truncated"`)
+	}
+	p.printNode(d.Bin, attrs)
 }
 
-func (p dotPrinter) printDepBin(d *dependency) {
-	// nop
+// node returns the name of the node of bin, its alias if it has one.
+func (p *dotPrinter) node(bin string) string {
+	if p.alias != nil {
+		if name, ok := p.alias(bin); ok {
+			return name
+		}
+	}
+	return bin
 }
-func (p dotPrinter) printDep(from, to string) {
-	fmt.Printf("\t\"%s\" -> \"%s\";\n", from, to)
+
+// printNode prints the statement of the node bin if it has attributes, its
+// label included.  Merged nodes are printed once, as boxes.
+func (p *dotPrinter) printNode(bin string, attrs []string) {
+	if name := p.node(bin); name != bin {
+		if p.merged[name] {
+			return
+		}
+		if p.merged == nil {
+			p.merged = make(map[string]bool)
+		}
+		p.merged[name] = true
+		fmt.Printf("\t\"%s\" [%s];\n", name, strings.Join(append(attrs, "shape=box"), ", "))
+		return
+	}
+	if p.label != nil {
+		if l := p.label(bin); l != bin {
+			attrs = append(attrs, fmt.Sprintf("label=%q", l))
+		}
+	}
+	if len(attrs) > 0 {
+		fmt.Printf("\t\"%s\" [%s];\n", bin, strings.Join(attrs, ", "))
+	}
+}
+func (p *dotPrinter) PrintDep(from, to string) {
+	from, to = p.node(from), p.node(to)
+	if p.alias != nil {
+		// Edges within and between merged nodes are printed once.
+		if from == to || p.edges[[2]string{from, to}] {
+			return
+		}
+		if p.edges == nil {
+			p.edges = make(map[[2]string]bool)
+		}
+		p.edges[[2]string{from, to}] = true
+	}
+	if p.colors {
+		fmt.Printf("\t\"%s\" -> \"%s\" [color=%s];\n", from, to, p.color())
+	} else {
+		fmt.Printf("\t\"%s\" -> \"%s\";\n", from, to)
+	}
+}
+
+// color returns the color of the root being walked.
+func (p *dotPrinter) color() string {
+	return dotColors[(p.roots-1)%len(dotColors)]
 }
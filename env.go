@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// envPrefix prefixes the names of the environment variables setting flags.
+const envPrefix = "TOTOOL_"
+
+// envName returns the name of the environment variable setting the flag name.
+//
+//	cache-dir -> TOTOOL_CACHE_DIR
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// setFlagsFromEnv sets the flags of fs from the environment, before parsing
+// the command line overrides them.  Repeatable flags take lists separated like
+// PATH.
+func setFlagsFromEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		values := []string{v}
+		if _, ok := f.Value.(*stringList); ok {
+			values = filepath.SplitList(v)
+		}
+		for _, v := range values {
+			if serr := f.Value.Set(v); serr != nil {
+				err = fmt.Errorf("%s: %v", envName(f.Name), serr)
+				return
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/nthery/totool/totool"
+)
+
+// An event is a line of -events output.  Empty fields are omitted.
+//
+//   - "root" is printed when the walk of a root starts;
+//   - "node" when a dependency is discovered, at depth;
+//   - "progress" before inspecting current, with how many binaries were
+//     inspected and how many are queued;
+//   - "error" when errors are logged, path failing if known;
+//   - "done" once the walk of all roots is over.
+type event struct {
+	Event     string `json:"event"`
+	Path      string `json:"path,omitempty"`
+	Parent    string `json:"parent,omitempty"`
+	Depth     int    `json:"depth,omitempty"`
+	Inspected int    `json:"inspected,omitempty"`
+	Queued    int    `json:"queued,omitempty"`
+	Root      string `json:"root,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// events writes events as JSON lines when -events is set, nil otherwise.
+var events *eventWriter
+
+// An eventWriter writes events for front ends wrapping totool to show
+// progress.
+type eventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// openEvents makes events be written to the file descriptor fd.
+func openEvents(fd int) error {
+	f := os.NewFile(uintptr(fd), "events")
+	if f == nil {
+		return fmt.Errorf("-events %d: invalid file descriptor", fd)
+	}
+	if _, err := f.Stat(); err != nil {
+		return fmt.Errorf("-events %d: %v", fd, err)
+	}
+	events = &eventWriter{enc: json.NewEncoder(f)}
+	return nil
+}
+
+func (w *eventWriter) write(e event) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enc.Encode(e)
+}
+
+// progress writes a progress event, as Options.Progress.
+func (w *eventWriter) progress(inspected, queued int, current string) {
+	w.write(event{Event: "progress", Path: current, Inspected: inspected, Queued: queued})
+}
+
+// eventPrinter forwards to another printer, writing root and node events.
+type eventPrinter struct{ pt totool.Printer }
+
+func (p eventPrinter) PrintPrologue() { p.pt.PrintPrologue() }
+func (p eventPrinter) PrintEpilogue() { p.pt.PrintEpilogue() }
+
+func (p eventPrinter) PrintRootBin(bin string) {
+	events.write(event{Event: "root", Path: bin})
+	p.pt.PrintRootBin(bin)
+}
+
+func (p eventPrinter) PrintDepBin(d *totool.Dependency) {
+	events.write(event{Event: "node", Path: d.Bin, Parent: d.Parent, Depth: d.Depth})
+	p.pt.PrintDepBin(d)
+}
+
+func (p eventPrinter) PrintDep(from, to string) { p.pt.PrintDep(from, to) }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// exportMain implements the export subcommand, copying the binaries given
+// after the destination directory and their transitive dependencies into it.
+// Copies keep their absolute paths below the destination, as needed to build
+// chroots, unless flatten is set.  With flatten set, all binaries land in the
+// destination itself, system binaries are left out and the copies refer to
+// each other relative to @loader_path, making the directory relocatable, then
+// are signed ad hoc.  With dryRun set, what would be done is only printed.
+func exportMain(ctx context.Context, args []string, opts totool.Options, dryRun, flatten bool) error {
+	if len(args) < 2 {
+		return usagef("usage: totool export dir file...")
+	}
+	dest, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+
+	bins, deps, err := walkClosure(ctx, args[1:], opts)
+	if err != nil {
+		return err
+	}
+	files, err := closureFiles(bins, !flatten)
+	if err != nil {
+		return err
+	}
+
+	// copies maps the binaries to export to their copies.
+	copies := make(map[string]string)
+	owners := make(map[string]string)
+	for _, bin := range files {
+		to := filepath.Join(dest, bin)
+		if flatten {
+			to = filepath.Join(dest, filepath.Base(bin))
+		}
+		if other, ok := owners[to]; ok {
+			return fmt.Errorf("cannot export both %s and %s to %s", other, bin, to)
+		}
+		owners[to] = bin
+		copies[bin] = to
+	}
+
+	srcs := make([]string, 0, len(copies))
+	for src := range copies {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	for _, src := range srcs {
+		fmt.Printf("copy %s to %s\n", src, copies[src])
+		if dryRun {
+			continue
+		}
+		fi, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(copies[src]), 0755); err != nil {
+			return err
+		}
+		if err := copyFile(src, copies[src], fi.Mode().Perm()|0200); err != nil {
+			return err
+		}
+	}
+	if !flatten {
+		return nil
+	}
+
+	p := newPatcher(ctx, dryRun, false)
+	isRoot := make(map[string]bool)
+	for _, d := range bins {
+		isRoot[d.Bin] = isRoot[d.Bin] || d.Depth == 0
+	}
+	for _, src := range srcs {
+		dup := copies[src]
+		if !isRoot[src] && !totool.IsExecutable(src) {
+			if err := p.setInstallID(dup, loaderName(dup)); err != nil {
+				return err
+			}
+		}
+		for _, d := range deps[src] {
+			to, ok := copies[d.Bin]
+			if !ok || d.Name == loaderName(to) {
+				continue
+			}
+			if err := p.changeInstallName(dup, d.Name, loaderName(to)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = p.sign()
+	return err
+}
+
+// loaderName returns how binaries exported next to dup should refer to it.
+func loaderName(dup string) string {
+	return "@loader_path/" + filepath.Base(dup)
+}
+
+// walkClosure walks roots and returns the binaries found in the order they
+// were, and the dependencies recorded by each binary, under the install names
+// it records them as.  Binaries that cannot be inspected are left for the
+// caller to report.
+func walkClosure(ctx context.Context, roots []string, opts totool.Options) ([]totool.Dependency, map[string][]totool.Dependency, error) {
+	var bins []totool.Dependency
+	seenBin := make(map[string]bool)
+	deps := make(map[string][]totool.Dependency)
+	seenDep := make(map[[2]string]bool)
+	vopts := opts
+	vopts.Filter = func(d *totool.Dependency) bool {
+		if opts.Filter != nil && !opts.Filter(d) {
+			return false
+		}
+		if k := [2]string{d.Parent, d.Name}; !seenDep[k] {
+			seenDep[k] = true
+			deps[d.Parent] = append(deps[d.Parent], *d)
+		}
+		return true
+	}
+	v := &totool.Visitor{
+		OnNode: func(d *totool.Dependency) {
+			if !seenBin[d.Bin] {
+				seenBin[d.Bin] = true
+				bins = append(bins, *d)
+			}
+		},
+		OnError: func(bin string, err error) error {
+			if ctx.Err() != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	for _, root := range roots {
+		if _, err := totool.VisitContext(ctx, root, v, vopts); err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", root, err)
+		}
+	}
+	return bins, deps, nil
+}
+
+// closureFiles returns the files of bins, leaving out system binaries unless
+// system is set, those living in the dyld shared cache only and absent weak
+// dependencies.  Other missing binaries are reported as an error.
+func closureFiles(bins []totool.Dependency, system bool) ([]string, error) {
+	var files, missing []string
+	for _, d := range bins {
+		if !system && totool.IsSystemBin(d.Bin) {
+			continue
+		}
+		if err := checkReadable(d.Bin); errors.Is(err, totool.ErrMissingFile) {
+			if !totool.IsWeak(d.Info) {
+				missing = append(missing, d.Bin)
+			}
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %v", d.Bin, err)
+		}
+		if _, err := os.Stat(d.Bin); err != nil {
+			// System binary living in the dyld shared cache only.
+			continue
+		}
+		files = append(files, d.Bin)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", totool.ErrMissingFile, strings.Join(missing, ", "))
+	}
+	return files, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A failure is a binary that could not be inspected by a -keep-going walk.
+type failure struct {
+	root, bin string
+	err       error
+
+	// dependents lists the binaries of the graph of root needing bin.
+	dependents []string
+}
+
+// failuresOf returns the failures recorded in g, the graph of root.
+func failuresOf(root string, g *totool.Graph) []failure {
+	var fs []failure
+	for _, bin := range g.Failed() {
+		fs = append(fs, failure{root: root, bin: bin, err: g.Err(bin), dependents: g.Dependents(bin)})
+	}
+	return fs
+}
+
+// logFailures logs the summary of the failures of a run, one error record
+// per binary.
+func logFailures(fs []failure) {
+	if len(fs) == 0 {
+		return
+	}
+	logAt(logError, "", "", "%d binaries could not be inspected:", len(fs))
+	for _, f := range fs {
+		msg := f.err.Error()
+		if f.bin != f.root && !strings.Contains(msg, f.bin) {
+			msg = f.bin + ": " + msg
+		}
+		if len(f.dependents) > 0 {
+			msg += ", needed by " + strings.Join(f.dependents, ", ")
+		}
+		logAt(logError, f.root, f.bin, "%s", msg)
+	}
+}
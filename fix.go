@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// fixBundle makes with p the changes that let the binaries of app, walked from
+// root along edges, refer to each other portably.  Absolute install names of
+// libraries embedded in Contents/Frameworks become relative to the run path
+// search paths, those of other embedded binaries relative to their loader.
+// Unresolved @rpath dependencies found in Contents/Frameworks get the run path
+// search path leading there.  Dependencies outside of app are left to the
+// bundle subcommand.
+func fixBundle(p *patcher, app, root string, edges, unresolved []totool.Dependency) error {
+	frameworks := filepath.Join(app, "Contents", "Frameworks")
+	rpathDone := false
+	addRpath := func(loader string) error {
+		if strings.HasPrefix(root, filepath.Join(app, "Contents", "MacOS")+"/") {
+			if rpathDone {
+				return nil
+			}
+			rpathDone = true
+			return p.addRpath(root, frameworksRpath)
+		}
+		rel, err := filepath.Rel(filepath.Dir(loader), frameworks)
+		if err != nil {
+			return err
+		}
+		return p.addRpath(loader, "@loader_path/"+rel)
+	}
+
+	ids := make(map[string]bool)
+	for _, d := range edges {
+		if !strings.HasPrefix(d.Bin, app+"/") || !filepath.IsAbs(d.Name) {
+			continue
+		}
+		if strings.HasPrefix(d.Bin, frameworks+"/") {
+			name := "@rpath/" + strings.TrimPrefix(d.Bin, frameworks+"/")
+			if err := p.changeInstallName(d.Parent, d.Name, name); err != nil {
+				return err
+			}
+			if !ids[d.Bin] {
+				ids[d.Bin] = true
+				if err := p.setInstallID(d.Bin, name); err != nil {
+					return err
+				}
+			}
+			if err := addRpath(d.Parent); err != nil {
+				return err
+			}
+			continue
+		}
+		rel, err := filepath.Rel(filepath.Dir(d.Parent), d.Bin)
+		if err != nil {
+			return err
+		}
+		if err := p.changeInstallName(d.Parent, d.Name, "@loader_path/"+rel); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range unresolved {
+		if checkReadable(filepath.Join(frameworks, strings.TrimPrefix(d.Bin, "@rpath/"))) != nil {
+			continue
+		}
+		if err := addRpath(d.Parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
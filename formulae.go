@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printFormulae prints the Homebrew formula and version each binary of g was
+// installed by, followed by the formulae the closure needs.
+func printFormulae(ctx context.Context, g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	needed := make(map[string]bool)
+	for _, bin := range g.Bins() {
+		p, err := totool.HomebrewFormula(ctx, bin)
+		if err != nil {
+			log.Print(err)
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		if p.Name == "" {
+			continue
+		}
+		version := p.Version
+		if version == "" {
+			version = "not installed"
+		}
+		fmt.Printf("\t%s: %s %s\n", bin, p.Name, version)
+		needed[p.Name] = true
+	}
+	if len(needed) == 0 {
+		fmt.Printf("\tno Homebrew formulae\n")
+		return
+	}
+	names := make([]string, 0, len(needed))
+	for name := range needed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("\tformulae: %s\n", strings.Join(names, ", "))
+}
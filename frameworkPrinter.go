@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// frameworkPrinter forwards to another printer the dependency graph where all
+// binaries inside a framework bundle are merged into a single node named after
+// the bundle.
+type frameworkPrinter struct {
+	pt totool.Printer
+
+	printed map[string]bool
+	edges   map[edge]bool
+}
+
+// edge is a direct dependency between two binaries.
+type edge struct{ from, to string }
+
+func (p *frameworkPrinter) PrintPrologue() {
+	p.printed = make(map[string]bool)
+	p.edges = make(map[edge]bool)
+	p.pt.PrintPrologue()
+}
+
+func (p *frameworkPrinter) PrintEpilogue() {
+	p.printed = nil
+	p.edges = nil
+	p.pt.PrintEpilogue()
+}
+
+func (p *frameworkPrinter) PrintRootBin(bin string) {
+	bin = frameworkOf(bin)
+	p.printed[bin] = true
+	p.pt.PrintRootBin(bin)
+}
+
+func (p *frameworkPrinter) PrintDepBin(d *totool.Dependency) {
+	fw := frameworkOf(d.Bin)
+	if p.printed[fw] {
+		return
+	}
+	p.printed[fw] = true
+	c := *d
+	c.Bin = fw
+	p.pt.PrintDepBin(&c)
+}
+
+func (p *frameworkPrinter) PrintDep(from, to string) {
+	e := edge{frameworkOf(from), frameworkOf(to)}
+	if e.from == e.to || p.edges[e] {
+		return
+	}
+	p.edges[e] = true
+	p.pt.PrintDep(e.from, e.to)
+}
+
+// frameworkOf returns the path of the outermost framework bundle containing bin
+// or bin itself if it does not belong to a framework.
+func frameworkOf(bin string) string {
+	const ext = ".framework/"
+	if i := strings.Index(bin, ext); i >= 0 {
+		return bin[:i+len(ext)-1]
+	}
+	return bin
+}
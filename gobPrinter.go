@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"log"
+	"os"
+
+	"github.com/nthery/totool/totool"
+)
+
+func init() {
+	totool.RegisterFormat("gob", func(totool.PrinterOptions) totool.Printer {
+		return &gobPrinter{}
+	})
+}
+
+// gobPrinter prints each dependency graph as an encoding/gob encoded
+// totool.Graph once walked, a graph being encoded as a whole.
+type gobPrinter struct {
+	g *totool.Graph
+
+	// edges and infos are kept until the epilogue as edges are walked before
+	// the binaries they lead to.
+	edges []totool.Edge
+	infos map[string]string
+
+	w   *bufio.Writer
+	enc *gob.Encoder
+}
+
+func (p *gobPrinter) PrintPrologue() {
+	if p.w == nil {
+		p.w = bufio.NewWriter(os.Stdout)
+		p.enc = gob.NewEncoder(p.w)
+	}
+}
+
+func (p *gobPrinter) PrintEpilogue() {
+	if p.g == nil {
+		return
+	}
+	for _, e := range p.edges {
+		p.g.AddDep(e.From, e.To, p.infos[e.To])
+	}
+	if err := p.enc.Encode(p.g); err != nil {
+		log.Printf("cannot encode graph: %v", err)
+	}
+	if err := p.w.Flush(); err != nil {
+		log.Printf("cannot write graph: %v", err)
+	}
+	p.g, p.edges, p.infos = nil, nil, nil
+}
+
+func (p *gobPrinter) PrintRootBin(bin string) {
+	if p.g == nil {
+		p.g = totool.NewGraph()
+		p.infos = make(map[string]string)
+	}
+	p.g.AddRoot(bin)
+	p.g.AddNode(totool.Node{Path: bin, Origin: totool.Classify(bin, bin), Size: totool.FileSize(bin)})
+}
+
+func (p *gobPrinter) PrintDepBin(d *totool.Dependency) {
+	p.g.AddNode(totool.Node{
+		Path:      d.Bin,
+		Depth:     d.Depth,
+		Parent:    d.Parent,
+		Name:      d.Name,
+		Origin:    d.Origin,
+		Truncated: d.Truncated,
+		Size:      d.BinSize(),
+	})
+	p.infos[d.Bin] = d.Info
+}
+
+func (p *gobPrinter) PrintDep(from, to string) {
+	p.edges = append(p.edges, totool.Edge{From: from, To: to})
+}
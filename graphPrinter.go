@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+func init() {
+	totool.RegisterFormat("topo", func(opts totool.PrinterOptions) totool.Printer {
+		return &graphPrinter{print: printTopo, color: opts.Color, label: opts.LabelOf}
+	})
+	totool.RegisterFormat("leaves", func(opts totool.PrinterOptions) totool.Printer {
+		return &graphPrinter{print: printLeaves, color: opts.Color, label: opts.LabelOf}
+	})
+}
+
+// graphPrinter collects the whole dependency graph and prints it once walked,
+// as neither the topological order nor the leaves are known before.
+type graphPrinter struct {
+	g *totool.Graph
+
+	// print prints the collected graph, in colors if color is set and
+	// displaying paths as label returns them.
+	print func(g *totool.Graph, color bool, label func(string) string)
+	color bool
+	label func(string) string
+}
+
+func (p *graphPrinter) PrintPrologue() {
+	// nop
+}
+
+func (p *graphPrinter) PrintEpilogue() {
+	if p.g == nil {
+		return
+	}
+	p.print(p.g, p.color, p.label)
+	p.g = nil
+}
+
+func (p *graphPrinter) PrintRootBin(bin string) {
+	if p.g == nil {
+		p.g = totool.NewGraph()
+	}
+	p.g.AddRoot(bin)
+	p.g.AddNode(totool.Node{Path: bin})
+}
+
+func (p *graphPrinter) PrintDepBin(d *totool.Dependency) {
+	p.g.AddNode(totool.Node{
+		Path:      d.Bin,
+		Depth:     d.Depth,
+		Parent:    d.Parent,
+		Origin:    d.Origin,
+		Truncated: d.Truncated,
+		Size:      d.Size,
+	})
+}
+
+func (p *graphPrinter) PrintDep(from, to string) {
+	p.g.AddDep(from, to, "")
+}
+
+// printTopo prints the dependencies of g in topological order, leaves first.
+func printTopo(g *totool.Graph, color bool, label func(string) string) {
+	fmt.Printf("%s:\n", paint(color, ansiBold, labelName(g, label)))
+	for _, bin := range g.TopoSort() {
+		if !g.IsRoot(bin) {
+			fmt.Printf("\t%s\n", paintNode(color, g, bin, label(bin)))
+		}
+	}
+}
+
+// printLeaves prints the non-system dependencies of g that depend on system
+// binaries only.
+func printLeaves(g *totool.Graph, color bool, label func(string) string) {
+	fmt.Printf("%s:\n", paint(color, ansiBold, labelName(g, label)))
+	for _, bin := range g.Bins() {
+		if !g.IsRoot(bin) && !totool.IsSystemBin(bin) && g.IsLeaf(bin) {
+			fmt.Printf("\t%s\n", paintNode(color, g, bin, label(bin)))
+		}
+	}
+}
+
+// labelName is like g.Name with the roots labeled by label.
+func labelName(g *totool.Graph, label func(string) string) string {
+	var names []string
+	for _, root := range g.Roots() {
+		names = append(names, label(root))
+	}
+	return strings.Join(names, ", ")
+}
+
+// paintNode returns label, that of bin, a binary of g, in the style of
+// binStyle if color is set.
+func paintNode(color bool, g *totool.Graph, bin, label string) string {
+	if !color {
+		return label
+	}
+	n, _ := g.Node(bin)
+	return paint(true, binStyle(&totool.Dependency{Bin: bin, Origin: n.Origin, Truncated: n.Truncated}), label)
+}
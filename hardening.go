@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printHardening prints whether each root of g runs with the hardened runtime
+// and enforces library validation, followed by the non-Apple dependencies it
+// would then refuse to load.
+func printHardening(ctx context.Context, g *totool.Graph) {
+	sigs := signaturesOf(ctx, g)
+	for _, root := range g.Roots() {
+		sig := sigs[root]
+		if sig == nil {
+			continue
+		}
+		lv := enforcesLibraryValidation(ctx, root, sig)
+		fmt.Printf("%s:\n", root)
+		fmt.Printf("\thardened runtime: %s\n", yesNo(sig.HardenedRuntime()))
+		fmt.Printf("\tlibrary validation: %s\n", yesNo(lv))
+		if !lv {
+			continue
+		}
+		refused := refusedLibraries(g, root, sigs)
+		for _, r := range refused {
+			fmt.Printf("\t%s: %s\n", r.bin, r.problem)
+		}
+		if len(refused) == 0 {
+			fmt.Printf("\tall dependencies satisfy library validation\n")
+		}
+	}
+}
+
+// enforcesLibraryValidation reports whether root, signed with sig, enforces
+// library validation given its entitlements.
+func enforcesLibraryValidation(ctx context.Context, root string, sig *totool.Signature) bool {
+	if sig.Status == totool.Unsigned {
+		return false
+	}
+	ents, err := totool.Entitlements(ctx, root)
+	if err != nil {
+		log.Print(err)
+	}
+	return sig.LibraryValidation(ents)
+}
+
+// refusal is a library a root enforcing library validation would not load.
+type refusal struct {
+	bin, problem string
+}
+
+// refusedLibraries returns the non-Apple binaries of g brought in by root that
+// root would refuse to load if enforcing library validation.  Binaries whose
+// signature is unknown are left out.
+func refusedLibraries(g *totool.Graph, root string, sigs map[string]*totool.Signature) []refusal {
+	var refused []refusal
+	for _, bin := range g.Bins() {
+		sig := sigs[bin]
+		if sig == nil || g.IsRoot(bin) || totool.IsSystemBin(bin) {
+			continue
+		}
+		if chain := g.Chain(bin); len(chain) == 0 || chain[0] != root {
+			continue
+		}
+		if p := sigs[root].LoadProblem(sig); p != "" {
+			refused = append(refused, refusal{bin, p})
+		}
+	}
+	return refused
+}
+
+// yesNo formats b for display.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// daemonPrefix prefixes the images image takes from the local Docker daemon
+// rather than from a tarball.
+const daemonPrefix = "docker:"
+
+// imageMain implements the image subcommand, walking the dependencies of
+// binaries of a container image, given by their paths in the image or taken
+// from its entrypoint, and reporting the shared objects they need that the
+// image lacks.  The image is a tarball written by "docker save" or holding an
+// OCI image layout, or an image of the local Docker daemon named after
+// daemonPrefix.  Its layers are extracted into a temporary directory that
+// dependencies are resolved below.
+func imageMain(ctx context.Context, args []string, opts totool.Options, resolver *totool.AutoResolver) error {
+	if len(args) < 1 {
+		return usagef("usage: totool image image.tar|%sname [path...]", daemonPrefix)
+	}
+	tmp, err := ioutil.TempDir("", "totool-image")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	tarball := args[0]
+	if strings.HasPrefix(tarball, daemonPrefix) {
+		tarball = filepath.Join(tmp, "image.tar")
+		name := strings.TrimPrefix(args[0], daemonPrefix)
+		if out, err := exec.CommandContext(ctx, "docker", "save", "-o", tarball, name).CombinedOutput(); err != nil {
+			if out := bytes.TrimSpace(out); len(out) > 0 {
+				return fmt.Errorf("docker save %s: %s", name, out)
+			}
+			return fmt.Errorf("docker save %s: %v", name, err)
+		}
+	}
+	blobs := filepath.Join(tmp, "image")
+	if err := untarBlobs(tarball, blobs); err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	layers, config, err := imageLayers(blobs)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	root := filepath.Join(tmp, "rootfs")
+	if err := os.Mkdir(root, 0755); err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		if err := extractLayer(filepath.Join(blobs, layer), root); err != nil {
+			return fmt.Errorf("%s: layer %s: %v", args[0], layer, err)
+		}
+	}
+
+	paths := args[1:]
+	if len(paths) == 0 {
+		p, err := config.entrypoint(root)
+		if err != nil {
+			return fmt.Errorf("%s: %v", args[0], err)
+		}
+		paths = []string{p}
+	}
+	resolver.ELF.Root, resolver.ELF.LibraryPath = root, nil
+	totool.AddSystemRoot(root)
+	inImage := func(p string) string {
+		if strings.HasPrefix(p, root+"/") {
+			return strings.TrimPrefix(p, root)
+		}
+		return p
+	}
+
+	total := 0
+	for _, p := range paths {
+		bin, ok := totool.InRoot(root, filepath.Join(root, p))
+		if !ok {
+			return fmt.Errorf("%s:%s: too many levels of symbolic links", args[0], p)
+		}
+		fmt.Printf("%s:%s:\n", args[0], p)
+		var missing []totool.Dependency
+		seen := make(map[string]bool)
+		vopts := opts
+		vopts.Filter = func(d *totool.Dependency) bool {
+			if opts.Filter != nil && !opts.Filter(d) {
+				return false
+			}
+			// Unresolved names are not files to inspect.
+			if _, err := os.Stat(d.Bin); !filepath.IsAbs(d.Bin) || err != nil {
+				if !seen[d.Bin] && !totool.IsWeak(d.Info) {
+					seen[d.Bin] = true
+					missing = append(missing, *d)
+				}
+				return false
+			}
+			return true
+		}
+		n := 0
+		_, err := totool.VisitContext(ctx, bin, &totool.Visitor{
+			OnNode: func(d *totool.Dependency) {
+				if d.Depth > 0 {
+					n++
+					fmt.Printf("\t%s\n", inImage(d.Bin))
+				}
+			},
+		}, vopts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		for _, d := range missing {
+			fmt.Printf("\tmissing: %s, needed by %s\n", inImage(d.Bin), inImage(d.Parent))
+		}
+		if len(missing) == 0 {
+			fmt.Printf("\tall %d binaries found\n", n)
+		}
+		total += len(missing)
+	}
+	if total > 0 {
+		return checkError{fmt.Errorf("%d shared objects missing from %s: %w", total, args[0], totool.ErrMissingFile)}
+	}
+	return nil
+}
+
+// untarBlobs extracts the regular files of the image tarball into dir.
+func untarBlobs(tarball, dir string) error {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := layerPath(h.Name)
+		if !ok || h.Typeflag != tar.TypeReg {
+			continue
+		}
+		dest := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := writeFileFrom(dest, 0644, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// imageConfig is the part of the configuration of an image that tells what
+// it runs.
+type imageConfig struct {
+	Config struct {
+		Entrypoint []string
+		Cmd        []string
+		Env        []string
+	} `json:"config"`
+}
+
+// entrypoint returns the path in the image of the executable the image with
+// root file system root runs, looked up in the PATH of the image if relative.
+func (c *imageConfig) entrypoint(root string) (string, error) {
+	argv := append(c.Config.Entrypoint, c.Config.Cmd...)
+	if len(argv) == 0 {
+		return "", errors.New("no entrypoint nor command, give the paths of the binaries to walk")
+	}
+	if strings.Contains(argv[0], "/") {
+		return argv[0], nil
+	}
+	dirs := "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	for _, env := range c.Config.Env {
+		if strings.HasPrefix(env, "PATH=") {
+			dirs = strings.TrimPrefix(env, "PATH=")
+		}
+	}
+	for _, dir := range strings.Split(dirs, ":") {
+		p := path.Join(dir, argv[0])
+		if bin, ok := totool.InRoot(root, filepath.Join(root, p)); ok {
+			if _, err := os.Stat(bin); err == nil {
+				return p, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("entrypoint %s not found in PATH %s", argv[0], dirs)
+}
+
+// imageLayers returns the layers of the image extracted into dir, bottom
+// first, and its configuration.  It reads the manifest.json of "docker save"
+// or, failing that, the index.json of OCI image layouts.
+func imageLayers(dir string) ([]string, *imageConfig, error) {
+	var config imageConfig
+	var docker []struct {
+		Config string
+		Layers []string
+	}
+	if err := readJSON(filepath.Join(dir, "manifest.json"), &docker); err == nil && len(docker) > 0 {
+		if len(docker) > 1 {
+			return nil, nil, errors.New("several images in tarball")
+		}
+		if err := readJSON(filepath.Join(dir, filepath.FromSlash(docker[0].Config)), &config); err != nil {
+			return nil, nil, err
+		}
+		return docker[0].Layers, &config, nil
+	} else if !os.IsNotExist(err) && err != nil {
+		return nil, nil, err
+	}
+
+	type descriptor struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+	}
+	var oci struct {
+		Manifests []descriptor `json:"manifests"`
+		Config    descriptor   `json:"config"`
+		Layers    []descriptor `json:"layers"`
+	}
+	if err := readJSON(filepath.Join(dir, "index.json"), &oci); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, errors.New("neither manifest.json nor index.json, not an image tarball")
+		}
+		return nil, nil, err
+	}
+	// Follow indexes down to the first image manifest.
+	for len(oci.Layers) == 0 {
+		if len(oci.Manifests) == 0 {
+			return nil, nil, errors.New("no image manifest in index.json")
+		}
+		next := oci.Manifests[0]
+		oci.Manifests = nil
+		if err := readJSON(filepath.Join(dir, blobPath(next.Digest)), &oci); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := readJSON(filepath.Join(dir, blobPath(oci.Config.Digest)), &config); err != nil {
+		return nil, nil, err
+	}
+	var layers []string
+	for _, l := range oci.Layers {
+		layers = append(layers, blobPath(l.Digest))
+	}
+	return layers, &config, nil
+}
+
+// blobPath returns where OCI image layouts store the blob with digest.
+//
+//	sha256:abc… -> blobs/sha256/abc…
+func blobPath(digest string) string {
+	return filepath.Join("blobs", strings.Replace(digest, ":", "/", 1))
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Whiteouts mark the files upper layers delete from lower ones.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq" // deletes all the files of its directory
+)
+
+// extractLayer applies the layer tarball, compressed with gzip or not, to
+// root.  Paths in the layer are resolved within root, symbolic links
+// included.  Device files and the like are skipped.
+func extractLayer(layer, root string) error {
+	f, err := os.Open(layer)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return errors.New("zstd-compressed layers not supported")
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := layerPath(h.Name)
+		if !ok {
+			continue
+		}
+		dir, ok := totool.InRoot(root, filepath.Join(root, filepath.Dir(name)))
+		if !ok {
+			log.Printf("%s: skipped, too many levels of symbolic links", name)
+			continue
+		}
+		base := filepath.Base(name)
+		switch {
+		case base == whiteoutOpaque:
+			fis, _ := ioutil.ReadDir(dir)
+			for _, fi := range fis {
+				os.RemoveAll(filepath.Join(dir, fi.Name()))
+			}
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			os.RemoveAll(filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		dest := filepath.Join(dir, base)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if fi, err := os.Lstat(dest); err == nil && !fi.IsDir() {
+				os.Remove(dest)
+			}
+			err = os.MkdirAll(dest, os.FileMode(h.Mode).Perm()|0700)
+		case tar.TypeReg, tar.TypeRegA:
+			os.RemoveAll(dest)
+			err = writeFileFrom(dest, os.FileMode(h.Mode).Perm()|0600, tr)
+		case tar.TypeSymlink:
+			os.RemoveAll(dest)
+			err = os.Symlink(h.Linkname, dest)
+		case tar.TypeLink:
+			target, ok := layerPath(h.Linkname)
+			if !ok {
+				continue
+			}
+			src, ok := totool.InRoot(root, filepath.Join(root, target))
+			if !ok {
+				log.Printf("%s: skipped, too many levels of symbolic links", name)
+				continue
+			}
+			os.RemoveAll(dest)
+			err = os.Link(src, dest)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// layerPath returns name, the path of a tarball entry, relative and cleaned,
+// and false if it is empty or climbs out of the tarball.
+func layerPath(name string) (string, bool) {
+	name = path.Clean("/" + name)[1:]
+	return filepath.FromSlash(name), name != ""
+}
+
+// writeFileFrom writes what r reads into a new file at path.
+func writeFileFrom(path string, perm os.FileMode, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
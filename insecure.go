@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printInsecure prints the binaries of g that users other than root could
+// replace, along with the library search paths looked into before system
+// paths that they could write to.  It returns how many problems it found.
+func printInsecure(g *totool.Graph) int {
+	fmt.Printf("%s:\n", g.Name())
+	n := 0
+	for _, dir := range totool.NewDyldResolver().LibraryPath {
+		if why := insecurePath(dir); why != "" {
+			n++
+			fmt.Printf("\tDYLD_LIBRARY_PATH entry %s: %s\n", dir, why)
+		}
+	}
+	for _, bin := range g.Bins() {
+		if _, err := os.Stat(bin); err != nil {
+			continue
+		}
+		if why := insecurePath(bin); why != "" {
+			n++
+			fmt.Printf("\t%s: %s\n", bin, why)
+		}
+	}
+	if n == 0 {
+		fmt.Printf("\tno insecure locations\n")
+	}
+	return n
+}
+
+// insecurePath returns why a user other than root could replace path, or the
+// empty string if it found no reason.
+func insecurePath(path string) string {
+	var reasons []string
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode().Perm()&0002 != 0 {
+			reasons = append(reasons, "world-writable")
+		}
+		if uid, ok := fileOwner(fi); ok && uid != 0 {
+			reasons = append(reasons, "owned by "+userName(uid))
+		}
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if why := insecureDir(dir); why != "" {
+			reasons = append(reasons, fmt.Sprintf("in %s %s", why, dir))
+			break
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// insecureDir returns why users other than root could replace files in dir,
+// or the empty string if they cannot.  World-writable directories with the
+// sticky bit set such as /tmp only let owners replace files.
+func insecureDir(dir string) string {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return ""
+	}
+	if fi.Mode().Perm()&0002 != 0 && fi.Mode()&os.ModeSticky == 0 {
+		return "world-writable directory"
+	}
+	if uid, ok := fileOwner(fi); ok && uid != 0 {
+		return "directory owned by " + userName(uid)
+	}
+	return ""
+}
+
+// userName returns the name of the user uid, or uid itself if unknown.
+func userName(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return "uid " + id
+}
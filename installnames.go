@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printInstallNames prints the direct dependencies of each binary of g as
+// otool -L does, under the install names it records, followed by the paths
+// they resolve to when different.  Each dependent shows the name it records
+// itself, which may differ from the one another dependent records.
+func printInstallNames(g *totool.Graph, label func(string) string) {
+	fmt.Printf("%s:\n", g.Name())
+	for _, bin := range g.Bins() {
+		deps := g.Dependencies(bin)
+		if len(deps) == 0 {
+			continue
+		}
+		fmt.Printf("\t%s:\n", label(bin))
+		for _, to := range deps {
+			line := label(to)
+			if name := g.InstallName(bin, to); name != "" && name != to {
+				line = name + " => " + line
+			}
+			fmt.Printf("\t\t%s\n", line)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printDirectDeps prints the union of the direct dependencies of bins, each
+// preceded by how many of bins depend on it.  It returns the first error met
+// inspecting bins after reporting it.
+func printDirectDeps(ctx context.Context, bins []string, opts totool.Options) error {
+	var first error
+	counts := make(map[string]int)
+	for _, bin := range bins {
+		abs, err := filepath.Abs(bin)
+		if err != nil {
+			log.Printf("%s: cannot get absolute path: %v", bin, err)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		deps, _, err := totool.InspectContext(ctx, abs, opts.Inspector, opts.Resolver)
+		if err != nil {
+			log.Printf("%s: %v", bin, err)
+			if first == nil {
+				first = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, d := range deps {
+			if !seen[d.Bin] {
+				seen[d.Bin] = true
+				counts[d.Bin]++
+			}
+		}
+	}
+
+	deps := make([]string, 0, len(counts))
+	for dep := range counts {
+		deps = append(deps, dep)
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		if counts[deps[i]] != counts[deps[j]] {
+			return counts[deps[i]] > counts[deps[j]]
+		}
+		return deps[i] < deps[j]
+	})
+	for _, dep := range deps {
+		fmt.Printf("%d\t%s\n", counts[dep], dep)
+	}
+	return first
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/nthery/totool/totool"
+)
+
+func init() {
+	totool.RegisterFormat("json", func(opts totool.PrinterOptions) totool.Printer {
+		return &jsonPrinter{nodes: opts.Nodes, edges: opts.Edges, hashes: opts.Hashes, licenses: opts.Licenses, sizes: opts.Sizes, label: opts.Label, skipped: opts.Skipped}
+	})
+}
+
+// jsonPrinter prints the dependency graph as a stream of JSON records, one per
+// line, as the graph is walked.
+type jsonPrinter struct {
+	// nodes and edges select which parts of the graph are printed.
+	nodes, edges bool
+
+	// hashes enables printing the SHA-256 digest of binaries.
+	hashes bool
+
+	// licenses enables printing the license of binaries.
+	licenses bool
+
+	// sizes enables printing the size of binaries, which takes a stat each.
+	sizes bool
+
+	// label returns how binaries are displayed, if not nil.
+	label func(string) string
+
+	// skipped lists the files scans left out, printed after the header.
+	skipped []totool.SkippedFile
+
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// jsonSchemaVersion is the version of the JSON output format described in
+// README.md.  It is bumped when fields are removed or change meaning, not when
+// they are added.
+const jsonSchemaVersion = 1
+
+// jsonRecord is a line of JSON output.  Kind is "header" for the first line,
+// "skipped" for files scans left out, "root" or "node" for binaries and "edge"
+// for direct dependencies.  Output predating jsonSchemaVersion has no header.
+type jsonRecord struct {
+	Kind          string `json:"kind"`
+	SchemaVersion int    `json:"schemaVersion,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	jsonNode
+	jsonEdge
+}
+
+// jsonGraph is a dependency graph as a single JSON document.  It is how -json
+// output was formatted before records were streamed and is still accepted as
+// input.
+type jsonGraph struct {
+	Roots []string   `json:"roots"`
+	Nodes []jsonNode `json:"nodes,omitempty"`
+	Edges []jsonEdge `json:"edges,omitempty"`
+}
+
+// jsonNode is the JSON representation of a binary.
+type jsonNode struct {
+	Path           string   `json:"path,omitempty"`
+	Label          string   `json:"label,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Info           string   `json:"info,omitempty"`
+	CompatVersion  string   `json:"compatVersion,omitempty"`
+	CurrentVersion string   `json:"currentVersion,omitempty"`
+	Weak           bool     `json:"weak,omitempty"`
+	Upward         bool     `json:"upward,omitempty"`
+	Attrs          []string `json:"attrs,omitempty"`
+	Depth          int      `json:"depth,omitempty"`
+	Class          string   `json:"class,omitempty"`
+	Parent         string   `json:"parent,omitempty"`
+	Truncated      bool     `json:"truncated,omitempty"`
+	Size           int64    `json:"size,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+	SHA256         string   `json:"sha256,omitempty"`
+	License        string   `json:"license,omitempty"`
+}
+
+// jsonEdge is the JSON representation of a direct dependency.
+type jsonEdge struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+func (p *jsonPrinter) PrintPrologue() {
+	if p.w == nil {
+		p.w = bufio.NewWriter(os.Stdout)
+		p.enc = json.NewEncoder(p.w)
+		p.write(jsonRecord{Kind: "header", SchemaVersion: jsonSchemaVersion})
+		for _, s := range p.skipped {
+			p.write(jsonRecord{Kind: "skipped", Reason: s.Reason, Detail: s.Detail, jsonNode: jsonNode{Path: s.Path, Label: p.labelOf(s.Path)}})
+		}
+	}
+}
+
+func (p *jsonPrinter) PrintEpilogue() {
+	if err := p.w.Flush(); err != nil {
+		log.Printf("cannot write JSON: %v", err)
+	}
+}
+
+func (p *jsonPrinter) PrintRootBin(bin string) {
+	p.write(jsonRecord{Kind: "root", jsonNode: jsonNode{Path: bin, Label: p.labelOf(bin), Class: totool.Classify(bin, bin).String(), Size: p.size(bin, 0), SHA256: p.hash(bin), License: p.license(bin)}})
+}
+
+func (p *jsonPrinter) PrintDepBin(d *totool.Dependency) {
+	if !p.nodes {
+		return
+	}
+	li := totool.ParseInfo(d.Info)
+	p.write(jsonRecord{Kind: "node", jsonNode: jsonNode{
+		Path:           d.Bin,
+		Label:          p.labelOf(d.Bin),
+		Name:           d.Name,
+		Info:           d.Info,
+		CompatVersion:  li.Compat,
+		CurrentVersion: li.Current,
+		Weak:           li.Weak,
+		Upward:         li.Upward,
+		Attrs:          li.Attrs,
+		Depth:          d.Depth,
+		Class:          d.Origin.String(),
+		Parent:         d.Parent,
+		Truncated:      d.Truncated,
+		Size:           p.size(d.Bin, d.Size),
+		SHA256:         p.hash(d.Bin),
+		License:        p.license(d.Bin),
+	}})
+}
+
+func (p *jsonPrinter) PrintDep(from, to string) {
+	if !p.edges {
+		return
+	}
+	p.write(jsonRecord{Kind: "edge", jsonEdge: jsonEdge{From: from, To: to}})
+}
+
+// write writes a single record, errors being reported when flushing.
+func (p *jsonPrinter) write(r jsonRecord) {
+	if err := p.enc.Encode(&r); err != nil {
+		log.Printf("cannot encode JSON: %v", err)
+	}
+}
+
+// labelOf returns the label of bin if it differs from its path.
+func (p *jsonPrinter) labelOf(bin string) string {
+	if p.label == nil {
+		return ""
+	}
+	if l := p.label(bin); l != bin {
+		return l
+	}
+	return ""
+}
+
+// size returns the size of bin if enabled, known already when not 0.
+func (p *jsonPrinter) size(bin string, known int64) int64 {
+	if !p.sizes {
+		return 0
+	}
+	if known != 0 {
+		return known
+	}
+	return totool.FileSize(bin)
+}
+
+// hash returns the digest of bin if enabled and available.
+func (p *jsonPrinter) hash(bin string) string {
+	if !p.hashes {
+		return ""
+	}
+	h, _ := totool.SHA256(bin)
+	return h
+}
+
+// license returns the license of bin if enabled and known.
+func (p *jsonPrinter) license(bin string) string {
+	if !p.licenses {
+		return ""
+	}
+	return totool.License(bin)
+}
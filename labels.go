@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// pathLabel returns the function displaying paths less the first of prefixes
+// they start with or, failing that, relative to dir if they are below it.
+// It returns nil if neither prefixes nor dir are given.
+func pathLabel(prefixes []string, dir string) (func(string) string, error) {
+	if len(prefixes) == 0 && dir == "" {
+		return nil, nil
+	}
+	if dir != "" {
+		var err error
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+	}
+	return func(path string) string {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) && len(path) > len(prefix) {
+				return strings.TrimPrefix(path, prefix)
+			}
+		}
+		if dir != "" && filepath.IsAbs(path) {
+			if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+				return rel
+			}
+		}
+		return path
+	}, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printLicenses prints the license of each binary of g that does not ship with
+// macOS, followed by how many have a license that could not be told.
+func printLicenses(g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	n, unknown := 0, 0
+	for _, node := range g.Nodes() {
+		if node.Origin == totool.OriginSystem {
+			continue
+		}
+		n++
+		l := totool.License(node.Path)
+		if l == "" {
+			unknown++
+			l = "unknown"
+		}
+		fmt.Printf("\t%s: %s\n", node.Path, l)
+	}
+	if unknown > 0 {
+		fmt.Printf("\tunknown license for %d of %d third-party binaries\n", unknown, n)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/nthery/totool/totool"
+)
+
+// logFormats lists the values of -log.
+var logFormats = []string{"text", "json", "xcode"}
+
+// Levels of log records.
+const (
+	logDebug   = "debug"   // resolution decisions and backend diagnostics
+	logWarning = "warning" // what looks wrong in graphs, the run goes on
+	logError   = "error"   // a root could not be walked, the run goes on
+	logFatal   = "fatal"   // the run stops
+)
+
+// A logRecord is a line of -log json output.  Empty fields are omitted.
+type logRecord struct {
+	Level   string `json:"level"`
+	Root    string `json:"root,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// jsonLog writes log records as JSON lines on the standard error when -log
+// json is set, nil otherwise.
+var jsonLog *jsonLogWriter
+
+// xcodeLog is set under -log xcode, records being printed as the diagnostics
+// Xcode shows in build logs when totool runs as a build phase script.
+var xcodeLog bool
+
+// xcodeLevels maps the levels of log records to Xcode diagnostics.
+var xcodeLevels = map[string]string{
+	logDebug:   "note",
+	logWarning: "warning",
+	logError:   "error",
+	logFatal:   "error",
+}
+
+// xcodeRe matches Xcode diagnostics.
+var xcodeRe = regexp.MustCompile(`^([^:]*: )?(note|warning|error): `)
+
+// xcodeLogWriter is the output of the log package under -log xcode, turning
+// the lines logged without a level into warnings.
+type xcodeLogWriter struct{ w io.Writer }
+
+func (w xcodeLogWriter) Write(b []byte) (int, error) {
+	if !xcodeRe.Match(b) {
+		b = append([]byte("warning: "), b...)
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// jsonLogWriter is the output of the log package under -log json, turning
+// the lines logged without a level into warning records.
+type jsonLogWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// setLogFormat makes log records be printed in format, one of logFormats.
+func setLogFormat(format string) error {
+	jsonLog, xcodeLog = nil, false
+	switch format {
+	case "text":
+	case "json":
+		jsonLog = &jsonLogWriter{enc: json.NewEncoder(os.Stderr)}
+		log.SetPrefix("")
+		log.SetOutput(jsonLog)
+	case "xcode":
+		xcodeLog = true
+		log.SetPrefix("")
+		log.SetOutput(xcodeLogWriter{os.Stderr})
+	default:
+		return fmt.Errorf("-log %s: want text, json or xcode", format)
+	}
+	return nil
+}
+
+func (w *jsonLogWriter) Write(b []byte) (int, error) {
+	w.write(logRecord{Level: logWarning, Message: strings.TrimSuffix(string(b), "\n")})
+	return len(b), nil
+}
+
+func (w *jsonLogWriter) write(r logRecord) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enc.Encode(r)
+}
+
+// logAt logs a record at level about path, a binary of the graph of root,
+// either of them possibly empty.  Text records are prefixed with root only,
+// the message being expected to name path.
+func logAt(level, root, path, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if level == logError || level == logFatal {
+		events.write(event{Event: "error", Root: root, Path: path, Message: msg})
+	}
+	if jsonLog != nil {
+		jsonLog.write(logRecord{Level: level, Root: root, Path: path, Message: msg})
+		return
+	}
+	if xcodeLog {
+		msg = xcodeLevels[level] + ": " + msg
+	}
+	if root != "" {
+		msg = root + ": " + msg
+	}
+	log.Print(msg)
+}
+
+// logRootError logs err, why root could not be walked, naming the binary
+// that could not be inspected if it is not root itself.
+func logRootError(root string, err error) {
+	path := root
+	var be *totool.BinError
+	if errors.As(err, &be) {
+		path = be.Bin
+		if be.Bin == root {
+			err = be.Err
+		}
+	}
+	logAt(logError, root, path, "%v", err)
+}
+
+// fatal logs v at the fatal level.  Callers return from run rather than exit
+// for its deferred calls to unmount disk images, remove temporary directories
+// and close the output.
+func fatal(v ...interface{}) {
+	logAt(logFatal, "", "", "%s", fmt.Sprint(v...))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A phase is a part of the run metrics report the time of.
+type phase struct {
+	name string
+	d    time.Duration
+}
+
+// phases returns the time spent so far in each phase of the run.  Binaries
+// being inspected concurrently, exec and parse may exceed discovery, the
+// elapsed time of walks.
+func phases() []phase {
+	exec, parse := totool.Times()
+	return []phase{
+		{"total", time.Since(startTime)},
+		{"discovery", time.Duration(atomic.LoadInt64(&walkTime))},
+		{"exec", exec},
+		{"parse", parse},
+		{"print", time.Duration(atomic.LoadInt64(&printTime))},
+	}
+}
+
+// writeMetrics writes the time of each phase of the run and how many binaries
+// each backend inspected and how many subprocesses ran into path, the
+// standard error if "-", in the Prometheus text format if path ends with
+// .prom.
+func writeMetrics(path string) error {
+	var w bytes.Buffer
+	backends, commands := totool.Counts()
+	if strings.HasSuffix(path, ".prom") {
+		writePrometheus(&w, backends, commands)
+	} else {
+		for _, p := range phases() {
+			fmt.Fprintf(&w, "%-10s %v\n", p.name+":", p.d)
+		}
+		for _, name := range sortedKeys(backends) {
+			fmt.Fprintf(&w, "backend %s: %d binaries\n", name, backends[name])
+		}
+		for _, name := range sortedKeys(commands) {
+			fmt.Fprintf(&w, "command %s: %d runs\n", name, commands[name])
+		}
+	}
+	if path == "-" {
+		_, err := os.Stderr.Write(w.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(path, w.Bytes(), 0644)
+}
+
+// writePrometheus writes metrics in the Prometheus text exposition format.
+func writePrometheus(w io.Writer, backends, commands map[string]int64) {
+	fmt.Fprintf(w, "# HELP totool_phase_seconds Time spent in each phase of the run.\n")
+	fmt.Fprintf(w, "# TYPE totool_phase_seconds gauge\n")
+	for _, p := range phases() {
+		fmt.Fprintf(w, "totool_phase_seconds{phase=%q} %g\n", p.name, p.d.Seconds())
+	}
+	fmt.Fprintf(w, "# HELP totool_backend_binaries_total Binaries inspected by each backend.\n")
+	fmt.Fprintf(w, "# TYPE totool_backend_binaries_total counter\n")
+	for _, name := range sortedKeys(backends) {
+		fmt.Fprintf(w, "totool_backend_binaries_total{backend=%q} %d\n", name, backends[name])
+	}
+	fmt.Fprintf(w, "# HELP totool_command_runs_total Subprocesses run, by command.\n")
+	fmt.Fprintf(w, "# TYPE totool_command_runs_total counter\n")
+	for _, name := range sortedKeys(commands) {
+		fmt.Fprintf(w, "totool_command_runs_total{command=%q} %d\n", name, commands[name])
+	}
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
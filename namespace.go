@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printNamespaces prints whether each binary of g uses a two-level or a flat
+// namespace and, in two-level namespace binaries, the library each undefined
+// symbol is bound to.  Missing libraries break flat namespace binaries only
+// when a symbol is used, two-level namespace ones when they are loaded.
+// Binaries that are not mach-o or live in the dyld shared cache only are
+// left out.
+func printNamespaces(g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	for _, bin := range g.Bins() {
+		if _, err := os.Stat(bin); err != nil {
+			continue
+		}
+		ns, err := totool.NamespaceOf(bin)
+		if err != nil {
+			continue
+		}
+		fmt.Printf("\t%s: %v\n", bin, ns)
+		if !ns.TwoLevel {
+			continue
+		}
+		imports, err := totool.Imports(bin)
+		if err != nil {
+			continue
+		}
+		// bound lists the symbols bound to each library, libs the
+		// libraries in the order first bound to.
+		var libs []string
+		bound := make(map[string][]string)
+		for _, imp := range imports {
+			lib := imp.Library
+			switch {
+			case imp.DynamicLookup:
+				lib = "dynamic lookup"
+			case imp.Executable:
+				lib = "main executable"
+			case lib == "":
+				lib = "no library recorded"
+			}
+			if _, ok := bound[lib]; !ok {
+				libs = append(libs, lib)
+			}
+			bound[lib] = append(bound[lib], imp.Name)
+		}
+		for _, lib := range libs {
+			fmt.Printf("\t\t%s: %s\n", lib, strings.Join(bound[lib], ", "))
+		}
+	}
+}
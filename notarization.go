@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printNotarization prints the Gatekeeper assessment of the roots of g and of
+// the binaries shipped along with them, then how many would be refused on a
+// fresh machine, which it returns.
+func printNotarization(ctx context.Context, g *totool.Graph) int {
+	fmt.Printf("%s:\n", g.Name())
+	n, rejected := 0, 0
+	for _, bin := range g.Bins() {
+		root := g.IsRoot(bin)
+		if !root && g.Origin(bin) != totool.OriginEmbedded {
+			continue
+		}
+		a, err := totool.Assess(ctx, bin, !root)
+		if err != nil {
+			log.Print(err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		n++
+		verdict := "accepted"
+		if !a.Accepted {
+			verdict = "rejected"
+			rejected++
+		}
+		switch {
+		case a.Source != "":
+			verdict += " (" + a.Source + ")"
+		case a.Reason != "":
+			verdict += " (" + a.Reason + ")"
+		}
+		fmt.Printf("\t%s: %s\n", bin, verdict)
+	}
+	fmt.Printf("\t%d of %d binaries would fail Gatekeeper\n", rejected, n)
+	return rejected
+}
@@ -0,0 +1,26 @@
+package main
+
+import "github.com/nthery/totool/totool"
+
+// nullPrinter prints nothing, for walks performed only to analyze the graph.
+type nullPrinter struct{}
+
+func (p nullPrinter) PrintPrologue() {
+	// nop
+}
+
+func (p nullPrinter) PrintEpilogue() {
+	// nop
+}
+
+func (p nullPrinter) PrintRootBin(bin string) {
+	// nop
+}
+
+func (p nullPrinter) PrintDepBin(d *totool.Dependency) {
+	// nop
+}
+
+func (p nullPrinter) PrintDep(from, to string) {
+	// nop
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// outputFormats maps the extensions of -o files to the formats they imply.
+// SVG files are rendered from the dot format by Graphviz.
+var outputFormats = map[string]string{
+	".dot":  "dot",
+	".gv":   "dot",
+	".svg":  "dot",
+	".json": "json",
+	".csv":  "csv",
+	".gob":  "gob",
+}
+
+// outputFormat returns the format implied by the extension of path, if any.
+func outputFormat(path string) (string, bool) {
+	format, ok := outputFormats[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// An output is a file standing for the standard output while it is open.
+type output struct {
+	f      *os.File
+	stdout *os.File
+
+	// dot renders what is written to f into an SVG file, if not nil.
+	dot *exec.Cmd
+}
+
+// createOutput creates the file path and makes it the standard output until
+// Close is called.  SVG files are written by dot -Tsvg, fed with what is
+// written to the standard output.
+func createOutput(path string) (*output, error) {
+	o := &output{stdout: os.Stdout}
+	if strings.ToLower(filepath.Ext(path)) == ".svg" {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		o.dot = exec.Command("dot", "-Tsvg", "-o", path)
+		o.dot.Stdin, o.dot.Stderr = r, os.Stderr
+		err = o.dot.Start()
+		r.Close()
+		if err != nil {
+			w.Close()
+			return nil, fmt.Errorf("%s: cannot run Graphviz: %v", path, err)
+		}
+		o.f = w
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		o.f = f
+	}
+	os.Stdout = o.f
+	return o, nil
+}
+
+// Close restores the standard output and closes the file, waiting for dot to
+// render it if need be.
+func (o *output) Close() error {
+	os.Stdout = o.stdout
+	err := o.f.Close()
+	if o.dot != nil {
+		if werr := o.dot.Wait(); werr != nil {
+			return fmt.Errorf("dot -Tsvg: %v", werr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startPager pipes the standard output through the pager PAGER names, less by
+// default, and returns the function restoring the standard output and waiting
+// for the user to quit the pager.  As with git, LESS defaults to FRX so that
+// less exits right away when the output fits the screen, shows colors and
+// leaves the output on the screen.
+func startPager() (stop func(), err error) {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	if pager == "cat" {
+		return func() {}, nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, os.Stdout, os.Stderr
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	err = cmd.Start()
+	r.Close()
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	return func() {
+		os.Stdout = stdout
+		w.Close()
+		cmd.Wait()
+	}, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A patcher changes binaries in place, printing each change, then signs the
+// binaries it changed ad hoc.
+type patcher struct {
+	ctx context.Context
+
+	// dryRun disables changes, which are only printed.
+	dryRun bool
+
+	// backup saves each binary as bin.orig before changing it the first
+	// time, unless an earlier run already did.
+	backup bool
+
+	changed map[string]bool
+
+	// names, ids and rpaths count the install names changed, the install
+	// names of libraries set and the run paths added.
+	names, ids, rpaths int
+}
+
+func newPatcher(ctx context.Context, dryRun, backup bool) *patcher {
+	return &patcher{ctx: ctx, dryRun: dryRun, backup: backup, changed: make(map[string]bool)}
+}
+
+// patch prints what, a change to bin, and makes it by calling fn.
+func (p *patcher) patch(bin, what string, fn func() error) error {
+	fmt.Printf("%s: %s\n", bin, what)
+	first := !p.changed[bin]
+	p.changed[bin] = true
+	if p.dryRun {
+		return nil
+	}
+	if first && p.backup {
+		if err := backupFile(bin); err != nil {
+			return err
+		}
+	}
+	defer totool.Forget(bin)
+	return fn()
+}
+
+// backupFile copies bin to bin.orig unless it exists, for the backup made
+// before the first change to be kept by later runs.
+func backupFile(bin string) error {
+	fi, err := os.Stat(bin)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(bin)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(bin+".orig", os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if os.IsExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(bin + ".orig")
+		return err
+	}
+	return out.Close()
+}
+
+// changeInstallName makes bin refer to its dependency old as new.
+func (p *patcher) changeInstallName(bin, old, new string) error {
+	p.names++
+	return p.patch(bin, "change "+old+" to "+new, func() error { return totool.ChangeInstallName(p.ctx, bin, old, new) })
+}
+
+// setInstallID sets the install name of bin, a library, to id.
+func (p *patcher) setInstallID(bin, id string) error {
+	p.ids++
+	return p.patch(bin, "set install name to "+id, func() error { return totool.SetInstallID(p.ctx, bin, id) })
+}
+
+// addRpath adds path to the run path search paths of bin unless already
+// there.
+func (p *patcher) addRpath(bin, path string) error {
+	if totool.HasRpath(bin, path) {
+		return nil
+	}
+	p.rpaths++
+	return p.patch(bin, "add run path "+path, func() error { return totool.AddRpath(p.ctx, bin, path) })
+}
+
+// sign signs the changed binaries ad hoc and returns how many there are.
+func (p *patcher) sign() (int, error) {
+	bins := make([]string, 0, len(p.changed))
+	for bin := range p.changed {
+		bins = append(bins, bin)
+	}
+	sort.Strings(bins)
+	for _, bin := range bins {
+		fmt.Printf("%s: sign ad hoc\n", bin)
+		if p.dryRun {
+			continue
+		}
+		if err := totool.AdHocSign(p.ctx, bin); err != nil {
+			return 0, err
+		}
+		totool.Forget(bin)
+	}
+	return len(bins), nil
+}
+
+// summary returns what the patcher changed or, in dry runs, would change.
+func (p *patcher) summary() string {
+	format := "%d install names changed, %d library install names set, %d run paths added and %d binaries signed"
+	if p.dryRun {
+		format = "%d install names to change, %d library install names to set, %d run paths to add and %d binaries to sign"
+	}
+	return fmt.Sprintf(format, p.names, p.ids, p.rpaths, len(p.changed))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A policy lists rules the dependencies of binaries must obey.  Policies are
+// read from files with one rule per line, a query as accepted by -query
+// preceded by "deny" for dependencies that must not be found or "require" for
+// dependencies that must be:
+//
+//	# No package manager paths in distributed binaries.
+//	deny path ~ "^(/usr/local|/opt/homebrew|/opt/local)/"
+//	deny name ~ "(?i)gpl"
+//	require name = "libcrypto.3.dylib"
+//
+// Blank lines and lines starting with "#" are ignored.
+type policy []policyRule
+
+type policyRule struct {
+	deny bool
+	q    query
+
+	// text and where describe the rule in reports.
+	text, where string
+}
+
+// readPolicy reads the policy file path.
+func readPolicy(path string) (policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p policy
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		where := fmt.Sprintf("%s:%d", path, n)
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || (fields[0] != "deny" && fields[0] != "require") {
+			return nil, fmt.Errorf("%s: expected deny or require followed by a query", where)
+		}
+		q, err := parseQuery(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", where, err)
+		}
+		p = append(p, policyRule{deny: fields[0] == "deny", q: q, text: line, where: where})
+	}
+	return p, s.Err()
+}
+
+// denied returns why p forbids d, or nil if it does not.
+func (p policy) denied(d *totool.Dependency) error {
+	for _, r := range p {
+		if r.deny && r.q.match(d) {
+			return fmt.Errorf("policy violation: %s (%s)", r.text, r.where)
+		}
+	}
+	return nil
+}
+
+// missing returns the require rules of p no dependency in deps matches.
+func (p policy) missing(deps []totool.Dependency) []policyRule {
+	var missing []policyRule
+	for _, r := range p {
+		if r.deny {
+			continue
+		}
+		found := false
+		for i := range deps {
+			if r.q.match(&deps[i]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nthery/totool/totool"
+)
+
+func TestReadPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+		err  string
+	}{
+		{
+			name: "rules",
+			in: `# No package manager paths in distributed binaries.
+deny path ~ "^(/usr/local|/opt/homebrew|/opt/local)/"
+
+  require name = "libcrypto.3.dylib"
+`,
+			want: []string{`deny 2 deny path ~ "^(/usr/local|/opt/homebrew|/opt/local)/"`, `require 4 require name = "libcrypto.3.dylib"`},
+		},
+		{name: "empty", in: "\n# nothing\n"},
+		{name: "no query", in: "deny", err: ":1: expected deny or require followed by a query"},
+		{name: "unknown verb", in: "# comment\nallow system", err: ":2: expected deny or require"},
+		{name: "bad query", in: "deny depth ~ 2", err: `:1: invalid operator "~" for depth in query`},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "policy")
+		if err := ioutil.WriteFile(path, []byte(tt.in), 0644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := readPolicy(path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, r := range p {
+			verb := "require"
+			if r.deny {
+				verb = "deny"
+			}
+			got = append(got, verb+" "+strings.TrimPrefix(r.where, path+":")+" "+r.text)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy")
+	rules := "deny path ~ ^/opt/local/\nrequire name = libcrypto.3.dylib\nrequire system\n"
+	if err := ioutil.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := readPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := []totool.Dependency{{Bin: "/usr/lib/libSystem.B.dylib"}, {Bin: "/opt/local/lib/libssl.1.1.dylib"}}
+	if err := p.denied(&deps[0]); err != nil {
+		t.Errorf("denied(%s) = %v, want nil", deps[0].Bin, err)
+	}
+	if err := p.denied(&deps[1]); err == nil || !strings.Contains(err.Error(), "policy violation: deny path ~ ^/opt/local/ ("+path+":1)") {
+		t.Errorf("denied(%s) = %v, want a violation of the first rule", deps[1].Bin, err)
+	}
+	missing := p.missing(deps)
+	if len(missing) != 1 || missing[0].text != "require name = libcrypto.3.dylib" {
+		t.Errorf("missing() = %v, want the libcrypto rule", missing)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printTime accumulates the time spent printing and walkTime the time spent
+// walking, printing included, in nanoseconds.
+var printTime, walkTime int64
+
+// startTime is when the run started.
+var startTime = time.Now()
+
+// addTime adds the time elapsed since start to the phase counter d.
+func addTime(d *int64, start time.Time) {
+	atomic.AddInt64(d, int64(time.Since(start)))
+}
+
+// printTimes prints the time spent in each phase of the run.
+func printTimes() {
+	exec, parse := totool.Times()
+	fmt.Fprintf(os.Stderr, "total: %v\n", time.Since(startTime))
+	fmt.Fprintf(os.Stderr, "exec:  %v\n", exec)
+	fmt.Fprintf(os.Stderr, "parse: %v\n", parse)
+	fmt.Fprintf(os.Stderr, "print: %v\n", time.Duration(atomic.LoadInt64(&printTime)))
+}
+
+// startCPUProfile starts writing a CPU profile into path and returns the
+// function stopping it.
+func startCPUProfile(path string) (func(), error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			log.Printf("cannot write CPU profile: %v", err)
+		}
+	}, nil
+}
+
+// writeMemProfile writes a heap profile into path.
+func writeMemProfile(path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("cannot write memory profile: %v", err)
+		return
+	}
+	defer f.Close()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		log.Printf("cannot write memory profile: %v", err)
+	}
+}
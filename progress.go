@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// progressInterval bounds how often the progress line is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// progressLine shows how walks progress on a terminal line redrawn in place.
+// It is also the output of the log package while shown, clearing the line
+// before each log line.
+type progressLine struct {
+	mu    sync.Mutex
+	w     io.Writer
+	width int
+	last  time.Time
+	shown bool
+}
+
+// newProgressLine returns a progress line drawn on w, a terminal as wide as
+// COLUMNS tells, 80 columns by default.
+func newProgressLine(w io.Writer) *progressLine {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width < 20 {
+		width = 80
+	}
+	return &progressLine{w: w, width: width}
+}
+
+// showProgress reports whether walks print a progress line on the standard
+// error: when it is a terminal the output does not go to.
+func showProgress() bool {
+	return os.Getenv("TERM") != "dumb" && isTerminal(os.Stderr) && !isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// update redraws the line, as Options.Progress.
+func (p *progressLine) update(inspected, queued int, current string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Sub(p.last) < progressInterval {
+		return
+	}
+	p.last = now
+	line := fmt.Sprintf("%d inspected, %d queued: ", inspected, queued)
+	// Keep the end of the path, which tells most.
+	if room := p.width - 1 - len(line); len(current) > room && room > 3 {
+		current = "..." + current[len(current)-room+3:]
+	}
+	fmt.Fprintf(p.w, "\r\x1b[K%s%s", line, current)
+	p.shown = true
+}
+
+// clear erases the line until the next update.
+func (p *progressLine) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+}
+
+func (p *progressLine) clearLocked() {
+	if p.shown {
+		fmt.Fprint(p.w, "\r\x1b[K")
+		p.shown = false
+	}
+}
+
+func (p *progressLine) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+	return p.w.Write(b)
+}
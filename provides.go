@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// providesMain implements the provides subcommand, printing the libraries of
+// the closure of a root that define a symbol along with the chain that brings
+// each in.  C symbols may be given without their leading underscore.
+func providesMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) != 2 {
+		return usagef("usage: totool provides file|graph.json symbol")
+	}
+	g, err := loadGraph(ctx, args[0], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	return printProviders(g, args[1])
+}
+
+// printProviders prints the libraries of the closure of the first root of g
+// that define sym along with the chain that brings each in.
+func printProviders(g *totool.Graph, sym string) error {
+	var found []string
+	unread := 0
+	for _, bin := range closureOf(g, g.Roots()[0])[1:] {
+		exports, err := totool.Exports(bin)
+		if err != nil {
+			unread++
+			continue
+		}
+		if exports[sym] || exports["_"+sym] {
+			found = append(found, bin)
+		}
+	}
+	for _, bin := range found {
+		fmt.Printf("%s\n\t%s\n", bin, strings.Join(g.Chain(bin), " -> "))
+	}
+	if len(found) == 0 {
+		if unread > 0 {
+			return fmt.Errorf("no library defines %s, %d could not be read", sym, unread)
+		}
+		return fmt.Errorf("no library defines %s", sym)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A qtPlugin is a kind of plugin Qt loads at run time for a module to work.
+type qtPlugin struct {
+	module string
+
+	// globs match the plugins that fit, relative to the plugin directory.
+	globs []string
+
+	// optional is set for plugins without which the module still works,
+	// with fewer features.
+	optional bool
+
+	what string
+}
+
+// qtPlugins lists the plugins macdeployqt deploys for each Qt module.
+var qtPlugins = []qtPlugin{
+	{"QtGui", []string{"platforms/libqcocoa*.dylib"}, false, "cocoa platform plugin"},
+	{"QtGui", []string{"imageformats/*.dylib"}, true, "image format plugins"},
+	{"QtWidgets", []string{"styles/libqmacstyle*.dylib"}, false, "macOS style plugin"},
+	{"QtSvg", []string{"imageformats/libqsvg*.dylib"}, false, "SVG image format plugin"},
+	{"QtSvg", []string{"iconengines/libqsvgicon*.dylib"}, true, "SVG icon engine plugin"},
+	{"QtNetwork", []string{"tls/*.dylib", "bearer/*.dylib"}, true, "TLS or bearer plugins"},
+	{"QtSql", []string{"sqldrivers/*.dylib"}, false, "SQL driver plugins"},
+	{"QtMultimedia", []string{"multimedia/*.dylib", "mediaservice/*.dylib"}, false, "multimedia plugins"},
+	{"QtPositioning", []string{"position/*.dylib"}, false, "position plugins"},
+}
+
+// qtLibRe matches the Qt libraries not packaged as frameworks.
+//
+//	libQt5Gui.5.dylib
+var qtLibRe = regexp.MustCompile(`^libQt\d(\w+?)(_debug)?(\.\d+)*\.dylib$`)
+
+// qtModule returns the name of the Qt module bin is the library of, if any.
+func qtModule(bin string) (string, bool) {
+	for _, part := range strings.Split(bin, "/") {
+		if strings.HasPrefix(part, "Qt") && strings.HasSuffix(part, ".framework") {
+			return strings.TrimSuffix(part, ".framework"), true
+		}
+	}
+	if sms := qtLibRe.FindStringSubmatch(filepath.Base(bin)); sms != nil {
+		return "Qt" + sms[1], true
+	}
+	return "", false
+}
+
+// printQt prints, for each root of g using Qt and living in an app bundle, the
+// plugins and QML modules that Qt loads at run time and the bundle lacks, then
+// the dependencies of the deployed plugins that are missing or lie outside of
+// the bundle.  QML imports are read from the .qml files of the bundle, those
+// compiled into resources are not seen.  It returns how many gaps it found.
+func printQt(ctx context.Context, g *totool.Graph, opts totool.Options) int {
+	fmt.Printf("%s:\n", g.Name())
+	n := 0
+	for _, root := range g.Roots() {
+		modules := make(map[string]bool)
+		for _, bin := range closureOf(g, root) {
+			if m, ok := qtModule(bin); ok {
+				modules[m] = true
+			}
+		}
+		if len(modules) == 0 {
+			fmt.Printf("\t%s: does not use Qt\n", root)
+			continue
+		}
+		app := totool.AppBundle(root)
+		if app == "" {
+			fmt.Printf("\t%s: not in an app bundle, nothing to check\n", root)
+			continue
+		}
+		gaps := 0
+		gap := func(format string, args ...interface{}) {
+			gaps++
+			fmt.Printf("\t%s: %s\n", root, fmt.Sprintf(format, args...))
+		}
+
+		pluginDir, qmlDir, err := qtDirs(app)
+		if err != nil {
+			gap("%v", err)
+		}
+		var plugins []string
+		for _, p := range qtPlugins {
+			if !modules[p.module] {
+				continue
+			}
+			var found []string
+			for _, glob := range p.globs {
+				matches, _ := filepath.Glob(filepath.Join(pluginDir, glob))
+				found = append(found, matches...)
+			}
+			plugins = append(plugins, found...)
+			switch {
+			case len(found) > 0:
+			case p.optional:
+				fmt.Printf("\t%s: note: no %s for %s in %s\n", root, p.what, p.module, pluginDir)
+			default:
+				gap("no %s for %s in %s", p.what, p.module, pluginDir)
+			}
+		}
+
+		if modules["QtQml"] {
+			imports, err := qmlImports(app)
+			if err != nil {
+				gap("%v", err)
+			}
+			if len(imports) == 0 {
+				fmt.Printf("\t%s: note: no .qml files, imports of QML compiled into resources not checked\n", root)
+			}
+			libs, missing := qmlModules(qmlDir, imports)
+			for _, m := range missing {
+				gap("QML module %s not found in %s", m, qmlDir)
+			}
+			plugins = append(plugins, libs...)
+		}
+
+		for _, p := range plugins {
+			for _, err := range pluginGaps(ctx, app, p, opts) {
+				gap("plugin %s: %v", p, err)
+			}
+			if ctx.Err() != nil {
+				return n + gaps
+			}
+		}
+		if gaps == 0 {
+			fmt.Printf("\t%s: Qt deployment complete\n", root)
+		}
+		n += gaps
+	}
+	return n
+}
+
+// qtDirs returns the directories Qt loads the plugins and QML modules of the
+// executables of app from, as set by Contents/Resources/qt.conf.  Relative
+// paths are relative to the Contents directory.
+func qtDirs(app string) (plugins, qml string, err error) {
+	contents := filepath.Join(app, "Contents")
+	paths := map[string]string{"Prefix": ".", "Plugins": "PlugIns", "Qml2Imports": "Resources/qml"}
+	conf := filepath.Join(contents, "Resources", "qt.conf")
+	f, err := os.Open(conf)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("no %s, Qt looks for plugins where it was built", conf)
+	} else if err == nil {
+		defer f.Close()
+		group := ""
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			line := strings.TrimSpace(s.Text())
+			if strings.HasPrefix(line, "[") {
+				group = strings.Trim(line, "[]")
+			} else if i := strings.Index(line, "="); i >= 0 && group == "Paths" {
+				paths[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			}
+		}
+		err = s.Err()
+	}
+	rel := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(contents, paths["Prefix"], path)
+	}
+	return rel(paths["Plugins"]), rel(paths["Qml2Imports"]), err
+}
+
+// qmlImportRe matches the imports of QML modules, leaving out imports of
+// directories and JavaScript files, which are quoted.
+//
+//	import QtQuick.Controls 2.15
+var qmlImportRe = regexp.MustCompile(`^\s*import\s+([A-Za-z_][\w.]*)`)
+
+// qmlImports returns the QML modules imported by the .qml files of app.
+func qmlImports(app string) ([]string, error) {
+	seen := make(map[string]bool)
+	var imports []string
+	err := filepath.Walk(app, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || filepath.Ext(path) != ".qml" {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if sms := qmlImportRe.FindStringSubmatch(s.Text()); sms != nil && !seen[sms[1]] {
+				seen[sms[1]] = true
+				imports = append(imports, sms[1])
+			}
+		}
+		return s.Err()
+	})
+	return imports, err
+}
+
+// qmlBuiltins lists the QML modules built into the QML engine.
+var qmlBuiltins = map[string]bool{"QtQml": true}
+
+// qmlModules looks the QML modules imports and their own dependencies up in
+// dir and returns the plugins of those found and the names of those that are
+// not.
+func qmlModules(dir string, imports []string) (plugins, missing []string) {
+	seen := make(map[string]bool)
+	for len(imports) > 0 {
+		m := imports[0]
+		imports = imports[1:]
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		mdir := qmlModuleDir(dir, m)
+		if mdir == "" {
+			if !qmlBuiltins[m] {
+				missing = append(missing, m)
+			}
+			continue
+		}
+		libs, deps := readQmldir(mdir)
+		plugins = append(plugins, libs...)
+		imports = append(imports, deps...)
+	}
+	sort.Strings(missing)
+	return plugins, missing
+}
+
+// qmlModuleDir returns the directory of the QML module m below dir, possibly
+// suffixed with a version, or an empty string if there is none.
+func qmlModuleDir(dir, m string) string {
+	path := filepath.Join(dir, filepath.FromSlash(strings.Replace(m, ".", "/", -1)))
+	if _, err := os.Stat(filepath.Join(path, "qmldir")); err == nil {
+		return path
+	}
+	matches, _ := filepath.Glob(path + ".*/qmldir")
+	if len(matches) > 0 {
+		return filepath.Dir(matches[0])
+	}
+	return ""
+}
+
+// readQmldir returns the plugins the qmldir file of the QML module in dir
+// declares, existing or not, and the modules it depends on or imports.
+func readQmldir(dir string) (plugins, deps []string) {
+	f, err := os.Open(filepath.Join(dir, "qmldir"))
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "plugin", "optional":
+			if fields[0] == "optional" {
+				if len(fields) < 3 || fields[1] != "plugin" {
+					continue
+				}
+				fields = fields[1:]
+			}
+			pdir := dir
+			if len(fields) > 2 {
+				pdir = filepath.Join(dir, fields[2])
+			}
+			plugins = append(plugins, filepath.Join(pdir, "lib"+fields[1]+".dylib"))
+		case "depends", "import":
+			deps = append(deps, fields[1])
+		}
+	}
+	return plugins, deps
+}
+
+// pluginGaps walks the dependencies of plugin, a library Qt loads at run time
+// from app, and returns the problems of those that are missing or neither
+// ship with macOS nor live in app.
+func pluginGaps(ctx context.Context, app, plugin string, opts totool.Options) []error {
+	if err := checkReadable(plugin); err != nil {
+		return []error{err}
+	}
+	var errs []error
+	_, err := totool.VisitContext(ctx, plugin, &totool.Visitor{
+		OnNode: func(d *totool.Dependency) {
+			switch {
+			case d.Depth == 0 || totool.IsSystemBin(d.Bin):
+			case isUnresolvedRpath(d.Bin):
+				errs = append(errs, fmt.Errorf("%s not found, loaded by %s", d.Bin, d.Parent))
+			case !strings.HasPrefix(d.Bin, app+"/"):
+				errs = append(errs, fmt.Errorf("%s outside of %s", d.Bin, app))
+			}
+		},
+		OnError: func(bin string, err error) error {
+			if ctx.Err() != nil {
+				return err
+			}
+			// Unresolved and outside dependencies are reported by OnNode.
+			if errors.Is(err, totool.ErrMissingFile) && strings.HasPrefix(bin, app+"/") {
+				errs = append(errs, fmt.Errorf("%s: %v", bin, err))
+			}
+			return nil
+		},
+	}, opts)
+	if err != nil && ctx.Err() == nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
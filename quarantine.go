@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printQuarantined prints the binaries of g carrying the quarantine attribute
+// along with the chain that brings them in, as Gatekeeper may refuse to load
+// them.  It returns how many there are.
+func printQuarantined(ctx context.Context, g *totool.Graph) int {
+	fmt.Printf("%s:\n", g.Name())
+	n := 0
+	for _, bin := range g.Bins() {
+		if _, err := os.Stat(bin); err != nil && totool.IsSystemBin(bin) {
+			continue
+		}
+		q, err := totool.Quarantine(ctx, bin)
+		if err != nil {
+			log.Print(err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if q == "" {
+			continue
+		}
+		n++
+		fmt.Printf("\t%s: quarantined (%s)\n", bin, q)
+		if chain := g.Chain(bin); len(chain) > 1 {
+			fmt.Printf("\t\t%s\n", strings.Join(chain, " -> "))
+		}
+	}
+	if n == 0 {
+		fmt.Printf("\tno quarantined binaries\n")
+	}
+	return n
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// A query selects binaries from their attributes.  Queries are parsed from
+// expressions such as:
+//
+//	depth <= 2 and not system
+//	name ~ "^libssl" or (path ~ /opt/ and version < 1.1)
+//
+// The following attributes are supported:
+//
+//	path     absolute path of the binary (=, !=, ~, !~)
+//	name     base name of the binary (=, !=, ~, !~)
+//	depth    length of the shortest chain from the root (=, !=, <, <=, >, >=)
+//	version  current version (=, !=, <, <=, >, >=)
+//	compat   compatibility version (=, !=, <, <=, >, >=)
+//	class    origin of the binary: system, package, embedded or other (=, !=, ~, !~)
+//	system   true for binaries shipped with macOS
+//
+// "~" matches against a regular expression.  Terms are combined with "and",
+// "or", "not" and parentheses.
+type query interface {
+	match(d *totool.Dependency) bool
+}
+
+type andQuery struct{ l, r query }
+
+func (q andQuery) match(d *totool.Dependency) bool { return q.l.match(d) && q.r.match(d) }
+
+type orQuery struct{ l, r query }
+
+func (q orQuery) match(d *totool.Dependency) bool { return q.l.match(d) || q.r.match(d) }
+
+type notQuery struct{ q query }
+
+func (q notQuery) match(d *totool.Dependency) bool { return !q.q.match(d) }
+
+type systemQuery struct{}
+
+func (q systemQuery) match(d *totool.Dependency) bool { return totool.IsSystemBin(d.Bin) }
+
+// stringQuery compares a string attribute against a value.
+type stringQuery struct {
+	attr  func(d *totool.Dependency) string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (q stringQuery) match(d *totool.Dependency) bool {
+	v := q.attr(d)
+	switch q.op {
+	case "=":
+		return v == q.value
+	case "!=":
+		return v != q.value
+	case "~":
+		return q.re.MatchString(v)
+	default: // "!~"
+		return !q.re.MatchString(v)
+	}
+}
+
+// orderedQuery compares an ordered attribute against a value.
+type orderedQuery struct {
+	// cmp compares the attribute of d against the value and returns false if
+	// d has no such attribute.
+	cmp func(d *totool.Dependency) (int, bool)
+	op  string
+}
+
+func (q orderedQuery) match(d *totool.Dependency) bool {
+	c, ok := q.cmp(d)
+	if !ok {
+		return false
+	}
+	switch q.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default: // ">="
+		return c >= 0
+	}
+}
+
+// parseQuery parses the query expression s.
+func parseQuery(s string) (query, error) {
+	toks, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := queryParser{toks: toks}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q in query", p.toks[p.pos].text)
+	}
+	return q, nil
+}
+
+// queryToken is a lexical element of a query.
+type queryToken struct {
+	text string
+
+	// quoted is set for string literals, which are never operators or keywords.
+	quoted bool
+}
+
+func (t queryToken) is(s string) bool {
+	return !t.quoted && t.text == s
+}
+
+// tokenizeQuery splits s into tokens.
+func tokenizeQuery(s string) ([]queryToken, error) {
+	var toks []queryToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			toks = append(toks, queryToken{text: s[i : i+1]})
+			i++
+		case strings.IndexByte("=!<>~", c) >= 0:
+			j := i + 1
+			for j < len(s) && strings.IndexByte("=~", s[j]) >= 0 {
+				j++
+			}
+			toks = append(toks, queryToken{text: s[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string in query")
+			}
+			toks = append(toks, queryToken{text: s[i+1 : i+1+j], quoted: true})
+			i += j + 2
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t()=!<>~\"'", s[j]) < 0 {
+				j++
+			}
+			toks = append(toks, queryToken{text: s[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// queryParser is a recursive descent parser for queries.
+type queryParser struct {
+	toks []queryToken
+	pos  int
+}
+
+// next consumes the next token if it is s.
+func (p *queryParser) next(s string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].is(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (query, error) {
+	q, err := p.parseAnd()
+	for err == nil && p.next("or") {
+		var r query
+		r, err = p.parseAnd()
+		q = orQuery{q, r}
+	}
+	return q, err
+}
+
+func (p *queryParser) parseAnd() (query, error) {
+	q, err := p.parseNot()
+	for err == nil && p.next("and") {
+		var r query
+		r, err = p.parseNot()
+		q = andQuery{q, r}
+	}
+	return q, err
+}
+
+func (p *queryParser) parseNot() (query, error) {
+	if p.next("not") {
+		q, err := p.parseNot()
+		return notQuery{q}, err
+	}
+	return p.parseTerm()
+}
+
+func (p *queryParser) parseTerm() (query, error) {
+	if p.next("(") {
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(")") {
+			return nil, fmt.Errorf("missing ) in query")
+		}
+		return q, nil
+	}
+
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	attr := p.toks[p.pos].text
+	p.pos++
+	if attrQuery, ok := boolAttrs[attr]; ok {
+		return attrQuery, nil
+	}
+
+	if p.pos+1 >= len(p.toks) {
+		return nil, fmt.Errorf("missing comparison after %q in query", attr)
+	}
+	op, value := p.toks[p.pos].text, p.toks[p.pos+1].text
+	p.pos += 2
+
+	if f, ok := stringAttrs[attr]; ok {
+		q := stringQuery{attr: f, op: op, value: value}
+		switch op {
+		case "=", "!=":
+		case "~", "!~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression in query: %v", err)
+			}
+			q.re = re
+		default:
+			return nil, fmt.Errorf("invalid operator %q for %s in query", op, attr)
+		}
+		return q, nil
+	}
+
+	switch attr {
+	case "depth", "version", "compat":
+		if !isOrderedOp(op) {
+			return nil, fmt.Errorf("invalid operator %q for %s in query", op, attr)
+		}
+	default:
+		return nil, fmt.Errorf("unknown attribute %q in query", attr)
+	}
+	switch attr {
+	case "depth":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid depth %q in query", value)
+		}
+		return orderedQuery{func(d *totool.Dependency) (int, bool) { return d.Depth - n, true }, op}, nil
+	default: // "version", "compat"
+		return orderedQuery{func(d *totool.Dependency) (int, bool) {
+			compat, current, ok := totool.ParseVersions(d.Info)
+			if attr == "compat" {
+				current = compat
+			}
+			return totool.CompareVersions(current, value), ok
+		}, op}, nil
+	}
+}
+
+// boolAttrs are the attributes usable as standalone predicates.
+var boolAttrs = map[string]query{
+	"system": systemQuery{},
+}
+
+// stringAttrs are the attributes compared as strings.
+var stringAttrs = map[string]func(d *totool.Dependency) string{
+	"path":  func(d *totool.Dependency) string { return d.Bin },
+	"name":  func(d *totool.Dependency) string { return filepath.Base(d.Bin) },
+	"class": func(d *totool.Dependency) string { return d.Origin.String() },
+}
+
+func isOrderedOp(op string) bool {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
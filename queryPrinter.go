@@ -0,0 +1,60 @@
+package main
+
+import "github.com/nthery/totool/totool"
+
+// queryPrinter forwards to another printer the subgraph made of the roots and
+// the binaries matching a query.  As whether a dependency matches is known only
+// once its target is reached, output is delayed until the walk completes.
+type queryPrinter struct {
+	pt totool.Printer
+	q  query
+
+	events []queryEvent
+}
+
+// queryEvent is a buffered printer call.
+type queryEvent struct {
+	root     bool
+	d        totool.Dependency
+	from, to string
+}
+
+func (p *queryPrinter) PrintPrologue() {
+	p.pt.PrintPrologue()
+}
+
+func (p *queryPrinter) PrintEpilogue() {
+	kept := make(map[string]bool)
+	for _, e := range p.events {
+		if e.root || (e.d.Bin != "" && p.q.match(&e.d)) {
+			kept[e.d.Bin] = true
+		}
+	}
+
+	for _, e := range p.events {
+		switch {
+		case e.root:
+			p.pt.PrintRootBin(e.d.Bin)
+		case e.d.Bin != "":
+			if kept[e.d.Bin] {
+				p.pt.PrintDepBin(&e.d)
+			}
+		case kept[e.from] && kept[e.to]:
+			p.pt.PrintDep(e.from, e.to)
+		}
+	}
+	p.events = nil
+	p.pt.PrintEpilogue()
+}
+
+func (p *queryPrinter) PrintRootBin(bin string) {
+	p.events = append(p.events, queryEvent{root: true, d: totool.Dependency{Bin: bin}})
+}
+
+func (p *queryPrinter) PrintDepBin(d *totool.Dependency) {
+	p.events = append(p.events, queryEvent{d: *d})
+}
+
+func (p *queryPrinter) PrintDep(from, to string) {
+	p.events = append(p.events, queryEvent{from: from, to: to})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nthery/totool/totool"
+)
+
+func TestQuery(t *testing.T) {
+	libSystem := totool.Dependency{Bin: "/usr/lib/libSystem.B.dylib", Depth: 1, Origin: totool.Classify("/app/R", "/usr/lib/libSystem.B.dylib"), Info: "(compatibility version 1.0.0, current version 1311.0.0)"}
+	libssl := totool.Dependency{Bin: "/opt/local/lib/libssl.1.1.dylib", Depth: 2, Origin: totool.Classify("/app/R", "/opt/local/lib/libssl.1.1.dylib"), Info: "(compatibility version 1.1.0, current version 1.1.1)"}
+	libA := totool.Dependency{Bin: "/app/libA.dylib", Depth: 3, Origin: totool.Classify("/app/R", "/app/libA.dylib")}
+	deps := map[string]totool.Dependency{"libSystem": libSystem, "libssl": libssl, "libA": libA}
+
+	tests := []struct {
+		q    string
+		want []string
+		err  string
+	}{
+		{q: "system", want: []string{"libSystem"}},
+		{q: "not system", want: []string{"libA", "libssl"}},
+		{q: "depth <= 2 and not system", want: []string{"libssl"}},
+		{q: "depth > 1", want: []string{"libA", "libssl"}},
+		{q: "depth != 2", want: []string{"libA", "libSystem"}},
+		{q: `name ~ "^libssl"`, want: []string{"libssl"}},
+		{q: `name = libA.dylib`, want: []string{"libA"}},
+		{q: `path !~ /opt/`, want: []string{"libA", "libSystem"}},
+		{q: `name ~ "^libssl" or (path ~ /usr/ and version >= 1000)`, want: []string{"libSystem", "libssl"}},
+		{q: "not (system or depth = 3)", want: []string{"libssl"}},
+		{q: "version < 1.1.2", want: []string{"libssl"}},
+		{q: "compat = 1.1", want: []string{"libssl"}},
+		{q: "class = package", want: []string{"libssl"}},
+		{q: "class != system and class != package", want: []string{"libA"}},
+		{q: `name = "and"`, want: nil},
+		{q: "", err: "unexpected end of query"},
+		{q: "depth", err: `missing comparison after "depth"`},
+		{q: "depth ~ 2", err: `invalid operator "~" for depth`},
+		{q: "name < a", err: `invalid operator "<" for name`},
+		{q: "size > 2", err: `unknown attribute "size"`},
+		{q: "depth = two", err: `invalid depth "two"`},
+		{q: `name ~ "("`, err: "invalid regular expression"},
+		{q: `name = "libA`, err: "unterminated string"},
+		{q: "(system", err: "missing )"},
+		{q: "system system", err: `unexpected "system"`},
+	}
+	for _, tt := range tests {
+		q, err := parseQuery(tt.q)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseQuery(%q) error = %v, want one containing %q", tt.q, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseQuery(%q): %v", tt.q, err)
+			continue
+		}
+		var got []string
+		for _, name := range []string{"libA", "libSystem", "libssl"} {
+			d := deps[name]
+			if q.match(&d) {
+				got = append(got, name)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q matches %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// remoteMain runs totool on host over ssh with args, the command line less
+// -remote, streaming its output back, and returns its exit status.  The
+// running executable is shipped to host, once per version, if host runs the
+// same system and architecture.  Otherwise the totool found in the PATH of
+// host is run.  File arguments are paths on host.
+func remoteMain(ctx context.Context, host string, args []string) int {
+	exe, err := remoteExecutable(ctx, host)
+	if err != nil {
+		log.Printf("%s: %v", host, err)
+		return exitInspection
+	}
+	quoted := []string{exe}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	cmd := exec.CommandContext(ctx, "ssh", host, strings.Join(quoted, " "))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	err = cmd.Run()
+	var ee *exec.ExitError
+	switch {
+	case err == nil:
+		return exitOK
+	case ctx.Err() != nil:
+		return exitCanceled
+	case errors.As(err, &ee) && ee.ExitCode() > 0 && ee.ExitCode() != 255:
+		// ssh exits with the status of the remote command, 255 reporting
+		// its own failures.
+		return ee.ExitCode()
+	default:
+		log.Printf("ssh %s: %v", host, err)
+		return exitInspection
+	}
+}
+
+// remoteExecutable returns the command running totool on host, shipping the
+// running executable there unless host runs another system or architecture.
+func remoteExecutable(ctx context.Context, host string) (string, error) {
+	out, err := sshOutput(ctx, host, "uname -sm", nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot tell system: %v", err)
+	}
+	if fields := strings.Fields(out); len(fields) != 2 || !sameArch(fields[0], fields[1]) {
+		if _, err := sshOutput(ctx, host, "command -v totool", nil); err != nil {
+			return "", fmt.Errorf("runs %s, cannot ship a %s/%s totool and no totool in PATH", strings.TrimSpace(out), runtime.GOOS, runtime.GOARCH)
+		}
+		return "totool", nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(self)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	// The binary goes to a directory only the ssh user can write to, what is
+	// already there being run unchecked.  A shared directory like /tmp would
+	// let other users plant it.  The remote shell expands the variables, the
+	// name is quoted as is.
+	dir := `"${XDG_CACHE_HOME:-$HOME/.cache}"/totool`
+	path := dir + "/totool-" + hex.EncodeToString(h.Sum(nil))[:16]
+	script := fmt.Sprintf(`mkdir -p %s && chmod 700 %s && p=%s && { test -x "$p" || { cat >"$p.$$" && chmod 700 "$p.$$" && mv "$p.$$" "$p"; }; }`, dir, dir, path)
+	if _, err := sshOutput(ctx, host, script, f); err != nil {
+		return "", fmt.Errorf("cannot ship totool: %v", err)
+	}
+	return path, nil
+}
+
+// sameArch reports whether a system printing sys and machine for "uname -sm"
+// runs the binaries of the running executable.
+func sameArch(sys, machine string) bool {
+	goos := map[string]string{"Darwin": "darwin", "Linux": "linux"}[sys]
+	goarch := map[string]string{"x86_64": "amd64", "arm64": "arm64", "aarch64": "arm64"}[machine]
+	return goos == runtime.GOOS && goarch == runtime.GOARCH
+}
+
+// sshOutput runs script on host with stdin as standard input, if not nil, and
+// returns its standard output.
+func sshOutput(ctx context.Context, host, script string, stdin io.Reader) (string, error) {
+	cmd := exec.CommandContext(ctx, "ssh", host, script)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// withoutFlag returns args, the flags of a command line, less the flag name
+// and its value.
+func withoutFlag(args []string, name string) []string {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimLeft(args[i], "-")
+		switch {
+		case !strings.HasPrefix(args[i], "-"):
+		case arg == name:
+			i++
+			continue
+		case strings.HasPrefix(arg, name+"="):
+			continue
+		}
+		rest = append(rest, args[i])
+	}
+	return rest
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// replHelp describes the commands of the repl subcommand.
+const replHelp = `why lib        print the shortest chain bringing in lib and its dependents
+paths lib      print all the chains from the root to lib
+provides sym   print the libraries defining sym
+stats          print graph statistics
+help           print this message
+quit           exit
+Libraries are given by path or by a unique part of it.`
+
+// replMain implements the repl subcommand, walking a root once and answering
+// queries about its graph read from the standard input, one per line.
+func replMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) != 1 {
+		return usagef("usage: totool repl file|graph.json")
+	}
+	g, err := loadGraph(ctx, args[0], opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	prompt := ""
+	if isTerminal(os.Stdin) {
+		prompt = "totool> "
+		fmt.Printf("%s: %d binaries, %d dependencies; type help for commands\n", g.Name(), g.Size(), g.EdgeCount())
+	}
+	s := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print(prompt)
+		if !s.Scan() {
+			break
+		}
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+		if err := replCommand(g, fields[0], fields[1:]); err != nil {
+			log.Print(err)
+		}
+	}
+	if prompt != "" {
+		fmt.Println()
+	}
+	return s.Err()
+}
+
+// replCommand runs the repl command cmd with args on g.
+func replCommand(g *totool.Graph, cmd string, args []string) error {
+	switch cmd {
+	case "why", "paths":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s lib", cmd)
+		}
+		bin, err := findBin(g, args[0])
+		if err != nil {
+			return err
+		}
+		if cmd == "paths" {
+			for _, p := range g.Paths(g.Roots()[0], bin) {
+				fmt.Println(strings.Join(p, " -> "))
+			}
+			return nil
+		}
+		fmt.Println(strings.Join(g.Chain(bin), " -> "))
+		for _, dep := range g.Dependents(bin) {
+			fmt.Printf("\t<- %s\n", dep)
+		}
+		return nil
+	case "provides":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: provides sym")
+		}
+		return printProviders(g, args[0])
+	case "stats":
+		printStats(g)
+		return nil
+	case "help":
+		fmt.Println(replHelp)
+		return nil
+	default:
+		return fmt.Errorf("%s: unknown command, type help for commands", cmd)
+	}
+}
+
+// findBin returns the binary of g whose path is name or the only one whose
+// path contains name.
+func findBin(g *totool.Graph, name string) (string, error) {
+	if _, ok := g.Node(name); ok {
+		return name, nil
+	}
+	var found []string
+	for _, bin := range g.Bins() {
+		if strings.Contains(bin, name) {
+			found = append(found, bin)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%s: not in graph", name)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%s: ambiguous: %s", name, strings.Join(found, ", "))
+	}
+}
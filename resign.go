@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// signBundle signs the code of app inside out with identity, starting with
+// extra, code to be added to app.  With dryRun set or without identity, it
+// prints the codesign commands to run instead.
+func signBundle(ctx context.Context, app string, extra []string, identity string, dryRun bool) error {
+	order, err := totool.SigningOrder(app)
+	if err != nil {
+		return err
+	}
+	order = append(extra, order...)
+	if identity == "" || dryRun {
+		id := `"$IDENTITY"`
+		if identity != "" {
+			id = shellQuote(identity)
+		}
+		fmt.Printf("sign %s inside out with:\n", app)
+		for _, path := range order {
+			fmt.Printf("\tcodesign --force --sign %s --preserve-metadata=identifier,entitlements,flags %s\n", id, shellQuote(path))
+		}
+		return nil
+	}
+	for _, path := range order {
+		fmt.Printf("%s: sign as %s\n", path, identity)
+		if err := totool.Sign(ctx, path, identity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for POSIX shells if needed.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./-_") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// readRootList returns the paths r lists, one per line or, with nul set, NUL-
+// terminated as "find -print0" writes them.  Empty entries are skipped.
+func readRootList(r io.Reader, nul bool) ([]string, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	if nul {
+		s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			if i := bytes.IndexByte(data, 0); i >= 0 {
+				return i + 1, data[:i], nil
+			}
+			if atEOF && len(data) > 0 {
+				return len(data), data, nil
+			}
+			return 0, nil, nil
+		})
+	}
+	var paths []string
+	for s.Scan() {
+		p := s.Text()
+		if !nul {
+			p = strings.TrimSuffix(p, "\r")
+		}
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, s.Err()
+}
+
+// expandRootLists replaces "-" in args with the paths read from the standard
+// input and appends those listed in the files lists, "-" among them standing
+// for the standard input too.  The standard input can be read once only.
+func expandRootLists(args, lists []string, nul bool) ([]string, error) {
+	stdinRead := false
+	readStdin := func() ([]string, error) {
+		if stdinRead {
+			return nil, usagef("- given more than once, the standard input can be read once only")
+		}
+		stdinRead = true
+		paths, err := readRootList(os.Stdin, nul)
+		if err != nil {
+			return nil, fmt.Errorf("standard input: %v", err)
+		}
+		return paths, nil
+	}
+
+	var roots []string
+	for _, arg := range args {
+		if arg != "-" {
+			roots = append(roots, arg)
+			continue
+		}
+		paths, err := readStdin()
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, paths...)
+	}
+	for _, list := range lists {
+		var paths []string
+		var err error
+		if list == "-" {
+			paths, err = readStdin()
+		} else {
+			var f *os.File
+			if f, err = os.Open(list); err != nil {
+				return nil, err
+			}
+			paths, err = readRootList(f, nul)
+			f.Close()
+			if err != nil {
+				err = fmt.Errorf("%s: %v", list, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, paths...)
+	}
+	return roots, nil
+}
+
+// withoutNonBinaries returns roots less the files that are not binaries of a
+// supported format, warning about each of them.  Missing files are kept for
+// the walk to report.
+func withoutNonBinaries(roots []string, relabel func(string) string) []string {
+	var bins []string
+	for _, root := range roots {
+		var fe *totool.FormatError
+		if _, err := totool.DetectFormat(root); errors.As(err, &fe) {
+			logAt(logWarning, relabel(root), root, "skipped, %v", err)
+			continue
+		}
+		bins = append(bins, root)
+	}
+	return bins
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// How much of the graph of a root was walked.
+const (
+	rootFull      = "full"      // all binaries were inspected
+	rootPartial   = "partial"   // some dependencies could not be inspected
+	rootFailed    = "failed"    // the root itself could not be inspected
+	rootTruncated = "truncated" // -root-timeout stopped the walk
+)
+
+// A rootResult tells how much of the graph of a root was walked.
+type rootResult struct {
+	root, status string
+
+	// err is the first error met walking root, if any.
+	err error
+}
+
+// rootResultOf returns how much of the graph of root was walked into g, err
+// being the error the walk returned and failed the number of failures g
+// recorded before the walk, by -keep-going walks of other roots.
+func rootResultOf(root string, g *totool.Graph, failed int, err error) rootResult {
+	r := rootResult{root: root, status: rootFull, err: err}
+	if fs := g.Failed(); r.err == nil && len(fs) > failed {
+		r.err = &totool.BinError{Bin: fs[failed], Err: g.Err(fs[failed])}
+	}
+	abs, _ := filepath.Abs(root)
+	var be *totool.BinError
+	switch {
+	case g.Err(abs) != nil || (errors.As(err, &be) && be.Bin == abs):
+		r.status = rootFailed
+	case errors.As(err, new(rootTimeoutError)):
+		r.status = rootTruncated
+	case r.err != nil:
+		r.status = rootPartial
+	}
+	return r
+}
+
+// A rootTimeoutError reports that -root-timeout stopped the walk of a root.
+type rootTimeoutError struct{ d time.Duration }
+
+func (e rootTimeoutError) Error() string {
+	return fmt.Sprintf("walk stopped after %v by -root-timeout, graph truncated", e.d)
+}
+
+func (e rootTimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// withRootTimeout calls walk with ctx, bounded by d if not 0.  It returns a
+// rootTimeoutError if d expired, ctx being left to bound the other roots.
+func withRootTimeout(ctx context.Context, d time.Duration, walk func(context.Context) error) error {
+	if d <= 0 {
+		return walk(ctx)
+	}
+	rctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := walk(rctx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return rootTimeoutError{d}
+	}
+	return err
+}
+
+// printRootResults prints to w the table of how much of the graph of each
+// root was walked, with the first error met.
+func printRootResults(w io.Writer, rs []rootResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "STATUS\tROOT\tFIRST ERROR\n")
+	for _, r := range rs {
+		msg := "-"
+		if r.err != nil {
+			msg = r.err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.status, r.root, msg)
+	}
+	tw.Flush()
+}
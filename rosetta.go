@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printRosetta prints, for each root of g with an x86_64 slice, the binaries of
+// its closure that lack one and keep it from running under Rosetta on Apple
+// silicon.
+// Missing system binaries live in the dyld shared cache, which Rosetta has an
+// x86_64 copy of.  It returns how many gaps it found.
+func printRosetta(g *totool.Graph) int {
+	fmt.Printf("%s:\n", g.Name())
+	n := 0
+	for _, root := range g.Roots() {
+		archs, err := totool.Archs(root)
+		if err != nil {
+			continue
+		}
+		if !hasArch(archs, "x86_64") {
+			fmt.Printf("\t%s: no x86_64 slice, never runs under Rosetta\n", root)
+			continue
+		}
+		gaps := 0
+		for _, bin := range closureOf(g, root)[1:] {
+			if _, err := os.Stat(bin); err != nil && totool.IsSystemBin(bin) {
+				continue
+			}
+			have, err := totool.Archs(bin)
+			if err != nil || hasArch(have, "x86_64") {
+				continue
+			}
+			gaps++
+			fmt.Printf("\t%s: no x86_64 slice, has %s\n", bin, strings.Join(have, ", "))
+			fmt.Printf("\t\t%s\n", strings.Join(g.Chain(bin), " -> "))
+		}
+		if gaps == 0 {
+			fmt.Printf("\t%s: ready for Rosetta\n", root)
+		}
+		n += gaps
+	}
+	return n
+}
+
+// hasArch reports whether archs includes arch.
+func hasArch(archs []string, arch string) bool {
+	for _, a := range archs {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// closureOf returns root followed by the binaries of g it transitively depends
+// on, in breadth-first order.
+func closureOf(g *totool.Graph, root string) []string {
+	seen := map[string]bool{root: true}
+	closure := []string{root}
+	for i := 0; i < len(closure); i++ {
+		for _, dep := range g.Dependencies(closure[i]) {
+			if !seen[dep] {
+				seen[dep] = true
+				closure = append(closure, dep)
+			}
+		}
+	}
+	return closure
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// An rpathEntry is a run path search path of a binary.
+type rpathEntry struct {
+	bin, rpath string
+}
+
+// An rpathSuggestion is a run path search path to add to a binary.
+type rpathSuggestion struct {
+	rpathEntry
+
+	// resolves lists the @rpath-prefixed dependencies it resolves.
+	resolves []string
+}
+
+func (s rpathSuggestion) String() string {
+	return fmt.Sprintf("add LC_RPATH %s to %s to resolve %s", s.rpath, s.bin, strings.Join(s.resolves, ", "))
+}
+
+// suggestRpaths returns a small set of run path search paths to add for the
+// unresolved @rpath-prefixed dependencies of g to resolve.  Libraries are
+// looked for in the directories of the binaries of g, the Frameworks and lib
+// directories next to them and the Frameworks directory of app, if not empty.
+// Paths are added to the executable at the start of the load chain when any,
+// relative to @executable_path, which covers all its dependencies, and to the
+// loading library relative to @loader_path otherwise.  Dependencies found
+// nowhere are left out.
+func suggestRpaths(g *totool.Graph, app string, unresolved []totool.Dependency) []rpathSuggestion {
+	var dirs []string
+	seenDir := make(map[string]bool)
+	addDir := func(dir string) {
+		if !seenDir[dir] {
+			seenDir[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	if app != "" {
+		addDir(filepath.Join(app, "Contents", "Frameworks"))
+	}
+	for _, bin := range g.Bins() {
+		if !filepath.IsAbs(bin) || totool.IsSystemBin(bin) {
+			continue
+		}
+		dir := filepath.Dir(bin)
+		addDir(dir)
+		addDir(filepath.Join(dir, "..", "Frameworks"))
+		addDir(filepath.Join(dir, "..", "lib"))
+	}
+
+	// byFix groups dependencies by the suggestion resolving them.
+	byFix := make(map[rpathEntry][]string)
+	var fixes []rpathEntry
+	for _, d := range unresolved {
+		suffix := strings.TrimPrefix(d.Bin, "@rpath/")
+		for _, dir := range dirs {
+			if checkReadable(filepath.Join(dir, suffix)) != nil {
+				continue
+			}
+			fix := rpathFix(g, d.Parent, dir)
+			if len(byFix[fix]) == 0 {
+				fixes = append(fixes, fix)
+			}
+			if !containsString(byFix[fix], d.Bin) {
+				byFix[fix] = append(byFix[fix], d.Bin)
+			}
+		}
+	}
+
+	// Greedily pick the suggestion resolving the most dependencies left.
+	var picked []rpathSuggestion
+	resolved := make(map[string]bool)
+	for {
+		var best rpathEntry
+		var bestDeps []string
+		for _, fix := range fixes {
+			var deps []string
+			for _, dep := range byFix[fix] {
+				if !resolved[dep] {
+					deps = append(deps, dep)
+				}
+			}
+			if len(deps) > len(bestDeps) {
+				best, bestDeps = fix, deps
+			}
+		}
+		if len(bestDeps) == 0 {
+			break
+		}
+		for _, dep := range bestDeps {
+			resolved[dep] = true
+		}
+		sort.Strings(bestDeps)
+		picked = append(picked, rpathSuggestion{rpathEntry: best, resolves: bestDeps})
+	}
+	return picked
+}
+
+// rpathFix returns the run path search path to add for a dependency of loader
+// to be looked for in dir.
+func rpathFix(g *totool.Graph, loader, dir string) rpathEntry {
+	if chain := g.Chain(loader); len(chain) > 0 && totool.IsExecutable(chain[0]) {
+		if rel, err := filepath.Rel(filepath.Dir(chain[0]), dir); err == nil {
+			return rpathEntry{chain[0], "@executable_path/" + filepath.ToSlash(rel)}
+		}
+	}
+	rel, _ := filepath.Rel(filepath.Dir(loader), dir)
+	return rpathEntry{loader, "@loader_path/" + filepath.ToSlash(rel)}
+}
+
+// containsString reports whether ss includes s.
+func containsString(ss []string, s string) bool {
+	for _, t := range ss {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// runMain implements the run subcommand, running a binary with the given
+// arguments for at most timeout to compare the libraries dyld loads with its
+// dependency graph.  It prints the libraries loaded at run time only, by
+// dlopen() for instance, and the dependencies never loaded.  Libraries loaded
+// at run time only that ship with macOS are only counted as they are mostly
+// dependencies of system libraries, which walks do not expand.
+func runMain(ctx context.Context, args []string, opts totool.Options, timeout time.Duration) error {
+	if len(args) == 0 {
+		return usagef("usage: totool run file [arg...]")
+	}
+	root, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	g, err := totool.VisitContext(ctx, root, &totool.Visitor{
+		OnError: func(bin string, err error) error {
+			if ctx.Err() != nil {
+				return err
+			}
+			return nil
+		},
+	}, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", root, err)
+	}
+
+	rctx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		rctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	loaded, err := totool.LoadedLibraries(rctx, root, args[1:])
+	stopped := err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+	if err != nil && !stopped {
+		return fmt.Errorf("%s: %v", root, err)
+	}
+
+	static := make(map[string]bool)
+	for _, bin := range g.Bins() {
+		static[canonicalPath(bin)] = true
+	}
+	fmt.Printf("%s:\n", root)
+	if stopped {
+		fmt.Printf("\tstopped after %v\n", timeout)
+	}
+	seen := make(map[string]bool)
+	system := 0
+	for _, lib := range loaded {
+		p := canonicalPath(lib)
+		seen[p] = true
+		if static[p] {
+			continue
+		}
+		if totool.IsSystemBin(lib) {
+			system++
+			continue
+		}
+		fmt.Printf("\tloaded at run time only: %s\n", lib)
+	}
+	if system > 0 {
+		fmt.Printf("\tloaded at run time only: %d system libraries\n", system)
+	}
+	never := 0
+	for _, bin := range g.Bins() {
+		if !seen[canonicalPath(bin)] {
+			never++
+			fmt.Printf("\tnever loaded: %s\n", bin)
+		}
+	}
+	fmt.Printf("\t%d of %d dependencies loaded\n", g.Size()-never, g.Size())
+	return nil
+}
+
+// canonicalPath returns path with symbolic links resolved if possible, as
+// dyld reports some libraries under their real path.
+func canonicalPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return path
+}
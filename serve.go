@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nthery/totool/totool"
+)
+
+// maxUpload bounds the size of the binaries the serve subcommand accepts.
+const maxUpload = 1 << 30
+
+// serveMain implements the serve subcommand, answering HTTP requests for the
+// graphs of binaries on addr, localhost:8080 unless given, until ctx is done:
+//
+//	GET /graph?path=/Applications/A.app/Contents/MacOS/A
+//	POST /graph?name=A, the binary as body
+//
+// Graphs are JSON documents as render reads them, or HTML pages with
+// format=html.  Uploaded binaries are walked from a temporary directory, their
+// dependencies being resolved on the serving machine.
+func serveMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) > 1 {
+		return usagef("usage: totool serve [addr]")
+	}
+	addr := "localhost:8080"
+	if len(args) == 1 {
+		addr = args[0]
+	}
+	s := &server{opts: opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graph", s.serveGraph)
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	log.Printf("serving graphs on http://%s/graph", addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return ctx.Err()
+}
+
+// A server answers the requests of the serve subcommand.
+type server struct {
+	opts totool.Options
+
+	// mu serializes walks, which share caches and inspectors.
+	mu sync.Mutex
+}
+
+func (s *server) serveGraph(w http.ResponseWriter, r *http.Request) {
+	var path, name string
+	switch r.Method {
+	case http.MethodGet:
+		path = r.URL.Query().Get("path")
+		if path == "" {
+			http.Error(w, "missing path parameter", http.StatusBadRequest)
+			return
+		}
+		name = path
+	case http.MethodPost:
+		name = r.URL.Query().Get("name")
+		if name == "" {
+			name = "upload"
+		}
+		dir, err := ioutil.TempDir("", "totool-serve")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, filepath.Base(name))
+		body := http.MaxBytesReader(w, r.Body, maxUpload)
+		if err := writeFileFrom(path, 0755, body); err != nil {
+			http.Error(w, fmt.Sprintf("cannot read upload: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "want GET or POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Each request walks the binaries as they are when it comes, not as
+	// earlier requests found them, and memoizes nothing past its walk.
+	s.mu.Lock()
+	g, err := totool.WalkContext(r.Context(), path, nullPrinter{}, s.opts)
+	var doc jsonGraph
+	if err == nil {
+		doc = jsonGraphOf(g)
+	}
+	totool.ForgetAll()
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, (&totool.BinError{Bin: name, Err: err}).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(doc)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = graphPage.Execute(w, struct {
+			Name string
+			jsonGraph
+		}{name, doc})
+	default:
+		http.Error(w, fmt.Sprintf("%s: unknown format, want json or html", format), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("%s: cannot write graph: %v", name, err)
+	}
+}
+
+// jsonGraphOf returns g as a single JSON document, roots included among nodes.
+func jsonGraphOf(g *totool.Graph) jsonGraph {
+	doc := jsonGraph{Roots: g.Roots()}
+	for _, n := range g.Nodes() {
+		info := g.Info(n.Parent, n.Path)
+		li := totool.ParseInfo(info)
+		doc.Nodes = append(doc.Nodes, jsonNode{
+			Path:           n.Path,
+			Name:           n.Name,
+			Info:           info,
+			CompatVersion:  li.Compat,
+			CurrentVersion: li.Current,
+			Weak:           li.Weak,
+			Upward:         li.Upward,
+			Attrs:          li.Attrs,
+			Depth:          n.Depth,
+			Class:          n.Origin.String(),
+			Parent:         n.Parent,
+			Truncated:      n.Truncated,
+			Size:           n.BinSize(),
+			Warnings:       n.Warnings,
+		})
+	}
+	for _, e := range g.Edges() {
+		doc.Edges = append(doc.Edges, jsonEdge{From: e.From, To: e.To})
+	}
+	return doc
+}
+
+// graphPage is the HTML page of a graph.
+var graphPage = template.Must(template.New("graph").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} dependencies</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 8px; text-align: left; }
+td { font-family: monospace; }
+tr.system td { color: gray; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{len .Nodes}} binaries, {{len .Edges}} dependencies.</p>
+<table>
+<tr><th>Depth</th><th>Library</th><th>Install name</th><th>Versions</th><th>Brought in by</th></tr>
+{{range .Nodes}}<tr class="{{.Class}}"><td>{{.Depth}}</td><td>{{.Path}}{{if .Truncated}} (truncated){{end}}</td><td>{{.Name}}</td><td>{{.CompatVersion}} {{.CurrentVersion}}</td><td>{{.Parent}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
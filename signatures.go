@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printSignatures prints how each binary of g is code signed, followed by its
+// entitlements if entitlements is set, then the binaries grouped by signing
+// team.  It warns about binaries that roots enforcing library validation would
+// refuse to load.
+func printSignatures(ctx context.Context, g *totool.Graph, entitlements bool) {
+	sigs := signaturesOf(ctx, g)
+	byTeam := make(map[string][]string)
+	fmt.Printf("%s:\n", g.Name())
+	for _, bin := range g.Bins() {
+		sig := sigs[bin]
+		if sig == nil {
+			continue
+		}
+		byTeam[sig.TeamID] = append(byTeam[sig.TeamID], bin)
+
+		line := sig.Status.String()
+		if sig.Identity != "" {
+			line += " by " + sig.Identity
+		}
+		switch {
+		case sig.Status == totool.Unsigned:
+		case sig.Valid:
+			line += ", valid"
+		default:
+			line += ", invalid: " + sig.Problem
+		}
+		fmt.Printf("\t%s: %s\n", bin, line)
+
+		if !entitlements || sig.Status == totool.Unsigned {
+			continue
+		}
+		ents, err := totool.Entitlements(ctx, bin)
+		if err != nil {
+			log.Print(err)
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		for _, e := range ents {
+			fmt.Printf("\t\t%s = %s\n", e.Key, e.Value)
+		}
+	}
+
+	teams := make([]string, 0, len(byTeam))
+	for team := range byTeam {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	for _, team := range teams {
+		name := team
+		if name == "" {
+			name = "no team"
+		}
+		fmt.Printf("\tteam %s: %s\n", name, strings.Join(byTeam[team], ", "))
+	}
+
+	for _, root := range g.Roots() {
+		if sig := sigs[root]; sig != nil && enforcesLibraryValidation(ctx, root, sig) {
+			for _, r := range refusedLibraries(g, root, sigs) {
+				log.Printf("%s: %s would not be loaded by %s: %s", g.Name(), r.bin, root, r.problem)
+			}
+		}
+	}
+}
+
+// signaturesOf returns the signatures of the binaries of g, logging those that
+// cannot be found.  System binaries living in the dyld shared cache only are
+// left out.
+func signaturesOf(ctx context.Context, g *totool.Graph) map[string]*totool.Signature {
+	sigs := make(map[string]*totool.Signature)
+	for _, bin := range g.Bins() {
+		if _, err := os.Stat(bin); err != nil && totool.IsSystemBin(bin) {
+			continue
+		}
+		sig, err := totool.CodeSignature(ctx, bin)
+		if err != nil {
+			log.Print(err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		sigs[bin] = &sig
+	}
+	return sigs
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printSizes prints the total size of the binaries of g, then the size of
+// each direct dependency of its roots and of everything it brings in, largest
+// first.
+func printSizes(g *totool.Graph) {
+	deps := g.DirectDeps()
+	closures := make(map[string]int64)
+	for _, d := range deps {
+		closures[d] = g.ClosureSize(d)
+	}
+	sort.SliceStable(deps, func(i, j int) bool { return closures[deps[i]] > closures[deps[j]] })
+
+	fmt.Printf("%s: %s in %s\n", g.Name(), byteSize(g.TotalSize()), plural(g.Size(), "binary", "binaries"))
+	for _, d := range deps {
+		n, _ := g.Node(d)
+		fmt.Printf("\t%9s %9s %s\n", byteSize(n.BinSize()), byteSize(closures[d]), d)
+	}
+}
+
+// byteSize formats n bytes with a binary unit.
+func byteSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	f, unit := float64(n)/1024, "KiB"
+	for _, u := range []string{"MiB", "GiB", "TiB"} {
+		if f < 1024 {
+			break
+		}
+		f, unit = f/1024, u
+	}
+	return fmt.Sprintf("%.1f %s", f, unit)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// isSnapshot reports whether path names a graph previously saved with -json or
+// -format gob rather than a binary.
+func isSnapshot(path string) bool {
+	return strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".gob")
+}
+
+// readSnapshot loads the graphs saved with -json or -format gob in path into a
+// single graph.
+func readSnapshot(path string) (*totool.Graph, error) {
+	if strings.HasSuffix(path, ".gob") {
+		return readGobSnapshot(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Records are gathered into a document as edges come before the nodes
+	// they lead to.
+	var records jsonGraph
+	g := totool.NewGraph()
+	dec := json.NewDecoder(f)
+	for {
+		var v struct {
+			jsonRecord
+			jsonGraph
+		}
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot decode %s: %v", path, err)
+		}
+		switch {
+		case v.Kind == "":
+			v.jsonGraph.addTo(g)
+		case v.Kind == "header":
+			if v.SchemaVersion > jsonSchemaVersion {
+				return nil, fmt.Errorf("%s: schema version %d is newer than supported version %d", path, v.SchemaVersion, jsonSchemaVersion)
+			}
+		case v.Kind == "root":
+			records.Roots = append(records.Roots, v.Path)
+			records.Nodes = append(records.Nodes, v.jsonNode)
+		case v.Kind == "node":
+			records.Nodes = append(records.Nodes, v.jsonNode)
+		case v.Kind == "edge":
+			records.Edges = append(records.Edges, v.jsonEdge)
+		default:
+			// Kinds added within the schema version, skipped files for
+			// instance, are ignored as README.md tells readers to.
+		}
+	}
+	records.addTo(g)
+	if len(g.Roots()) == 0 {
+		return nil, fmt.Errorf("%s: no graph found", path)
+	}
+	return g, nil
+}
+
+// readGobSnapshot loads the graphs saved with -format gob in path into a single
+// graph.
+func readGobSnapshot(path string) (*totool.Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g := totool.NewGraph()
+	dec := gob.NewDecoder(f)
+	for {
+		var v totool.Graph
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot decode %s: %v", path, err)
+		}
+		for _, root := range v.Roots() {
+			g.AddRoot(root)
+		}
+		for _, n := range v.Nodes() {
+			g.AddNode(n)
+		}
+		for _, e := range v.Edges() {
+			g.AddDep(e.From, e.To, e.Info)
+		}
+	}
+	if len(g.Roots()) == 0 {
+		return nil, fmt.Errorf("%s: no graph found", path)
+	}
+	return g, nil
+}
+
+// addTo adds the binaries and dependencies of doc to g.
+func (doc *jsonGraph) addTo(g *totool.Graph) {
+	infos := make(map[string]string)
+	for _, root := range doc.Roots {
+		g.AddRoot(root)
+	}
+	for _, n := range doc.Nodes {
+		origin, _ := totool.ParseOrigin(n.Class)
+		g.AddNode(totool.Node{Path: n.Path, Depth: n.Depth, Parent: n.Parent, Name: n.Name, Origin: origin, Truncated: n.Truncated, Size: n.Size, Warnings: n.Warnings})
+		infos[n.Path] = n.Info
+	}
+	for _, e := range doc.Edges {
+		g.AddDep(e.From, e.To, infos[e.To])
+	}
+}
+
+// loadGraph walks the binary path or loads it if it is a snapshot.
+func loadGraph(ctx context.Context, path string, opts totool.Options) (*totool.Graph, error) {
+	if isSnapshot(path) {
+		return readSnapshot(path)
+	}
+	return totool.WalkContext(ctx, path, nullPrinter{}, opts)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nthery/totool/totool"
+)
+
+// graphLines describes the roots, binaries and dependencies of g, one per
+// line, for tests to compare graphs.
+func graphLines(g *totool.Graph) []string {
+	lines := []string{"roots " + strings.Join(g.Roots(), " ")}
+	for _, n := range g.Nodes() {
+		lines = append(lines, strings.Join([]string{"node", n.Path, n.Name, n.Parent, n.Origin.String(), strings.Repeat("+", n.Depth)}, " "))
+	}
+	for _, e := range g.Edges() {
+		lines = append(lines, strings.Join([]string{"edge", e.From, e.To, e.Info}, " "))
+	}
+	return lines
+}
+
+func TestJSONSnapshotRoundTrip(t *testing.T) {
+	info := "(compatibility version 1.0.0, current version 1.2.3)"
+	g := totool.NewGraph()
+	g.AddRoot("/app/R")
+	g.AddNode(totool.Node{Path: "/app/R", Origin: totool.Classify("/app/R", "/app/R")})
+	for _, e := range [][2]string{{"/app/R", "/app/libA.dylib"}, {"/app/R", "/usr/lib/libSystem.B.dylib"}, {"/app/libA.dylib", "/usr/lib/libSystem.B.dylib"}} {
+		g.AddNode(totool.Node{Path: e[1], Name: "@rpath/" + filepath.Base(e[1]), Parent: e[0], Depth: 1, Origin: totool.Classify("/app/R", e[1])})
+		g.AddDep(e[0], e[1], info)
+	}
+
+	pt, err := totool.NewPrinter("json", totool.PrinterOptions{Nodes: true, Edges: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := captureStdout(func() error {
+		g.Render(pt)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, `{"kind":"header","schemaVersion":1}`+"\n") {
+		t.Errorf("output does not start with the header:\n%s", out)
+	}
+	path := filepath.Join(t.TempDir(), "graph.json")
+	if err := ioutil.WriteFile(path, []byte(out), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := graphLines(g); !reflect.DeepEqual(graphLines(got), want) {
+		t.Errorf("read back\n%s\nwant\n%s", strings.Join(graphLines(got), "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadJSONSnapshot(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+		err  string
+	}{
+		{
+			name: "document predating records",
+			in:   `{"roots": ["/R"], "nodes": [{"path": "/R"}, {"path": "/A", "name": "@rpath/A", "parent": "/R", "depth": 1, "class": "embedded", "info": "(x)"}], "edges": [{"from": "/R", "to": "/A"}]}`,
+			want: []string{"roots /R", "node /R   other ", "node /A @rpath/A /R embedded +", "edge /R /A (x)"},
+		},
+		{
+			name: "records",
+			in: `{"kind":"header","schemaVersion":1}
+{"kind":"root","path":"/R","class":"embedded"}
+{"kind":"edge","from":"/R","to":"/A"}
+{"kind":"node","path":"/A","parent":"/R","depth":1,"class":"other","info":"(x)"}`,
+			want: []string{"roots /R", "node /R   embedded ", "node /A  /R other +", "edge /R /A (x)"},
+		},
+		{
+			name: "records of unknown kinds",
+			in: `{"kind":"header","schemaVersion":1}
+{"kind":"skipped","path":"/README","reason":"not a binary"}
+{"kind":"future","path":"/X"}
+{"kind":"root","path":"/R","class":"embedded"}`,
+			want: []string{"roots /R", "node /R   embedded "},
+		},
+		{
+			name: "newer schema",
+			in:   `{"kind":"header","schemaVersion":2}`,
+			err:  "schema version 2 is newer than supported version 1",
+		},
+		{
+			name: "no root",
+			in:   `{"kind":"header","schemaVersion":1}`,
+			err:  "no graph found",
+		},
+		{
+			name: "not JSON",
+			in:   `roots: /R`,
+			err:  "cannot decode",
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "graph.json")
+		if err := ioutil.WriteFile(path, []byte(tt.in), 0644); err != nil {
+			t.Fatal(err)
+		}
+		g, err := readSnapshot(path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := graphLines(g); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/nthery/totool/totool"
+)
+
+// sortPrinter forwards to another printer the graph it is notified of once
+// walked, binaries sorted by depth then path and dependencies by path, so that
+// output does not depend on the order binaries list their dependencies in nor
+// on the walk and can be committed and diffed.  Sorting needs the whole graph,
+// so nothing is forwarded before the walk completes.
+type sortPrinter struct {
+	pt totool.Printer
+
+	roots []string
+	bins  []totool.Dependency
+	deps  []edge
+}
+
+func (p *sortPrinter) PrintPrologue() {
+	p.roots, p.bins, p.deps = nil, nil, nil
+	p.pt.PrintPrologue()
+}
+
+func (p *sortPrinter) PrintEpilogue() {
+	sort.Strings(p.roots)
+	sort.Slice(p.bins, func(i, j int) bool {
+		if p.bins[i].Depth != p.bins[j].Depth {
+			return p.bins[i].Depth < p.bins[j].Depth
+		}
+		return p.bins[i].Bin < p.bins[j].Bin
+	})
+	sort.Slice(p.deps, func(i, j int) bool {
+		if p.deps[i].from != p.deps[j].from {
+			return p.deps[i].from < p.deps[j].from
+		}
+		return p.deps[i].to < p.deps[j].to
+	})
+
+	// As when walking, binaries are notified a level at a time, each level
+	// followed by the dependencies of its binaries.
+	depth := make(map[string]int)
+	byDepth := make(map[int][]edge)
+	for _, d := range p.bins {
+		depth[d.Bin] = d.Depth
+	}
+	for _, e := range p.deps {
+		byDepth[depth[e.from]] = append(byDepth[depth[e.from]], e)
+	}
+	for _, root := range p.roots {
+		p.pt.PrintRootBin(root)
+	}
+	level := 0
+	for i := 0; i <= len(p.bins); i++ {
+		if i == len(p.bins) || p.bins[i].Depth != level {
+			for _, e := range byDepth[level] {
+				p.pt.PrintDep(e.from, e.to)
+			}
+			if i == len(p.bins) {
+				break
+			}
+			level = p.bins[i].Depth
+		}
+		p.pt.PrintDepBin(&p.bins[i])
+	}
+	p.roots, p.bins, p.deps = nil, nil, nil
+	p.pt.PrintEpilogue()
+}
+
+func (p *sortPrinter) PrintRootBin(bin string) {
+	p.roots = append(p.roots, bin)
+}
+
+func (p *sortPrinter) PrintDepBin(d *totool.Dependency) {
+	p.bins = append(p.bins, *d)
+}
+
+func (p *sortPrinter) PrintDep(from, to string) {
+	p.deps = append(p.deps, edge{from, to})
+}
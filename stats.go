@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// topReferencedCount is how many of the most referenced binaries printStats lists.
+const topReferencedCount = 10
+
+// printStats prints a summary of the complexity of g.
+func printStats(g *totool.Graph) {
+	maxDepth := 0
+	for _, d := range g.Depths() {
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+
+	edges := g.EdgeCount()
+	fanOut := 0.0
+	if g.Size() > 0 {
+		fanOut = float64(edges) / float64(g.Size())
+	}
+
+	fmt.Printf("%s:\n", g.Name())
+	fmt.Printf("\tnodes: %d\n", g.Size())
+	fmt.Printf("\tedges: %d\n", edges)
+	fmt.Printf("\tmax depth: %d\n", maxDepth)
+	fmt.Printf("\taverage fan-out: %.2f\n", fanOut)
+
+	fanIn := g.FanIn()
+	bins := make([]string, 0, len(fanIn))
+	for bin := range fanIn {
+		bins = append(bins, bin)
+	}
+	sort.Slice(bins, func(i, j int) bool {
+		if fanIn[bins[i]] != fanIn[bins[j]] {
+			return fanIn[bins[i]] > fanIn[bins[j]]
+		}
+		return bins[i] < bins[j]
+	})
+	if len(bins) > topReferencedCount {
+		bins = bins[:topReferencedCount]
+	}
+	fmt.Printf("\tmost referenced:\n")
+	for _, bin := range bins {
+		fmt.Printf("\t\t%d %s\n", fanIn[bin], bin)
+	}
+}
+
+// printSummary prints a line counting the libraries of g, the graph of name,
+// its dependencies, the libraries missing or that could not be inspected and
+// the warnings reported about it.
+//
+//	A.app: 137 libraries, 412 dependencies, 3 missing, 2 warnings
+func printSummary(name string, g *totool.Graph, warnings int) {
+	libs, missing := 0, 0
+	for _, bin := range g.Bins() {
+		if g.IsRoot(bin) {
+			continue
+		}
+		libs++
+		if !fileExists(bin) {
+			missing++
+		}
+	}
+	line := fmt.Sprintf("%s: %s, %s, %d missing", name, plural(libs, "library", "libraries"), plural(g.EdgeCount(), "dependency", "dependencies"), missing)
+	// Missing libraries are not counted again among those that failed.
+	failed := 0
+	for _, bin := range g.Failed() {
+		if fileExists(bin) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		line += fmt.Sprintf(", %d failed", failed)
+	}
+	fmt.Printf("%s, %s\n", line, plural(warnings, "warning", "warnings"))
+}
+
+// printSkipped prints a line counting the files scans of name left out by
+// reason and, with verbose set, each of them.
+func printSkipped(name string, skipped []totool.SkippedFile, verbose bool) {
+	counts := make(map[string]int)
+	var reasons []string
+	for _, s := range skipped {
+		if counts[s.Reason] == 0 {
+			reasons = append(reasons, s.Reason)
+		}
+		counts[s.Reason]++
+	}
+	sort.Strings(reasons)
+	parts := make([]string, len(reasons))
+	for i, r := range reasons {
+		parts[i] = fmt.Sprintf("%d %s", counts[r], r)
+	}
+	fmt.Printf("%s: %s skipped: %s\n", name, plural(len(skipped), "file", "files"), strings.Join(parts, ", "))
+	if verbose {
+		for _, s := range skipped {
+			fmt.Printf("\t%s: %v\n", s.Path, s)
+		}
+	}
+}
+
+// plural formats n followed by one or many depending on n.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// printLongestChain prints the longest dependency chain of g, one binary per
+// line, each indented one level deeper than its dependent.
+func printLongestChain(g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	for i, bin := range g.LongestChain() {
+		fmt.Printf("\t%s%s\n", strings.Repeat("  ", i), bin)
+	}
+}
+
+// printPathCounts prints the dependencies of g sorted by decreasing number of
+// distinct dependency chains leading to them.
+func printPathCounts(g *totool.Graph) {
+	counts := g.PathCounts()
+	var bins []string
+	for _, bin := range g.Bins() {
+		if !g.IsRoot(bin) {
+			bins = append(bins, bin)
+		}
+	}
+	sort.SliceStable(bins, func(i, j int) bool {
+		return counts[bins[i]].Cmp(counts[bins[j]]) > 0
+	})
+
+	fmt.Printf("%s:\n", g.Name())
+	for _, bin := range bins {
+		fmt.Printf("\t%s %s\n", counts[bin], bin)
+	}
+}
+
+// printOrigins prints how many dependencies of g belong to each origin class.
+func printOrigins(g *totool.Graph) {
+	counts := make(map[totool.Origin]int)
+	for _, bin := range g.Bins() {
+		if !g.IsRoot(bin) {
+			counts[g.Origin(bin)]++
+		}
+	}
+
+	fmt.Printf("%s:\n", g.Name())
+	for _, o := range totool.Origins {
+		fmt.Printf("\t%s: %d\n", o, counts[o])
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// swiftRuntimeDir is where macOS ships the Swift runtime since it became ABI
+// stable.
+const swiftRuntimeDir = "/usr/lib/swift/"
+
+// swiftInOS maps the Swift runtime libraries macOS did not ship along with
+// the first ABI-stable runtime to the version of macOS that first shipped
+// them, and libraries it never ships to an empty string.
+var swiftInOS = map[string]string{
+	"libswift_Concurrency.dylib":      "12.0",
+	"libswift_StringProcessing.dylib": "13.0",
+	"libswift_RegexParser.dylib":      "13.0",
+	"libswiftObservation.dylib":       "14.0",
+	"libswiftSwiftOnoneSupport.dylib": "",
+}
+
+// swiftStableOS is the first version of macOS shipping the Swift runtime.
+const swiftStableOS = "10.14.4"
+
+// printSwift prints the copies of the Swift runtime libraries bundled along
+// with the roots of g, whether linked or lying in the Frameworks directories of
+// their app bundles and embedded frameworks.  It warns about libraries bundled
+// in several versions and about copies roots never load as they target macOS
+// versions shipping the library.
+func printSwift(g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	for _, root := range g.Roots() {
+		copies := bundledSwift(g, root)
+		if len(copies) == 0 {
+			fmt.Printf("\t%s: no bundled Swift runtime\n", root)
+			continue
+		}
+		minOS := lowestMinOS(root)
+
+		byName := make(map[string][]string)
+		for _, bin := range copies {
+			name := filepath.Base(bin)
+			byName[name] = append(byName[name], bin)
+			v, _ := totool.CurrentVersion(bin)
+			if v == "" {
+				v = "unknown version"
+			}
+			fmt.Printf("\t%s: %s\n", bin, v)
+		}
+
+		names := make([]string, 0, len(byName))
+		for name := range byName {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			bins := byName[name]
+			seen := make(map[string]bool)
+			var versions []string
+			for _, bin := range bins {
+				if v, _ := totool.CurrentVersion(bin); !seen[v] {
+					seen[v] = true
+					versions = append(versions, v)
+				}
+			}
+			if len(versions) > 1 {
+				log.Printf("%s: mixed Swift runtime versions of %s: %s", root, name, strings.Join(bins, ", "))
+			}
+			since, known := swiftInOS[name]
+			if !known {
+				since = swiftStableOS
+			}
+			if since != "" && minOS != "" && totool.CompareVersions(minOS, since) >= 0 {
+				log.Printf("%s: bundled %s unnecessary when targeting macOS %s, which ships it in %s", root, name, minOS, swiftRuntimeDir)
+			}
+		}
+	}
+}
+
+// bundledSwift returns the copies of Swift runtime libraries root depends on
+// that do not ship with macOS, followed by those found in the Frameworks
+// directories of its app bundle, if any.
+func bundledSwift(g *totool.Graph, root string) []string {
+	var copies []string
+	seen := make(map[string]bool)
+	add := func(bin string) {
+		if !seen[bin] && isSwiftRuntime(bin) && !strings.HasPrefix(bin, swiftRuntimeDir) {
+			seen[bin] = true
+			copies = append(copies, bin)
+		}
+	}
+	for _, bin := range closureOf(g, root)[1:] {
+		add(bin)
+	}
+	if app := totool.AppBundle(root); app != "" {
+		filepath.Walk(filepath.Join(app, "Contents"), func(path string, fi os.FileInfo, err error) error {
+			if err == nil && !fi.IsDir() && filepath.Base(filepath.Dir(path)) == "Frameworks" {
+				add(path)
+			}
+			return nil
+		})
+	}
+	return copies
+}
+
+// isSwiftRuntime reports whether bin is a library of the Swift runtime or one
+// of its overlays.
+func isSwiftRuntime(bin string) bool {
+	base := filepath.Base(bin)
+	return strings.HasPrefix(base, "libswift") && strings.HasSuffix(base, ".dylib")
+}
+
+// lowestMinOS returns the lowest version of macOS the architectures of bin
+// run on, empty if unknown.
+func lowestMinOS(bin string) string {
+	versions, _ := totool.MinOS(bin)
+	lowest := ""
+	for _, v := range versions {
+		if lowest == "" || totool.CompareVersions(v, lowest) < 0 {
+			lowest = v
+		}
+	}
+	return lowest
+}
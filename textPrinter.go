@@ -1,29 +1,130 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+func init() {
+	totool.RegisterFormat("text", func(opts totool.PrinterOptions) totool.Printer {
+		return &textPrinter{verbose: opts.Verbose, fanIn: opts.FanIn, color: opts.Color, label: opts.LabelOf}
+	})
+}
 
 // textPrinter prints dependencies like otool.
-type textPrinter struct{ verbose bool }
+type textPrinter struct {
+	verbose bool
 
-func (p textPrinter) printPrologue() {
-	// nop
+	// fanIn enables listing the dependents of each dependency.  As they are
+	// known only once the whole graph is walked, output is delayed until then.
+	fanIn bool
+
+	// color enables ANSI colors, roots in bold and dependencies as binStyle.
+	color bool
+
+	// label returns how paths are displayed.
+	label func(string) string
+
+	// bins and parents buffer the graph when fanIn is set.
+	bins    []textBin
+	parents map[string][]string
 }
 
-func (p textPrinter) printEpilogue() {
+// textBin is a binary buffered by textPrinter.
+type textBin struct {
+	d    totool.Dependency
+	root bool
+}
+
+func (p *textPrinter) PrintPrologue() {
 	// nop
 }
 
-func (p textPrinter) printRootBin(bin string) {
-	fmt.Printf("%s:\n", bin)
+func (p *textPrinter) PrintEpilogue() {
+	for _, b := range p.bins {
+		p.printBin(&b.d, b.root)
+		if p.verbose && !b.root {
+			for _, parent := range p.parents[b.d.Bin] {
+				fmt.Printf("\t\t<- %s\n", paint(p.color, ansiDim, p.label(parent)))
+			}
+		}
+	}
+	p.bins = nil
+	p.parents = nil
 }
 
-func (p textPrinter) printDepBin(d *dependency) {
-	if p.verbose {
-		fmt.Printf("\t%s %s\n", d.bin, d.info)
-	} else {
-		fmt.Printf("\t%s\n", d.bin)
+func (p *textPrinter) PrintRootBin(bin string) {
+	if p.fanIn {
+		p.bins = append(p.bins, textBin{totool.Dependency{Bin: bin}, true})
+		return
 	}
+	p.printBin(&totool.Dependency{Bin: bin}, true)
 }
-func (p textPrinter) printDep(from, to string) {
-	// nop
+
+func (p *textPrinter) PrintDepBin(d *totool.Dependency) {
+	if p.fanIn {
+		p.bins = append(p.bins, textBin{*d, false})
+		return
+	}
+	p.printBin(d, false)
+}
+
+func (p *textPrinter) PrintDep(from, to string) {
+	if p.fanIn {
+		if p.parents == nil {
+			p.parents = make(map[string][]string)
+		}
+		p.parents[to] = append(p.parents[to], from)
+	}
+}
+
+// printBin prints a single root or dependency binary.
+func (p *textPrinter) printBin(d *totool.Dependency, root bool) {
+	if root {
+		fmt.Printf("%s:\n", paint(p.color, ansiBold, p.label(d.Bin)))
+		return
+	}
+	bin := p.label(d.Bin)
+	if p.color {
+		bin = paint(true, binStyle(d), bin)
+	}
+	switch {
+	case d.Truncated:
+		fmt.Printf("\t%s (truncated)\n", bin)
+	case p.verbose:
+		if d.Name != "" && d.Name != d.Bin {
+			bin = d.Name + " => " + bin
+		}
+		fields := totool.ParseInfo(d.Info).Fields()
+		if v, ok := totool.FrameworkVersionsOf(d.Bin); ok {
+			fields = append(fields, v.Chain())
+		}
+		fields = append(fields, fmt.Sprintf("depth %d", d.Depth), "brought in by "+p.label(d.Parent))
+		fmt.Printf("\t%s [%s]\n", bin, strings.Join(fields, ", "))
+		if segs := segmentSizes(d.Bin); segs != "" {
+			fmt.Printf("\t\t%s\n", paint(p.color, ansiDim, segs))
+		}
+	case p.fanIn:
+		fmt.Printf("\t%s (%d dependents)\n", bin, len(p.parents[d.Bin]))
+	default:
+		fmt.Printf("\t%s\n", bin)
+	}
+}
+
+// segmentSizes describes the size of bin and of its segments holding data in
+// the file, or returns the empty string if bin is not a Mach-O binary.
+func segmentSizes(bin string) string {
+	segs, err := totool.Segments(bin)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, s := range segs {
+		if s.Size > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", s.Name, byteSize(int64(s.Size))))
+		}
+	}
+	return fmt.Sprintf("%s: %s", byteSize(totool.FileSize(bin)), strings.Join(parts, ", "))
 }
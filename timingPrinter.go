@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nthery/totool/totool"
+)
+
+// timingPrinter forwards to another printer, measuring the time it takes.
+type timingPrinter struct{ pt totool.Printer }
+
+func (p timingPrinter) PrintPrologue() {
+	defer addTime(&printTime, time.Now())
+	p.pt.PrintPrologue()
+}
+
+func (p timingPrinter) PrintEpilogue() {
+	defer addTime(&printTime, time.Now())
+	p.pt.PrintEpilogue()
+}
+
+func (p timingPrinter) PrintRootBin(bin string) {
+	defer addTime(&printTime, time.Now())
+	p.pt.PrintRootBin(bin)
+}
+
+func (p timingPrinter) PrintDepBin(d *totool.Dependency) {
+	defer addTime(&printTime, time.Now())
+	p.pt.PrintDepBin(d)
+}
+
+func (p timingPrinter) PrintDep(from, to string) {
+	defer addTime(&printTime, time.Now())
+	p.pt.PrintDep(from, to)
+}
@@ -3,161 +3,777 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
+	"errors"
 	"flag"
-	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
+	"time"
+
+	"github.com/nthery/totool/totool"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the command line and returns the exit status.
+func run() (exit int) {
 	log.SetPrefix("totool: ")
 	log.SetFlags(0)
 
-	verbose := flag.Bool("v", false, "output extra info")
-	dot := flag.Bool("dot", false, "generate dot output")
+	verbose := flag.Bool("v", false, "print the version info of each dependency, also printed with -vv")
+	fanIn := flag.Bool("fanin", false, "print the dependents of each dependency")
+	format := flag.String("format", "text", "print the graph in `format`: "+strings.Join(totool.Formats(), ", "))
+	dot := flag.Bool("dot", false, "same as -format dot")
+	jsonOut := flag.Bool("json", false, "same as -format json")
+	csvOut := flag.Bool("csv", false, "same as -format csv")
+	nodesOnly := flag.Bool("nodes-only", false, "emit only binaries in JSON and CSV output")
+	edgesOnly := flag.Bool("edges-only", false, "emit only direct dependencies in JSON and CSV output")
+	topo := flag.Bool("topo", false, "same as -format topo, printing dependencies in topological order (leaves first)")
+	leaves := flag.Bool("leaves", false, "same as -format leaves, printing only dependencies without non-system dependencies")
+	classes := flag.Bool("classes", false, "print how many dependencies come from the system, package managers, the bundle or elsewhere")
+	stats := flag.Bool("stats", false, "print graph statistics for each file")
+	longest := flag.Bool("longest", false, "print the longest dependency chain of each file")
+	dominators := flag.Bool("dominators", false, "print the binaries reachable only through each direct dependency")
+	attribution := flag.Bool("attribution", false, "print how many binaries each direct dependency brings in on its own")
+	paths := flag.Bool("paths", false, "print how many dependency chains lead to each dependency")
+	frameworks := flag.Bool("frameworks", false, "show each framework bundle as a single binary")
+	queryExpr := flag.String("query", "", "print only the binaries matching `expr` (e.g. 'depth <= 2 and not system')")
+	merge := flag.Bool("merge", false, "walk all files into a single graph")
+	expandSystem := flag.Bool("expand-system", false, "expand system binaries present on all systems")
+	var leafBins stringList
+	flag.Var(&leafBins, "leaf", "do not expand binaries matching `path`, a directory if ending with / (repeatable)")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "inspect up to `N` binaries concurrently")
+	maxProcs := flag.Int("max-procs", 0, "run up to `N` subprocesses at once (0 means no limit)")
+	maxDepth := flag.Int("max-depth", 0, "do not expand binaries deeper than `N` (0 means no limit)")
+	maxNodes := flag.Int("max-nodes", 0, "stop expanding the graph after `N` binaries (0 means no limit)")
+	warnFanOut := flag.Int("warn-fanout", 0, "warn about binaries with more than `N` direct dependencies (0 disables)")
+	baseline := flag.String("baseline", "", "fail if dependencies are missing from the graph saved with -json or -format gob in `file`")
+	cacheDir := flag.String("cache-dir", totool.DefaultCacheDir(), "cache otool output across runs in `dir`")
+	noCache := flag.Bool("no-cache", false, "do not cache otool output across runs")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to `file`")
+	memProfile := flag.String("memprofile", "", "write a memory profile to `file`")
+	timing := flag.Bool("timing", false, "print the time spent running, parsing and printing")
+	direct := flag.Bool("direct", false, "print the direct dependencies of all files with reference counts")
+	backend := flag.String("backend", "otool", "find dependencies of mach-o binaries with `tool`, ELF binaries being parsed: "+strings.Join(totool.Backends, ", "))
+	timeout := flag.Duration("timeout", 0, "stop walking after `duration`, printing what was found so far (0 means no limit)")
+	rootTimeout := flag.Duration("root-timeout", 0, "stop walking each root after `duration`, reporting its graph as truncated before moving on to the next root (0 means no limit)")
+	scan := flag.Bool("scan", false, "walk all binaries found below the given directories into a single graph")
+	signatures := flag.Bool("signatures", false, "print how each binary is code signed")
+	hashes := flag.Bool("hashes", false, "add the SHA-256 digest of each binary to JSON and CSV output")
+	checkSec := flag.Bool("checksec", false, "print the exploit mitigations each binary was built with")
+	notarization := flag.Bool("notarization", false, "print whether Gatekeeper accepts the roots and the binaries shipped along with them and fail if it does not")
+	quarantine := flag.Bool("quarantine", false, "print the binaries carrying the quarantine attribute and fail if there are any")
+	hardening := flag.Bool("hardening", false, "print whether roots run with the hardened runtime and enforce library validation, and the dependencies they would refuse to load")
+	entitlements := flag.Bool("entitlements", false, "same as -signatures, also printing the entitlements of each signed binary")
+	record := flag.String("record", "", "save what is found about each binary in `dir` for -replay")
+	replay := flag.String("replay", "", "walk from what -record saved in `dir` instead of inspecting binaries")
+	policyFile := flag.String("policy", "", "make check fail on dependencies breaking the deny and require rules in `file`")
+	distributable := flag.Bool("distributable", false, "make check fail on dependencies installed by Homebrew, MacPorts or Fink")
+	insecure := flag.Bool("insecure", false, "print the binaries and library search paths users other than root could replace and fail if there are any")
+	vulns := flag.Bool("vulns", false, "print the known vulnerabilities of binaries installed by Homebrew as reported by OSV")
+	osvEcosystem := flag.String("osv-ecosystem", "", "look packages up in OSV `ecosystem` for -vulns")
+	offline := flag.Bool("offline", false, "only use cached answers for -vulns")
+	licenses := flag.Bool("licenses", false, "print the license of each third-party binary and add it to JSON and CSV output")
+	formulae := flag.Bool("formulae", false, "print the Homebrew formula and version each binary was installed by")
+	rosetta := flag.Bool("rosetta", false, "print the dependencies lacking the x86_64 slice roots need to run under Rosetta and fail if there are any")
+	undefined := flag.Bool("undefined", false, "print the undefined symbols of roots and the libraries defining them and fail if some are not defined")
+	dlopen := flag.Bool("dlopen", false, "add the libraries whose paths appear among the strings of binaries as runtime dependencies")
+	runTimeout := flag.Duration("run-timeout", 10*time.Second, "stop binaries started by run after `duration` (0 means no limit)")
+	weak := flag.Bool("weak", false, "print whether the weak dependencies of each binary are present")
+	dryRun := flag.Bool("dry-run", false, "make bundle and export print what they would do without doing it")
+	fix := flag.Bool("fix", false, "make check print the changes fixing the install names and run paths of app bundles")
+	apply := flag.Bool("apply", false, "make check -fix change binaries, saving them as file.orig, and check again")
+	flatten := flag.Bool("flatten", false, "make export copy all binaries into the destination directory itself, referring to each other relative to @loader_path")
+	qt := flag.Bool("qt", false, "print the Qt plugins and QML modules app bundles lack and fail if some are needed")
+	swift := flag.Bool("swift", false, "print the bundled copies of the Swift runtime, warning about mixed versions and copies the targeted macOS makes unnecessary")
+	signIdentity := flag.String("sign", "", "make bundle and check -fix sign the changed app bundles inside out with `identity` rather than print the codesign commands to run")
+	var dllDirs stringList
+	flag.Var(&dllDirs, "dll-dir", "look for the DLLs of Windows binaries in `dir` after the directory of the executable (repeatable)")
+	elfRoot := flag.String("elf-root", "", "resolve the dependencies of ELF binaries as if `dir` was the root directory, to examine binaries of another Linux system, on macOS for instance")
+	remote := flag.String("remote", "", "run on `user@host` over ssh, shipping totool there, file arguments being paths on the remote host")
+	var scanDirs stringList
+	flag.Var(&scanDirs, "r", "walk all the binaries found below `dir`, told by their magic number, along with the given files into a merged report (repeatable)")
+	var fileLists stringList
+	flag.Var(&fileLists, "files", "walk the files listed in `file`, one per line, along with the given ones, - standing for those listed on the standard input (repeatable)")
+	nul := flag.Bool("0", false, "read the lists of -files and - as NUL-terminated entries, as find -print0 writes them")
+	colorMode := flag.String("color", "auto", "color the text, topo and leaves formats and warnings: `when` among never, auto, for terminals unless NO_COLOR is set, and always")
+	outPath := flag.String("o", "", "write the output to `file` rather than the standard output, the extension .dot, .svg, rendered by Graphviz, .json, .csv or .gob implying -format unless set")
+	quiet := flag.Bool("q", false, "print errors only, leaving out warnings about the graph")
+	debug := flag.Bool("vv", false, "like -v, also logging how each dependency is resolved and the commands and caches backends use")
+	logFormat := flag.String("log", "text", "print log records on the standard error in `format`: text, or json with fields level, among debug, warning, error and fatal, root, path and message, or xcode, as build log diagnostics")
+	noProgress := flag.Bool("no-progress", false, "do not print the progress of walks on the standard error, printed when it is a terminal and the output is not")
+	keepGoing := flag.Bool("keep-going", false, "walk on past the binaries that cannot be inspected, summing up their errors once done, rather than stop walking a root on the first one")
+	strict := flag.Bool("strict", false, "exit with the status of failed checks when warnings about the graph are printed, cycles or duplicate libraries for instance")
+	skipNonBinaries := flag.Bool("skip-non-binaries", false, "leave out with a warning the roots that are not binaries, scripts or text files for instance, rather than fail on them, as -r does")
+	otoolPath := flag.String("otool", "", "run `path` in place of the otool found in PATH or in -toolchain")
+	toolchain := flag.String("toolchain", "", "run the otool, dyld_info, install_name_tool and codesign xcrun finds in `toolchain`, an Xcode or command line tools directory or a toolchain identifier")
+	var stripPrefixes stringList
+	flag.Var(&stripPrefixes, "strip-prefix", "display paths less `prefix` in the text, topo, leaves and dot formats, json recording labels next to full paths (repeatable)")
+	relativeTo := flag.String("relative-to", "", "display the paths below `dir`, an app bundle for instance, relative to it, as -strip-prefix")
+	aliasFile := flag.String("aliases", "", "merge in the nodes of the dot format the binaries matching the regular expressions of `file` under the labels following them, one per line")
+	sorted := flag.Bool("sort", false, "print binaries and dependencies sorted by depth then path rather than in the order they are found, for output to diff between builds")
+	summary := flag.Bool("summary", false, "print for each file a line counting its libraries, dependencies, missing libraries and warnings")
+	pager := flag.Bool("pager", false, "pipe the output through $PAGER, less by default, when the standard output is a terminal")
+	eventsFD := flag.Int("events", 0, "write progress events as JSON lines to the file descriptor `fd`, 2 for the standard error, for front ends to show progress")
+	sizes := flag.Bool("sizes", false, "print the size of each file and of all its binaries, then of each direct dependency on its own and with what it brings in, json records carrying sizes too")
+	namespaces := flag.Bool("namespace", false, "print whether each binary uses a two-level or flat namespace and the library each undefined symbol is bound to")
+	installNames := flag.Bool("install-names", false, "print once the text output is done the direct dependencies of each binary under the install names it records, @rpath/libA.dylib for instance, next to the paths they resolve to")
+	metricsPath := flag.String("metrics", "", "write the time of each phase of the run and how many binaries each backend inspected into `file`, - for the standard error, in the Prometheus text format if it ends with .prom")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: totool [flags] file...\n")
-		flag.PrintDefaults()
+		printUsage(os.Stderr, flag.CommandLine)
+	}
+	if err := setFlagsFromEnv(flag.CommandLine); err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	// Bad flags exit with exitUsage rather than the status of the flag
+	// package.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	nflags := len(os.Args) - 1 - flag.NArg()
+	if err := setLogFormat(*logFormat); err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	level, err := verbosity(*quiet, *verbose, *debug)
+	if err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	if *outPath != "" {
+		if f, ok := outputFormat(*outPath); ok && !isFlagSet(flag.CommandLine, "format") {
+			*format = f
+		}
+		out, err := createOutput(*outPath)
+		if err != nil {
+			log.Print(err)
+			return exitInspection
+		}
+		defer func() {
+			if err := out.Close(); err != nil {
+				log.Print(err)
+				if exit == exitOK {
+					exit = exitInspection
+				}
+			}
+		}()
+	}
+	color, err := useColor(*colorMode, os.Stdout)
+	if err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	if c, _ := useColor(*colorMode, os.Stderr); c && jsonLog == nil && !xcodeLog {
+		log.SetPrefix(paint(true, ansiRed, "totool:") + " ")
 	}
-	flag.Parse()
 	args := flag.Args()
-	if len(args) == 0 {
+	// cmd is the subcommand, empty when only -r or -files give binaries to
+	// walk.
+	cmd := ""
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	sub, isSub := findSubcommand(cmd)
+	if sub.flagsAfter {
+		rest, err := parseInterspersed(flag.CommandLine, args[1:])
+		if err == flag.ErrHelp {
+			return exitOK
+		} else if err != nil {
+			return exitUsage
+		}
+		args = append(args[:1], rest...)
+	}
+	if (len(args) == 0 && len(scanDirs) == 0 && len(fileLists) == 0) || (cmd == "render" && len(args) == 1) {
 		flag.Usage()
-		os.Exit(1)
+		return exitUsage
 	}
-
-	var pt printer
-	if *dot {
-		pt = dotPrinter{}
-	} else {
-		pt = textPrinter{*verbose}
+	env := &cmdEnv{fs: flag.CommandLine}
+	if isSub && sub.early {
+		if err := sub.main(context.Background(), args[1:], env); err != nil {
+			log.Print(err)
+			return exitCode(err)
+		}
+		return exitOK
+	}
+	paging := *pager && isTerminal(os.Stdout) && !sub.interactive
+	if paging {
+		stop, err := startPager()
+		if err != nil {
+			log.Printf("cannot run pager: %v", err)
+			return exitInspection
+		}
+		defer stop()
 	}
 
-	for _, root := range args {
-		err := walk(root, pt)
+	if *cpuProfile != "" {
+		stop, err := startCPUProfile(*cpuProfile)
 		if err != nil {
-			log.Printf("%s: %v", root, err)
+			fatal(err)
+			return exitInspection
 		}
+		defer stop()
+	}
+	if *memProfile != "" {
+		defer writeMemProfile(*memProfile)
+	}
+	if *timing {
+		defer printTimes()
+	}
+	if *metricsPath != "" {
+		defer func() {
+			if err := writeMetrics(*metricsPath); err != nil {
+				log.Printf("cannot write metrics: %v", err)
+			}
+		}()
 	}
-}
 
-// dependency stores a single dependency found by otool.
-type dependency struct {
-	// path to binary
-	bin string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	cancelOnInterrupt(cancel)
 
-	// additional data (versions...)
-	info string
-}
+	if *remote != "" {
+		// -o writes here what the remote totool prints.
+		rargs := withoutFlag(withoutFlag(os.Args[1:1+nflags], "remote"), "o")
+		if *outPath != "" {
+			rargs = append(rargs, "-format="+*format)
+		}
+		return remoteMain(ctx, *remote, append(rargs, flag.Args()...))
+	}
 
-// A printer abstracts the rest of the program from the output layout.
-type printer interface {
-	// printPrologue is called before walking the dependency graph.
-	printPrologue()
+	totool.SetMaxProcs(*maxProcs)
+	if !*noCache {
+		totool.SetCacheDir(*cacheDir)
+	}
+	if *nodesOnly && *edgesOnly {
+		log.Print("-nodes-only and -edges-only are mutually exclusive")
+		return exitUsage
+	}
 
-	// printRootBin is called to print the binary we want to print dependencies of.
-	printRootBin(bin string)
+	opts := totool.Options{
+		MaxNodes: *maxNodes,
+		MaxDepth: *maxDepth,
+		Jobs:     *jobs,
+		Leaves:   leafBins,
 
-	// printDepBin is called when walking into a new binary.
-	printDepBin(d *dependency)
+		KeepGoing: *keepGoing,
+	}
+	resolver := totool.NewAutoResolver(totool.NewDyldResolver())
+	resolver.PE.SearchPath = dllDirs
+	if *elfRoot != "" {
+		root, err := filepath.Abs(*elfRoot)
+		if err != nil {
+			fatal(err)
+			return exitUsage
+		}
+		// The library path of the host is meaningless below root.
+		resolver.ELF.Root, resolver.ELF.LibraryPath = root, nil
+		totool.AddSystemRoot(root)
+	}
+	opts.Resolver = resolver
+	if level >= levelDebug {
+		totool.SetDebugLog(func(format string, args ...interface{}) {
+			logAt(logDebug, "", "", format, args...)
+		})
+	}
+	if *toolchain != "" {
+		if err := totool.SetToolchain(ctx, *toolchain); err != nil {
+			log.Print(err)
+			return exitUsage
+		}
+	}
+	if *otoolPath != "" {
+		totool.SetTool("otool", *otoolPath)
+	}
+	if opts.Inspector, err = totool.NewInspector(*backend); err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	opts.Inspector = totool.NewAutoInspector(opts.Inspector)
+	if *dlopen {
+		opts.Inspector = totool.NewDlopenInspector(opts.Inspector, opts.Resolver)
+	}
+	switch {
+	case *record != "" && *replay != "":
+		log.Print("-record and -replay are mutually exclusive")
+		return exitUsage
+	case *record != "":
+		rec, err := totool.NewRecorder(*record, opts.Inspector, opts.Resolver)
+		if err != nil {
+			fatal(err)
+			return exitInspection
+		}
+		opts.Inspector, opts.Resolver = rec, rec
+		defer func() {
+			if err := rec.Save(); err != nil {
+				log.Printf("cannot save recording: %v", err)
+			}
+		}()
+	case *replay != "":
+		rp := totool.NewReplayer(*replay)
+		opts.Inspector, opts.Resolver = rp, rp
+	}
+	if level >= levelDebug {
+		opts.Resolver = debugResolver{opts.Resolver}
+	}
+	if !*noProgress && !paging && level > levelQuiet && jsonLog == nil && !xcodeLog && showProgress() {
+		progress := newProgressLine(os.Stderr)
+		log.SetOutput(progress)
+		opts.Progress = progress.update
+		defer progress.clear()
+	}
+	if *eventsFD > 0 {
+		if err := openEvents(*eventsFD); err != nil {
+			log.Print(err)
+			return exitUsage
+		}
+		lineProgress := opts.Progress
+		opts.Progress = func(inspected, queued int, current string) {
+			if lineProgress != nil {
+				lineProgress(inspected, queued, current)
+			}
+			events.progress(inspected, queued, current)
+		}
+	}
+	if !*expandSystem {
+		opts.Leaves = append(opts.Leaves, totool.SystemLeaves...)
+	}
 
-	// printDep is called to print a direct dependency between from and to binaries.
-	printDep(from, to string)
+	if isSub && sub.main != nil {
+		env.opts, env.resolver, env.cacheDir = opts, resolver, *cacheDir
+		env.colorMode, env.dryRun, env.flatten, env.identity, env.runTimeout = *colorMode, *dryRun, *flatten, *signIdentity, *runTimeout
+		env.check = checkOptions{distributable: *distributable, fix: *fix, apply: *apply, identity: *signIdentity}
+		env.policyFile = *policyFile
+		if err := sub.main(ctx, args[1:], env); err != nil {
+			log.Print(err)
+			return exitCode(err)
+		}
+		return exitOK
+	}
 
-	// printEpilogue is called after walking all nodes in the dependency graph.
-	printEpilogue()
-}
+	if *scan {
+		scanDirs, args = append(scanDirs, args...), nil
+	}
+	if args, err = expandRootLists(args, fileLists, *nul); err != nil {
+		fatal(err)
+		return exitUsage
+	}
+	name := strings.Join(append(append([]string(nil), scanDirs...), args...), ", ")
+	relabel := func(path string) string { return path }
+	// skipped lists the files scans of directories and bundles left out.
+	var skipped []totool.SkippedFile
+	bundles := cmd != "render" && containsBundle(args)
+	if bundles {
+		tmp, err := ioutil.TempDir("", "totool-archives")
+		if err != nil {
+			fatal(err)
+			return exitInspection
+		}
+		defer os.RemoveAll(tmp)
+		var unmount func()
+		if args, relabel, unmount, err = expandBundles(ctx, args, tmp, &skipped); err != nil {
+			log.Print(err)
+			return exitCode(err)
+		}
+		defer unmount()
+		*merge = true
+	}
+	if len(scanDirs) > 0 {
+		for _, dir := range scanDirs {
+			found, left, err := totool.ScanDirSkipped(dir)
+			if err != nil {
+				fatal(err)
+				return exitInspection
+			}
+			args = append(args, found...)
+			skipped = append(skipped, left...)
+		}
+		*merge = true
+	}
+	if *skipNonBinaries && cmd != "render" {
+		args = withoutNonBinaries(args, relabel)
+	}
 
-// walk traverses the graph of dependencies of the root binary in breadth-first
-// order and call printer for each one.
-func walk(root string, pt printer) error {
-	root, err := filepath.Abs(root)
-	if err != nil {
-		return fmt.Errorf("cannot get %q absolute path: %v", root, err)
+	if *direct {
+		if err := printDirectDeps(ctx, args, opts); err != nil {
+			return exitCode(err)
+		}
+		return exitOK
 	}
 
-	pt.printPrologue()
-	defer pt.printEpilogue()
+	switch {
+	case *dot:
+		*format = "dot"
+	case *jsonOut:
+		*format = "json"
+	case *csvOut:
+		*format = "csv"
+	case *topo:
+		*format = "topo"
+	case *leaves:
+		*format = "leaves"
+	}
+	if *installNames && *format != "text" {
+		log.Print("-install-names only applies to the text format")
+		return exitUsage
+	}
+	label, err := pathLabel(stripPrefixes, *relativeTo)
+	if err != nil {
+		fatal(err)
+		return exitUsage
+	}
+	var alias func(string) (string, bool)
+	if *aliasFile != "" {
+		as, err := readAliases(*aliasFile)
+		if err != nil {
+			fatal(err)
+			return exitUsage
+		}
+		alias = as.lookup
+	}
+	pt, err := totool.NewPrinter(*format, totool.PrinterOptions{
+		Verbose:  level >= levelVerbose,
+		FanIn:    *fanIn,
+		Nodes:    !*edgesOnly,
+		Edges:    !*nodesOnly,
+		Merged:   *merge,
+		Hashes:   *hashes,
+		Licenses: *licenses,
+		Sizes:    *sizes,
+		Color:    color,
+		Label:    label,
+		Alias:    alias,
+		Skipped:  skipped,
+	})
+	if err != nil {
+		log.Print(err)
+		return exitUsage
+	}
+	if *sorted {
+		pt = &sortPrinter{pt: pt}
+	}
+	if *frameworks {
+		pt = &frameworkPrinter{pt: pt}
+	}
+	if bundles {
+		pt = relabelPrinter{pt: pt, relabel: relabel}
+	}
+	if *timing || *metricsPath != "" {
+		pt = timingPrinter{pt}
+	}
+	if *queryExpr != "" {
+		q, err := parseQuery(*queryExpr)
+		if err != nil {
+			log.Print(err)
+			return exitUsage
+		}
+		pt = &queryPrinter{pt: pt, q: q}
+	}
+	if events != nil {
+		pt = eventPrinter{pt}
+	}
 
-	toVisit := make([]dependency, 0)
-	toVisit = append(toVisit, dependency{root, ""})
+	var base *totool.Graph
+	if *baseline != "" {
+		var err error
+		if base, err = readSnapshot(*baseline); err != nil {
+			fatal(err)
+			return exitUsage
+		}
+	}
 
-	visited := make(map[string]bool)
+	// status is the exit status, reflecting the first failure.
+	status := exitOK
+	fail := func(code int) {
+		if status == exitOK {
+			status = code
+		}
+	}
+	// failures accumulates what -keep-going walks could not inspect.
+	var failures []failure
+	report := func(name string, g *totool.Graph) {
+		if fs := failuresOf(name, g); len(fs) > 0 {
+			failures = append(failures, fs...)
+			fail(exitCode(fs[0].err))
+		}
+		// warnings counts what warnf reported, printed or not.
+		warnings := 0
+		// warnf reports what looks wrong about path in g unless -q is set.
+		warnf := func(path, format string, args ...interface{}) {
+			warnings++
+			if level > levelQuiet {
+				logAt(logWarning, name, path, format, args...)
+			}
+			if *strict {
+				fail(exitCheck)
+			}
+		}
+		if n := g.Truncated(); n > 0 {
+			warnf("", "graph truncated, %d binaries not expanded", n)
+		}
+		// Backend warnings are reported once the graph is printed rather
+		// than mixed with it.
+		if level >= levelVerbose {
+			for _, bin := range g.Bins() {
+				for _, w := range g.Warnings(bin) {
+					if !strings.Contains(w, bin) {
+						w = bin + ": " + w
+					}
+					logAt(logWarning, name, bin, "%s", w)
+				}
+			}
+		}
+		for _, c := range g.Cycles() {
+			warnf("", "dependency cycle: %s", strings.Join(c, " -> "))
+		}
+		for _, d := range g.Duplicates() {
+			warnf("", "duplicate library: %s", strings.Join(d, ", "))
+		}
+		for _, c := range g.Collisions() {
+			warnf("", "colliding library: %s", c)
+		}
+		for _, bin := range g.Bins() {
+			if note, ok := totool.Deprecated(bin); ok {
+				warnf(bin, "deprecated library: %s (%s)", bin, note)
+			}
+		}
+		for _, bin := range g.Bins() {
+			if v, ok := totool.FrameworkVersionsOf(bin); ok && v.Problem != "" {
+				warnf(bin, "%s: %s", v.Framework, v.Problem)
+			}
+		}
+		for _, bin := range g.Bins() {
+			if g.IsRoot(bin) || g.Err(bin) != nil {
+				continue
+			}
+			if where, ok := volumeHazard(bin); ok {
+				warnf(bin, "%s: %s, missing when unmounted", bin, where)
+			}
+		}
+		for _, bin := range g.Bins() {
+			if why, ok := totool.KegDrift(bin); ok {
+				warnf(bin, "keg path %s linked by %s: %s", bin, strings.Join(g.Dependents(bin), ", "), why)
+			}
+		}
+		for _, c := range g.VersionConflicts() {
+			warnf("", "version conflict: %s", c)
+		}
+		for _, m := range g.VersionMismatches() {
+			warnf(m.To, "%s: %v", m.To, m)
+		}
+		if *warnFanOut > 0 {
+			for _, bin := range g.Bins() {
+				if n := g.FanOut(bin); n > *warnFanOut {
+					warnf(bin, "%s has %d direct dependencies", bin, n)
+				}
+			}
+		}
+		if base != nil {
+			for _, bin := range diffGraphs(base, g).added {
+				log.Printf("%s: dependency not in baseline: %s", name, bin)
+				fail(exitCheck)
+			}
+		}
+		if *stats {
+			printStats(g)
+		}
+		if *longest {
+			printLongestChain(g)
+		}
+		if *dominators {
+			printDominators(g)
+		}
+		if *attribution {
+			printContributions(g)
+		}
+		if *sizes {
+			printSizes(g)
+		}
+		if *namespaces {
+			printNamespaces(g)
+		}
+		if *installNames {
+			printInstallNames(g, totool.PrinterOptions{Label: label}.LabelOf)
+		}
+		if *paths {
+			printPathCounts(g)
+		}
+		if *classes {
+			printOrigins(g)
+		}
+		if *signatures || *entitlements {
+			printSignatures(ctx, g, *entitlements)
+		}
+		if *hardening {
+			printHardening(ctx, g)
+		}
+		if *quarantine && printQuarantined(ctx, g) > 0 {
+			fail(exitCheck)
+		}
+		if *checkSec {
+			printCheckSec(g)
+		}
+		if *notarization && printNotarization(ctx, g) > 0 {
+			fail(exitCheck)
+		}
+		if *insecure && printInsecure(g) > 0 {
+			fail(exitCheck)
+		}
+		if *licenses {
+			printLicenses(g)
+		}
+		if *formulae {
+			printFormulae(ctx, g)
+		}
+		if *rosetta && printRosetta(g) > 0 {
+			fail(exitCheck)
+		}
+		if *undefined && printUndefined(g, opts.Resolver) > 0 {
+			fail(exitCheck)
+		}
+		if *weak {
+			printWeak(g)
+		}
+		if *qt && printQt(ctx, g, opts) > 0 {
+			fail(exitCheck)
+		}
+		if *swift {
+			printSwift(g)
+		}
+		if *vulns {
+			printVulns(ctx, g, &totool.OSVClient{Ecosystem: *osvEcosystem, Offline: *offline})
+		}
+		if *summary {
+			printSummary(name, g, warnings)
+			if len(skipped) > 0 {
+				printSkipped(name, skipped, level >= levelVerbose)
+			}
+		}
+	}
 
-	for len(toVisit) > 0 {
-		var from dependency
-		from, toVisit = toVisit[0], toVisit[1:]
-		if !visited[from.bin] {
-			visited[from.bin] = true
-			if from.bin == root {
-				pt.printRootBin(root)
-			} else {
-				pt.printDepBin(&from)
+	if cmd == "render" {
+		for _, path := range args[1:] {
+			g, err := readSnapshot(path)
+			if err != nil {
+				log.Print(err)
+				fail(exitCheck)
+				continue
 			}
-			i := len(toVisit)
-			toVisit, err = appendDirectDeps(toVisit, from.bin)
+			g.Render(pt)
+			report(path, g)
+		}
+		return status
+	}
+
+	// results tells how much of the graph of each root was walked.
+	var results []rootResult
+	if *merge {
+		w := totool.NewWalker(pt, opts)
+		pt.PrintPrologue()
+		for _, root := range args {
+			failed := len(w.Graph().Failed())
+			start := time.Now()
+			err := withRootTimeout(ctx, *rootTimeout, func(ctx context.Context) error {
+				return w.WalkContext(ctx, root)
+			})
+			addTime(&walkTime, start)
+			results = append(results, rootResultOf(relabel(root), w.Graph(), failed, err))
 			if err != nil {
+				logRootError(relabel(root), err)
+				fail(exitCode(err))
+				if ctx.Err() != nil {
+					break
+				}
+			}
+		}
+		pt.PrintEpilogue()
+		report(name, w.Graph())
+	} else {
+		for _, root := range args {
+			start := time.Now()
+			var g *totool.Graph
+			err := withRootTimeout(ctx, *rootTimeout, func(ctx context.Context) (err error) {
+				g, err = totool.WalkContext(ctx, root, pt, opts)
 				return err
+			})
+			addTime(&walkTime, start)
+			r := rootResultOf(root, g, 0, err)
+			results = append(results, r)
+			if err != nil {
+				logRootError(root, err)
+				fail(exitCode(err))
+				if ctx.Err() != nil {
+					break
+				}
 			}
-			for _, to := range toVisit[i:] {
-				pt.printDep(from.bin, to.bin)
+			// Partial graphs are worth reporting about too.
+			if r.status != rootFailed {
+				report(root, g)
 			}
 		}
 	}
+	logFailures(failures)
+	events.write(event{Event: "done"})
+	if len(results) > 1 && level > levelQuiet && jsonLog == nil && !xcodeLog {
+		printRootResults(log.Writer(), results)
+	}
 
-	return nil
+	return status
 }
 
-// depRe matches on otool output line.
-// 	/usr/lib/libobjc.A.dylib (compatibility version 1.0.0, current version 228.0.0, upward)
-var depRe = regexp.MustCompile(`\s*(.*)\s+(\(.*\))`)
-
-// appendDirectDeps calls otool on bin and appends its dependencies to deps and
-// returns the augmented slice.
-func appendDirectDeps(deps []dependency, bin string) ([]dependency, error) {
-	cmd := exec.Command("otool", "-L", bin)
-	out, err := cmd.Output()
-	if err != nil {
-		err := err.(*exec.ExitError)
-		fmt.Fprintf(os.Stderr, "%s", string(err.Stderr))
-		return deps, fmt.Errorf("otool error when processing %s", bin)
-	}
+// Exit statuses.
+const (
+	exitOK         = 0
+	exitUsage      = 1 // bad command line
+	exitInspection = 2 // binaries could not be found or inspected, or other errors
+	exitCheck      = 3 // checks failed, or warnings were printed under -strict
+	exitCanceled   = 4 // -timeout or -root-timeout expired or interrupted
+)
 
-	s := bufio.NewScanner(bytes.NewReader(out))
+// A checkError reports that checks found problems.
+type checkError struct{ err error }
 
-	// Skip first line (the binary we are inspecting)
-	s.Scan()
+func (e checkError) Error() string { return e.err.Error() }
+func (e checkError) Unwrap() error { return e.err }
 
-	for s.Scan() {
-		sms := depRe.FindStringSubmatch(s.Text())
-		if len(sms) != 3 {
-			panic(fmt.Sprintf("unexpected otool output: %q, matched %v", s.Text(), sms))
-		}
-		depbin := resolveDepPath(bin, sms[1])
-		if depbin != bin {
-			deps = append(deps, dependency{depbin, sms[2]})
-		} else {
-			// The first dependency is the binary itself probably to display extra info about it.
-			// Filter it out to avoid displaying self-edges in the graph.
-		}
+// exitCode returns the exit status reporting err.
+func exitCode(err error) int {
+	var ue usageError
+	var ce checkError
+	switch {
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return exitCanceled
+	case errors.As(err, &ce):
+		return exitCheck
+	default:
+		return exitInspection
 	}
-
-	return deps, s.Err()
 }
 
-// resolveDepPath transforms a path emitted by otool representing a dependency
-// of bin into an real path that can be fed back into otool.
-func resolveDepPath(bin, path string) string {
-	const relPrefix = "@executable_path/"
-	if strings.HasPrefix(path, relPrefix) {
-		bindir := filepath.Dir(bin) + string(filepath.Separator)
-		return filepath.Clean(strings.Replace(path, relPrefix, bindir, 1))
-	}
-	return path
+// cancelOnInterrupt calls cancel on the first interrupt so that walks stop
+// cleanly.  Later interrupts kill the process as usual.
+func cancelOnInterrupt(cancel func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		signal.Stop(c)
+		cancel()
+	}()
 }
@@ -0,0 +1,186 @@
+package totool
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadCmdLinkerOption is the load command of the auto-link directives of
+// object files, which debug/macho does not name.
+const loadCmdLinkerOption macho.LoadCmd = 0x2d
+
+// errNotObject reports files AutoLinks cannot read.
+var errNotObject = errors.New("not a Mach-O object file or static library")
+
+// An AutoLink lists the auto-link directives of a Mach-O object file, which
+// compilers record in LC_LINKER_OPTION load commands for the linker to add
+// the libraries and frameworks of the modules the object file imports.
+type AutoLink struct {
+	// Member is the name of the object file in its static library, empty
+	// for object files given on their own.
+	Member string
+
+	// Options are the linker options, such as "-framework Foundation" or
+	// "-lz".
+	Options []string
+}
+
+// AutoLinks returns the auto-link directives of path, a Mach-O object file
+// or a static library holding some.  Only the first architecture of
+// universal files is considered and archive members that are not Mach-O
+// object files are skipped.
+func AutoLinks(path string) ([]AutoLink, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrMissingFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r := io.NewSectionReader(f, 0, fi.Size())
+	if r, err = firstArch(r); err != nil {
+		return nil, err
+	}
+	var magic [8]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		return nil, errNotObject
+	}
+	if string(magic[:]) != "!<arch>\n" {
+		opts, err := linkerOptions(r)
+		if err != nil {
+			return nil, errNotObject
+		}
+		return []AutoLink{{Options: opts}}, nil
+	}
+	members, err := archiveMembers(r)
+	if err != nil {
+		return nil, err
+	}
+	var links []AutoLink
+	for _, m := range members {
+		if opts, err := linkerOptions(m.r); err == nil {
+			links = append(links, AutoLink{Member: m.name, Options: opts})
+		}
+	}
+	return links, nil
+}
+
+// firstArch returns the first architecture of r if it is a universal file,
+// r itself otherwise.  Universal static libraries hold archives rather than
+// Mach-O files, which debug/macho does not accept.
+func firstArch(r *io.SectionReader) (*io.SectionReader, error) {
+	var head [8 + 20]byte
+	if _, err := r.ReadAt(head[:], 0); err != nil || binary.BigEndian.Uint32(head[:]) != macho.MagicFat {
+		return r, nil
+	}
+	if binary.BigEndian.Uint32(head[4:]) == 0 {
+		return nil, fmt.Errorf("universal file without architectures")
+	}
+	off := int64(binary.BigEndian.Uint32(head[16:]))
+	size := int64(binary.BigEndian.Uint32(head[20:]))
+	return io.NewSectionReader(r, off, size), nil
+}
+
+// linkerOptions returns the options of the LC_LINKER_OPTION load commands of
+// the Mach-O file r, each one's strings joined with spaces.
+func linkerOptions(r io.ReaderAt) ([]string, error) {
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	var opts []string
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 12 || macho.LoadCmd(f.ByteOrder.Uint32(raw)) != loadCmdLinkerOption {
+			continue
+		}
+		n := int(f.ByteOrder.Uint32(raw[8:]))
+		args := strings.Split(string(raw[12:]), "\x00")
+		if n > len(args) {
+			n = len(args)
+		}
+		opts = append(opts, strings.Join(args[:n], " "))
+	}
+	return opts, nil
+}
+
+// An archiveMember is a file of a static library.
+type archiveMember struct {
+	name string
+	r    *io.SectionReader
+}
+
+// archiveMembers returns the members of the static library r, in the BSD or
+// GNU ar format, leaving out symbol tables.
+func archiveMembers(r *io.SectionReader) ([]archiveMember, error) {
+	const headerSize = 60
+	var members []archiveMember
+	var longNames []byte
+	for off := int64(8); off+headerSize <= r.Size(); {
+		var h [headerSize]byte
+		if _, err := r.ReadAt(h[:], off); err != nil {
+			return nil, err
+		}
+		if string(h[58:60]) != "`\n" {
+			return nil, fmt.Errorf("corrupt archive member header at offset %d", off)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(string(h[48:58])), 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("corrupt archive member size at offset %d", off)
+		}
+		data := off + headerSize
+		next := data + size + size%2
+		name := strings.TrimRight(string(h[:16]), " ")
+		switch {
+		case strings.HasPrefix(name, "#1/"):
+			// BSD archives store long names before the data.
+			n, err := strconv.ParseInt(name[3:], 10, 64)
+			if err != nil || n > size {
+				return nil, fmt.Errorf("corrupt archive member name at offset %d", off)
+			}
+			buf := make([]byte, n)
+			if _, err := r.ReadAt(buf, data); err != nil {
+				return nil, err
+			}
+			name = string(bytes.TrimRight(buf, "\x00"))
+			data += n
+			size -= n
+		case name == "//":
+			// GNU archives store long names in a table.
+			longNames = make([]byte, size)
+			if _, err := r.ReadAt(longNames, data); err != nil {
+				return nil, err
+			}
+			off = next
+			continue
+		case strings.HasPrefix(name, "/") && len(name) > 1 && longNames != nil:
+			i, err := strconv.Atoi(name[1:])
+			if err != nil || i >= len(longNames) {
+				return nil, fmt.Errorf("corrupt archive member name at offset %d", off)
+			}
+			name = string(longNames[i:])
+			if j := strings.IndexAny(name, "/\n"); j >= 0 {
+				name = name[:j]
+			}
+		default:
+			name = strings.TrimSuffix(name, "/")
+		}
+		if name != "" && !strings.HasPrefix(name, "__.SYMDEF") {
+			members = append(members, archiveMember{name, io.NewSectionReader(r, data, size)})
+		}
+		off = next
+	}
+	return members, nil
+}
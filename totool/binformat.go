@@ -0,0 +1,221 @@
+package totool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// A BinaryFormat is a file format of binaries.
+type BinaryFormat int
+
+// Binary formats.
+const (
+	FormatUnknown BinaryFormat = iota
+	FormatMachO                // thin mach-o binary
+	FormatFat                  // universal binary holding mach-o binaries
+	FormatELF
+	FormatPE
+)
+
+func (f BinaryFormat) String() string {
+	switch f {
+	case FormatMachO:
+		return "mach-o"
+	case FormatFat:
+		return "fat mach-o"
+	case FormatELF:
+		return "ELF"
+	case FormatPE:
+		return "PE"
+	default:
+		return "unknown"
+	}
+}
+
+// A FormatError reports a file that is not a binary of a supported format.
+type FormatError struct {
+	Path string
+
+	// Kind describes what the file looks like, if known.
+	Kind string
+
+	// Want is the format expected by the backend that failed on the file,
+	// FormatUnknown if any supported format would do.
+	Want BinaryFormat
+}
+
+// Error leaves Path out as callers report which binary failed.
+func (e *FormatError) Error() string {
+	msg := "not a mach-o, ELF or PE binary"
+	if e.Want != FormatUnknown {
+		msg = fmt.Sprintf("not a %v binary", e.Want)
+	}
+	if e.Kind != "" {
+		msg += ": " + e.Kind
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrNotMachO) hold for FormatErrors.
+func (e *FormatError) Is(target error) bool {
+	return target == ErrNotMachO
+}
+
+// DetectFormat returns the format of the binary at path from its magic number.
+// It returns ErrMissingFile if path does not exist and a FormatError if it is
+// not a binary of a known format.
+func DetectFormat(path string) (BinaryFormat, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return FormatUnknown, ErrMissingFile
+	}
+	if err != nil {
+		return FormatUnknown, err
+	}
+	defer f.Close()
+	var head [64]byte
+	n, err := io.ReadFull(f, head[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return FormatUnknown, fmt.Errorf("%s: %v", path, err)
+	}
+	if format := formatOf(f, head[:n]); format != FormatUnknown {
+		return format, nil
+	}
+	return FormatUnknown, &FormatError{Path: path, Kind: formatKind(head[:n])}
+}
+
+// formatOf returns the format of the binary f starting with head.
+func formatOf(f io.ReaderAt, head []byte) BinaryFormat {
+	if len(head) < 4 {
+		return FormatUnknown
+	}
+	for _, m := range [][]byte{
+		{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe}, // 32-bit
+		{0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe}, // 64-bit
+	} {
+		if bytes.Equal(head[:4], m) {
+			return FormatMachO
+		}
+	}
+	switch {
+	case bytes.Equal(head[:4], []byte{0xca, 0xfe, 0xba, 0xbe}) || bytes.Equal(head[:4], []byte{0xca, 0xfe, 0xba, 0xbf}):
+		// Java class files share the magic number of fat binaries, they
+		// follow it with a version of at least 45 where fat binaries have
+		// their number of architectures.
+		if len(head) >= 8 && binary.BigEndian.Uint32(head[4:]) < 45 {
+			return FormatFat
+		}
+	case bytes.Equal(head[:4], []byte("\x7fELF")):
+		return FormatELF
+	case bytes.HasPrefix(head, []byte("MZ")) && len(head) >= 0x40:
+		// DOS executables are PE binaries if the offset at 0x3c points to
+		// the PE signature.
+		var sig [4]byte
+		off := int64(binary.LittleEndian.Uint32(head[0x3c:]))
+		if _, err := f.ReadAt(sig[:], off); err == nil && string(sig[:]) == "PE\x00\x00" {
+			return FormatPE
+		}
+	}
+	return FormatUnknown
+}
+
+// formatKind describes the non-binary file starting with head, returning an
+// empty string if it is not recognized.
+func formatKind(head []byte) string {
+	switch {
+	case len(head) == 0:
+		return "empty file"
+	case bytes.HasPrefix(head, []byte("#!")):
+		return scriptKind(head)
+	case bytes.HasPrefix(head, []byte("!<arch>\n")):
+		return "static library"
+	case bytes.HasPrefix(head, []byte{0xca, 0xfe, 0xba, 0xbe}):
+		return "Java class file"
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return "DOS executable"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return "zip archive"
+	case isText(head):
+		return "text file"
+	}
+	return ""
+}
+
+// scriptKind describes the script starting with head after its interpreter.
+//
+//	#!/usr/bin/env python3 -> Python script
+func scriptKind(head []byte) string {
+	line := string(head[2:])
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) > 1 && path.Base(fields[0]) == "env" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "script"
+	}
+	switch name := strings.TrimRight(path.Base(fields[0]), "0123456789."); name {
+	case "sh", "bash", "zsh", "dash", "ksh", "csh", "tcsh":
+		return "shell script"
+	case "python":
+		return "Python script"
+	case "perl":
+		return "Perl script"
+	case "ruby":
+		return "Ruby script"
+	case "node":
+		return "JavaScript script"
+	default:
+		return name + " script"
+	}
+}
+
+// isText reports whether head looks like the start of a text file: UTF-8
+// without control characters other than white space.
+func isText(head []byte) bool {
+	// The last rune may be cut.
+	for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+	for _, b := range head {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// isFormat reports whether path is a binary of one of formats.
+func isFormat(path string, formats ...BinaryFormat) bool {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return false
+	}
+	for _, f := range formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// wrongFormat returns the error reporting that bin, which a backend for want
+// binaries failed to parse, is missing or of another format.
+func wrongFormat(bin string, want BinaryFormat) error {
+	format, err := DetectFormat(bin)
+	if err != nil {
+		return err
+	}
+	return &FormatError{Path: bin, Kind: fmt.Sprintf("%v binary", format), Want: want}
+}
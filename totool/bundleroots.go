@@ -0,0 +1,87 @@
+package totool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IsAppBundle reports whether path is the directory of an app bundle.
+func IsAppBundle(path string) bool {
+	if filepath.Ext(strings.TrimSuffix(path, "/")) != ".app" {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(path, "Contents"))
+	return err == nil && fi.IsDir()
+}
+
+// BundleRoots returns the binaries of app worth walking as roots: its main
+// executable, from its Info.plist, then the main executables of the bundles it
+// embeds, such as frameworks, plug-ins, XPC services and helper apps, and the
+// other mach-o binaries it holds, outermost first.
+func BundleRoots(app string) ([]string, error) {
+	roots, _, err := BundleRootsSkipped(app)
+	return roots, err
+}
+
+// BundleRootsSkipped is like BundleRoots but also returns the other files of
+// app, in walk order, along with why they were skipped.  Unreadable
+// directories are skipped rather than failing the scan.
+func BundleRootsSkipped(app string) ([]string, []SkippedFile, error) {
+	app, err := filepath.Abs(app)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, main := bundleCode(app)
+	if fi, err := os.Stat(main); err != nil || !fi.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%s: main executable %s: %w", app, main, ErrMissingFile)
+	}
+	roots := []string{main}
+	seen := map[string]bool{main: true}
+	add := func(bin string) {
+		if !seen[bin] {
+			seen[bin] = true
+			roots = append(roots, bin)
+		}
+	}
+	var skipped []SkippedFile
+	err = filepath.Walk(app, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == app {
+				return err
+			}
+			s, _ := skippedFile(path, err)
+			skipped = append(skipped, s)
+			return nil
+		}
+		if fi.IsDir() && path != app && bundleExts[filepath.Ext(path)] {
+			if _, main := bundleCode(path); isMachO(main) {
+				add(main)
+			}
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		switch format, err := DetectFormat(path); {
+		case format == FormatMachO || format == FormatFat:
+			add(path)
+		case err == nil:
+			skipped = append(skipped, SkippedFile{Path: path, Reason: SkipWrongFormat, Detail: format.String() + " binary"})
+		default:
+			if s, ok := skippedFile(path, err); ok {
+				skipped = append(skipped, s)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	rest := roots[1:]
+	sort.SliceStable(rest, func(i, j int) bool {
+		return strings.Count(rest[i], "/") < strings.Count(rest[j], "/")
+	})
+	return roots, skipped, nil
+}
@@ -0,0 +1,220 @@
+package totool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// inspections memoizes the direct dependencies of binaries found by otool for
+// the duration of the process so that binaries shared by several roots are
+// inspected once.
+var inspections = struct {
+	sync.Mutex
+	m map[string]inspection
+}{m: make(map[string]inspection)}
+
+// Forget drops what was memoized about bin for it to be looked at again, as
+// needed once bin is modified.
+func Forget(bin string) {
+	inspections.Lock()
+	delete(inspections.m, bin)
+	inspections.Unlock()
+	metas.Lock()
+	delete(metas.m, bin)
+	metas.Unlock()
+}
+
+// ForgetAll drops what was memoized about all binaries, for long-running
+// processes to bound their memory and to look at binaries again.
+func ForgetAll() {
+	inspections.Lock()
+	inspections.m = make(map[string]inspection)
+	inspections.Unlock()
+	metas.Lock()
+	metas.m = make(map[string]*binMeta)
+	metas.Unlock()
+}
+
+// inspectBins returns the direct dependencies of bins, which must be distinct.
+// Binaries neither inspected before nor cached on disk are inspected by a
+// single otool run.  Failures due to ctx being done are not memoized.  The
+// returned slices must not be modified.
+func inspectBins(ctx context.Context, bins []string) []inspection {
+	results := make([]inspection, len(bins))
+	done := make([]bool, len(bins))
+	inspections.Lock()
+	for i, bin := range bins {
+		results[i], done[i] = inspections.m[bin]
+	}
+	inspections.Unlock()
+
+	outs := make(map[string][]byte)
+	var missing []string
+	for i, bin := range bins {
+		if done[i] {
+			continue
+		}
+		if out, ok := readDiskCache(bin); ok {
+			outs[bin] = out
+		} else {
+			missing = append(missing, bin)
+		}
+	}
+
+	// Output along with warnings is not cached on disk, for later runs to
+	// report them too.  Batches with warnings naming none of their binaries
+	// are run again one binary at a time to tell which one they are about.
+	warnings := make(map[string][]string)
+	if len(missing) > 1 {
+		if out, ws, err := otool(ctx, missing...); err == nil && len(ws[""]) == 0 {
+			for bin, o := range splitOtoolOutput(out) {
+				outs[bin] = o
+				warnings[bin] = ws[bin]
+				if len(ws[bin]) == 0 {
+					writeDiskCache(bin, o)
+				}
+			}
+		}
+	}
+
+	for i, bin := range bins {
+		if done[i] {
+			continue
+		}
+		r := &results[i]
+		out, ok := outs[bin]
+		if !ok {
+			// Run otool on its own to report errors about this binary only.
+			var ws map[string][]string
+			if out, ws, r.err = otool(ctx, bin); r.err == nil {
+				warnings[bin] = ws[bin]
+				if len(ws[bin]) == 0 {
+					writeDiskCache(bin, out)
+				}
+			} else if ctx.Err() != nil {
+				continue
+			}
+		}
+		r.warnings = warnings[bin]
+		if r.err == nil {
+			r.deps, r.err = appendDirectDeps(nil, bin, out)
+		}
+
+		inspections.Lock()
+		inspections.m[bin] = *r
+		inspections.Unlock()
+	}
+	return results
+}
+
+// diskCacheDir is where otool output is cached across runs, empty to disable.
+var diskCacheDir string
+
+// diskCacheExt is the extension of files in diskCacheDir.
+const diskCacheExt = ".otool"
+
+// SetCacheDir caches otool output across runs in dir, the empty string
+// disabling caching.
+func SetCacheDir(dir string) {
+	diskCacheDir = dir
+}
+
+// DefaultCacheDir returns the directory where otool output is cached unless
+// told otherwise.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "totool")
+}
+
+// diskCachePath returns where the otool output for bin is cached or the empty
+// string if it cannot be cached.
+func diskCachePath(bin string) string {
+	if diskCacheDir == "" {
+		return ""
+	}
+	fi, err := metadataOf(bin).fileInfo()
+	if err != nil {
+		return ""
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d", bin, fi.ModTime().UnixNano(), fi.Size())))
+	return filepath.Join(diskCacheDir, hex.EncodeToString(key[:])+diskCacheExt)
+}
+
+// readDiskCache returns the otool output for bin cached by a previous run if
+// any.
+func readDiskCache(bin string) ([]byte, bool) {
+	path := diskCachePath(bin)
+	if path == "" {
+		return nil, false
+	}
+	out, err := ioutil.ReadFile(path)
+	if err == nil {
+		debugf("%s: otool output cached in %s", bin, path)
+		countBackend("cache")
+	}
+	return out, err == nil
+}
+
+// writeDiskCache caches out, the otool output for bin, for later runs.
+func writeDiskCache(bin string, out []byte) {
+	path := diskCachePath(bin)
+	if path == "" {
+		return
+	}
+	if err := writeCacheFile(path, out); err != nil {
+		log.Printf("cannot cache otool output: %v", err)
+	}
+}
+
+// writeCacheFile atomically writes data into path.
+func writeCacheFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// ClearCache removes the otool output, scan results and OSV answers cached in
+// dir.
+// Other files are left alone in case dir is shared with something else.
+func ClearCache(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("no cache directory")
+	}
+	for _, ext := range []string{diskCacheExt, scanIndexExt, osvCacheExt} {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package totool
+
+import (
+	"debug/macho"
+)
+
+// Hardening lists the exploit mitigations a binary was built with.  For fat
+// binaries, a mitigation is reported only if all architectures have it.
+type Hardening struct {
+	// PIE is set for position-independent executables and for libraries,
+	// which always are.
+	PIE bool
+
+	// StackCanary is set when the binary checks stack canaries.
+	StackCanary bool
+
+	// ARC is set when the binary uses automatic reference counting.
+	ARC bool
+
+	// Restrict is set when the binary has a __RESTRICT segment, making dyld
+	// ignore DYLD_ environment variables.
+	Restrict bool
+
+	// Encrypted is set when the binary is encrypted as App Store binaries are.
+	Encrypted bool
+}
+
+// Load commands describing encrypted segments, not decoded by debug/macho.
+const (
+	loadCmdEncryptionInfo   macho.LoadCmd = 0x21
+	loadCmdEncryptionInfo64 macho.LoadCmd = 0x2c
+)
+
+// CheckSec returns the mitigations bin was built with.
+func CheckSec(bin string) (Hardening, error) {
+	if ff, err := macho.OpenFat(bin); err == nil {
+		defer ff.Close()
+		h := Hardening{PIE: true, StackCanary: true, ARC: true, Restrict: true, Encrypted: true}
+		for _, a := range ff.Arches {
+			ah := checkSec(a.File)
+			h.PIE = h.PIE && ah.PIE
+			h.StackCanary = h.StackCanary && ah.StackCanary
+			h.ARC = h.ARC && ah.ARC
+			h.Restrict = h.Restrict && ah.Restrict
+			h.Encrypted = h.Encrypted && ah.Encrypted
+		}
+		return h, nil
+	}
+
+	f, err := macho.Open(bin)
+	if err != nil {
+		return Hardening{}, err
+	}
+	defer f.Close()
+	return checkSec(f), nil
+}
+
+// checkSec returns the mitigations of the architecture f of a binary.
+func checkSec(f *macho.File) Hardening {
+	h := Hardening{
+		PIE:      f.Type != macho.TypeExec || f.Flags&macho.FlagPIE != 0,
+		Restrict: f.Segment("__RESTRICT") != nil,
+	}
+	syms, _ := f.ImportedSymbols()
+	for _, sym := range syms {
+		switch sym {
+		case "___stack_chk_fail", "___stack_chk_guard":
+			h.StackCanary = true
+		case "_objc_release", "_objc_retain", "_objc_storeStrong", "_objc_autoreleaseReturnValue":
+			h.ARC = true
+		}
+	}
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 20 {
+			continue
+		}
+		switch macho.LoadCmd(f.ByteOrder.Uint32(raw)) {
+		case loadCmdEncryptionInfo, loadCmdEncryptionInfo64:
+			// cryptid follows cryptoff and cryptsize.
+			h.Encrypted = h.Encrypted || f.ByteOrder.Uint32(raw[16:]) != 0
+		}
+	}
+	return h
+}
@@ -0,0 +1,301 @@
+package totool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// SigningStatus classifies how a binary is code signed.
+type SigningStatus int
+
+const (
+	// Unsigned binaries have no code signature.
+	Unsigned SigningStatus = iota
+
+	// AdHocSigned binaries are signed without an identity.
+	AdHocSigned
+
+	// Signed binaries are signed by an identity.
+	Signed
+)
+
+func (s SigningStatus) String() string {
+	switch s {
+	case AdHocSigned:
+		return "ad-hoc signed"
+	case Signed:
+		return "signed"
+	default:
+		return "unsigned"
+	}
+}
+
+// A Signature describes the code signature of a binary as reported by
+// codesign.
+type Signature struct {
+	Status SigningStatus
+
+	// Identity is the leaf signing authority of signed binaries.
+	Identity string
+
+	// TeamID is the team identifier of the signing identity, empty if not
+	// set.
+	TeamID string
+
+	// Flags lists the flags of the code directory, "runtime" for the hardened
+	// runtime for instance.
+	Flags []string
+
+	// Valid is set when "codesign --verify --strict" accepts the signature.
+	Valid bool
+
+	// Problem is why the signature is invalid as codesign reports it.
+	Problem string
+}
+
+// HardenedRuntime reports whether the binary runs with the hardened runtime.
+func (s *Signature) HardenedRuntime() bool {
+	return contains(s.Flags, "runtime")
+}
+
+// DisableLibraryValidation is the entitlement exempting binaries running with
+// the hardened runtime from library validation.
+const DisableLibraryValidation = "com.apple.security.cs.disable-library-validation"
+
+// LibraryValidation reports whether the binary, signed with entitlements ents,
+// only loads libraries signed by Apple or by its own team.  The hardened
+// runtime implies it unless ents disable it.
+func (s *Signature) LibraryValidation(ents []Entitlement) bool {
+	if contains(s.Flags, "library-validation") {
+		return true
+	}
+	for _, e := range ents {
+		if e.Key == DisableLibraryValidation && e.Value == "true" {
+			return false
+		}
+	}
+	return s.HardenedRuntime()
+}
+
+// LoadProblem returns why a binary enforcing library validation and signed
+// with s would refuse to load a non-Apple library signed with lib, or the
+// empty string if it would load it.
+func (s *Signature) LoadProblem(lib *Signature) string {
+	switch {
+	case lib.Status != Signed:
+		return lib.Status.String()
+	case !lib.Valid:
+		return "invalid signature"
+	case lib.TeamID != s.TeamID:
+		return fmt.Sprintf("signed by team %q instead of %q", lib.TeamID, s.TeamID)
+	default:
+		return ""
+	}
+}
+
+// CodeSignature returns the signature of bin by running codesign.
+func CodeSignature(ctx context.Context, bin string) (Signature, error) {
+	_, out, err := codesign(ctx, bin, "-d", "--verbose=2")
+	if err != nil {
+		if ctx.Err() != nil {
+			return Signature{}, ctx.Err()
+		}
+		if strings.Contains(out, "not signed at all") {
+			return Signature{Status: Unsigned}, nil
+		}
+		return Signature{}, toolErrorStderr("codesign", bin, out, err)
+	}
+	sig := parseCodesign(out)
+
+	_, out, err = codesign(ctx, bin, "--verify", "--strict")
+	if ctx.Err() != nil {
+		return Signature{}, ctx.Err()
+	}
+	if err == nil {
+		sig.Valid = true
+	} else if _, ok := err.(*exec.ExitError); ok {
+		sig.Problem = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(out), bin+":"))
+	} else {
+		return Signature{}, toolErrorStderr("codesign", bin, out, err)
+	}
+	return sig, nil
+}
+
+// codesign runs codesign with args on bin and returns what it printed on its
+// standard output and error, where it reports everything but extracted data.
+func codesign(ctx context.Context, bin string, args ...string) ([]byte, string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tool("codesign"), append(args, bin)...)
+	cmd.Stderr = &stderr
+	out, err := output(ctx, cmd)
+	return out, stderr.String(), err
+}
+
+// parseCodesign parses the output of "codesign -d --verbose=2":
+//
+//	Executable=/usr/local/bin/foo
+//	Identifier=foo
+//	Signature=adhoc
+//	CodeDirectory v=20500 size=1234 flags=0x10000(runtime) hashes=30+7 location=embedded
+//	Authority=Developer ID Application: Some One (TEAMID)
+//	TeamIdentifier=TEAMID
+//
+// The first authority is the leaf one.
+func parseCodesign(out string) Signature {
+	sig := Signature{Status: Signed}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		key, value := s.Text(), ""
+		if i := strings.Index(key, "="); i >= 0 {
+			key, value = key[:i], key[i+1:]
+		}
+		switch {
+		case key == "Signature" && value == "adhoc":
+			sig.Status = AdHocSigned
+		case key == "Authority" && sig.Identity == "":
+			sig.Identity = value
+		case key == "TeamIdentifier" && value != "not set":
+			sig.TeamID = value
+		case key == "CodeDirectory v":
+			sig.Flags = parseCodeDirectoryFlags(value)
+		}
+	}
+	return sig
+}
+
+// parseCodeDirectoryFlags returns the names of the flags in the CodeDirectory
+// line of codesign output, given what follows "CodeDirectory v=".
+func parseCodeDirectoryFlags(s string) []string {
+	i := strings.Index(s, " flags=")
+	if i < 0 {
+		return nil
+	}
+	s = s[i+len(" flags="):]
+	start, end := strings.Index(s, "("), strings.Index(s, ")")
+	if start < 0 || end < start || strings.Contains(s[:start], " ") {
+		return nil
+	}
+	if names := s[start+1 : end]; names != "" && names != "none" {
+		return strings.Split(names, ",")
+	}
+	return nil
+}
+
+// An Entitlement is a key of the entitlements of a binary with its value
+// formatted for display.
+type Entitlement struct {
+	Key, Value string
+}
+
+// Entitlements returns the entitlements bin is signed with, in the order they
+// are listed, by running codesign.
+func Entitlements(ctx context.Context, bin string) ([]Entitlement, error) {
+	out, stderr, err := codesign(ctx, bin, "-d", "--entitlements", ":-")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if strings.Contains(stderr, "not signed at all") {
+			return nil, nil
+		}
+		return nil, toolErrorStderr("codesign", bin, stderr, err)
+	}
+	ents, err := parseEntitlements(out)
+	if err != nil {
+		return nil, &ToolError{Tool: "codesign", Bins: []string{bin}, Err: fmt.Errorf("cannot parse entitlements: %v", err)}
+	}
+	return ents, nil
+}
+
+// parseEntitlements parses the property list of entitlements codesign prints:
+//
+//	<plist version="1.0"><dict>
+//		<key>com.apple.security.app-sandbox</key><true/>
+//		<key>com.apple.security.application-groups</key>
+//		<array><string>TEAMID.group</string></array>
+//	</dict></plist>
+//
+// Arrays are formatted as their elements in brackets and nested dictionaries
+// as "{...}".
+func parseEntitlements(data []byte) ([]Entitlement, error) {
+	var ents []Entitlement
+	var key string
+	top := false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return ents, nil
+		} else if err != nil {
+			return nil, err
+		}
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var value string
+		switch t.Name.Local {
+		case "plist":
+			continue
+		case "dict":
+			if !top {
+				top = true
+				continue
+			}
+			value = "{...}"
+			err = dec.Skip()
+		case "key":
+			err = dec.DecodeElement(&key, &t)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case "true", "false":
+			value = t.Name.Local
+			err = dec.Skip()
+		case "array":
+			var a struct {
+				Values []string `xml:",any"`
+			}
+			err = dec.DecodeElement(&a, &t)
+			value = "[" + strings.Join(a.Values, ", ") + "]"
+		default:
+			err = dec.DecodeElement(&value, &t)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ents = append(ents, Entitlement{Key: key, Value: value})
+	}
+}
+
+// Sign replaces the code signature of path, a binary or a bundle, by one made
+// with identity, keeping its identifier, entitlements and flags.  The content
+// of bundles, nested code included, must be signed first.
+func Sign(ctx context.Context, path, identity string) error {
+	if _, stderr, err := codesign(ctx, path, "--force", "--sign", identity, "--preserve-metadata=identifier,entitlements,flags"); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &ToolError{Tool: "codesign", Bins: []string{path}, Err: err, Stderr: stderr}
+	}
+	return nil
+}
+
+// AdHocSign replaces the code signature of bin by an ad-hoc one, which arm64
+// binaries need to run after being modified.
+func AdHocSign(ctx context.Context, bin string) error {
+	if _, stderr, err := codesign(ctx, bin, "--force", "--sign", "-"); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return toolErrorStderr("codesign", bin, stderr, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package totool
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// A Collision is a library embedded in an app bundle that another copy
+// installed on the system is loaded along with.  Both copies define the same
+// Objective-C classes and C++ symbols, which the runtime resolves to either
+// copy depending on the caller.
+type Collision struct {
+	// Embedded is the copy inside the bundle.
+	Embedded string
+
+	// Other is the copy outside of it.
+	Other string
+
+	// Origin is where Other comes from, OriginSystem or OriginPackage.
+	Origin Origin
+}
+
+func (c Collision) String() string {
+	return fmt.Sprintf("embedded %s is loaded along with %s copy %s", c.Embedded, c.Origin, c.Other)
+}
+
+// Collisions returns the libraries embedded in the bundles of the roots of g
+// that also come from the system or a package manager, matching frameworks by
+// name and other libraries by base name.
+func (g *Graph) Collisions() []Collision {
+	var embedded []Node
+	others := make(map[string][]Node)
+	for _, node := range g.Nodes() {
+		switch node.Origin {
+		case OriginEmbedded:
+			if !g.IsRoot(node.Path) {
+				embedded = append(embedded, node)
+			}
+		case OriginSystem, OriginPackage:
+			name := libraryName(node.Path)
+			others[name] = append(others[name], node)
+		}
+	}
+	var cs []Collision
+	for _, e := range embedded {
+		for _, o := range others[libraryName(e.Path)] {
+			cs = append(cs, Collision{Embedded: e.Path, Other: o.Path, Origin: o.Origin})
+		}
+	}
+	return cs
+}
+
+// libraryName returns the name of the framework bin is part of, such as
+// Sparkle.framework, or its base name if it is not part of a framework.
+func libraryName(bin string) string {
+	parts := strings.Split(bin, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if strings.HasSuffix(parts[i], ".framework") {
+			return parts[i]
+		}
+	}
+	return filepath.Base(bin)
+}
@@ -0,0 +1,18 @@
+package totool
+
+// debugLog receives the diagnostics of backends, nil to drop them.
+var debugLog func(format string, args ...interface{})
+
+// SetDebugLog makes the package report to logf the diagnostics of its
+// backends: the commands it runs, the cached otool output it reuses and the
+// inspector it picks for each binary.  A nil logf disables them.
+func SetDebugLog(logf func(format string, args ...interface{})) {
+	debugLog = logf
+}
+
+// debugf reports a diagnostic to debugLog, if set.
+func debugf(format string, args ...interface{}) {
+	if debugLog != nil {
+		debugLog(format, args...)
+	}
+}
@@ -0,0 +1,37 @@
+package totool
+
+import "regexp"
+
+// A Deprecation describes libraries Apple deprecated or removed.
+type Deprecation struct {
+	// Re matches the paths of the libraries.
+	Re *regexp.Regexp
+
+	// Note tells what the libraries are and what happened to them.
+	Note string
+}
+
+// Deprecations lists well-known deprecated or removed libraries.
+var Deprecations = []Deprecation{
+	{regexp.MustCompile(`/lib(ssl|crypto)\.0\.9\.[0-9]+\.dylib$`), "OpenSSL 0.9, deprecated since OS X 10.7"},
+	{regexp.MustCompile(`/lib(ssl|crypto)\.1\.0(\.[0-9]+)?\.dylib$`), "OpenSSL 1.0, no longer maintained since 2019"},
+	{regexp.MustCompile(`^/usr/lib/lib(ssl|crypto)\.dylib$`), "system OpenSSL, deprecated since OS X 10.7 and not loadable since macOS 11"},
+	{regexp.MustCompile(`/libstdc\+\+(\.[0-9]+)*\.dylib$`), "libstdc++, removed from the SDK in macOS 10.14 in favor of libc++"},
+	{regexp.MustCompile(`/Carbon\.framework/`), "Carbon, deprecated since OS X 10.8"},
+	{regexp.MustCompile(`/QTKit\.framework/`), "QTKit, deprecated since OS X 10.9 and removed in macOS 10.15"},
+	{regexp.MustCompile(`/QuickTime\.framework/`), "QuickTime, deprecated since OS X 10.9 and removed in macOS 10.15"},
+	{regexp.MustCompile(`/AddressBook\.framework/`), "AddressBook, deprecated since OS X 10.11 in favor of Contacts"},
+	{regexp.MustCompile(`/(OpenGL|OpenCL|GLUT)\.framework/`), "OpenGL and OpenCL, deprecated since macOS 10.14 in favor of Metal"},
+	{regexp.MustCompile(`^/System/Library/Frameworks/Python\.framework/`), "system Python 2, removed in macOS 12.3"},
+	{regexp.MustCompile(`/JavaVM\.framework/`), "Apple Java, deprecated since OS X 10.6"},
+}
+
+// Deprecated returns what is known about bin if it is a deprecated library.
+func Deprecated(bin string) (string, bool) {
+	for _, d := range Deprecations {
+		if d.Re.MatchString(bin) {
+			return d.Note, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"debug/macho"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// HeuristicInfo is the additional data of the dependencies a DlopenInspector
+// guesses.
+const HeuristicInfo = "(runtime, heuristic)"
+
+// A DlopenInspector wraps an inspector, adding to the dependencies it finds
+// the libraries whose paths appear among the string literals of binaries, as
+// passed to dlopen() or NSBundle by plugin architectures that otool -L does not
+// see through.  Only the paths its resolver maps to existing files or to
+// system libraries are kept, as guesses that cannot be checked.
+type DlopenInspector struct {
+	in Inspector
+	r  Resolver
+}
+
+// NewDlopenInspector returns an inspector adding heuristic dependencies to
+// those in finds, r resolving the paths it guesses.  Nil in and r stand for an
+// OtoolInspector and a DyldResolver without search paths.
+func NewDlopenInspector(in Inspector, r Resolver) *DlopenInspector {
+	if in == nil {
+		in = OtoolInspector{}
+	}
+	if r == nil {
+		r = &DyldResolver{}
+	}
+	return &DlopenInspector{in: in, r: r}
+}
+
+// Inspect implements Inspector.
+func (di *DlopenInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	deps, err := di.in.Inspect(ctx, bin)
+	if err != nil {
+		return nil, err
+	}
+	return di.addGuesses(bin, deps), nil
+}
+
+func (di *DlopenInspector) inspectBatch(ctx context.Context, bins []string) []inspection {
+	results := inspectBatch(ctx, di.in, bins)
+	for i, bin := range bins {
+		if results[i].err == nil {
+			results[i].deps = di.addGuesses(bin, results[i].deps)
+		}
+	}
+	return results
+}
+
+// addGuesses returns deps, the dependencies of bin, followed by those guessed
+// from its string literals.
+func (di *DlopenInspector) addGuesses(bin string, deps []Dependency) []Dependency {
+	seen := map[string]bool{bin: true}
+	for _, d := range deps {
+		seen[d.Bin] = true
+	}
+	var guesses []Dependency
+	for _, name := range DlopenCandidates(bin) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		p := di.r.Resolve(name, bin, bin)
+		if !filepath.IsAbs(p) {
+			continue
+		}
+		if _, err := os.Stat(p); err != nil && !IsSystemBin(p) {
+			continue
+		}
+		guesses = append(guesses, Dependency{Bin: intern(name), Info: HeuristicInfo})
+	}
+	if len(guesses) == 0 {
+		return deps
+	}
+	// The slice of the wrapped inspector must not be modified.
+	return append(append([]Dependency(nil), deps...), guesses...)
+}
+
+// libraryPathRe matches string literals that look like paths to libraries.
+//
+//	@rpath/libplugin.dylib
+//	/Library/Frameworks/Foo.framework/Foo
+var libraryPathRe = regexp.MustCompile(`^[^\s%*]*(\.dylib|\.so|\.framework/(Versions/[^/\s]+/)?[^/\s]+|\.bundle/Contents/MacOS/[^/\s]+)$`)
+
+// DlopenCandidates returns the string literals of bin that look like paths to
+// libraries, in the order they appear.
+func DlopenCandidates(bin string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	forEachArch(bin, func(f *macho.File) {
+		s := f.Section("__cstring")
+		if s == nil || s.Seg != "__TEXT" {
+			return
+		}
+		data, err := s.Data()
+		if err != nil {
+			return
+		}
+		for _, lit := range bytes.Split(data, []byte{0}) {
+			name := string(lit)
+			if seen[name] || !libraryPathRe.MatchString(name) || !isFrameworkBinary(name) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// isFrameworkBinary reports whether name, if it is a path into a framework,
+// names the binary of the framework rather than a resource of it.
+func isFrameworkBinary(name string) bool {
+	i := strings.LastIndex(name, ".framework/")
+	if i < 0 || strings.HasSuffix(name, ".dylib") || strings.HasSuffix(name, ".so") {
+		return true
+	}
+	framework := filepath.Base(name[:i])
+	return filepath.Base(name) == framework
+}
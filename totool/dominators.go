@@ -0,0 +1,184 @@
+package totool
+
+// Dominators returns the immediate dominator of each binary reachable from the
+// roots of g, that is the closest binary all dependency chains leading to it go
+// through.  Roots are dominated by the empty string, which stands for a virtual
+// entry depending on all roots.
+//
+// This is the iterative algorithm from "A Simple, Fast Dominance Algorithm" by
+// Cooper, Harvey and Kennedy.
+func (g *Graph) Dominators() map[string]string {
+	// The virtual entry gets the first ID past those of binaries.
+	entry := int32(len(g.paths))
+	roots := g.rootIDs()
+	succs := func(bin int32) []int32 {
+		if bin == entry {
+			return roots
+		}
+		return g.deps[bin]
+	}
+
+	post := make([]int32, entry+1)
+	for i := range post {
+		post[i] = -2
+	}
+	var order []int32
+	var visit func(bin int32)
+	visit = func(bin int32) {
+		post[bin] = -1
+		for _, to := range succs(bin) {
+			if post[to] == -2 {
+				visit(to)
+			}
+		}
+		post[bin] = int32(len(order))
+		order = append(order, bin)
+	}
+	visit(entry)
+
+	preds := make([][]int32, entry+1)
+	for _, from := range order {
+		for _, to := range succs(from) {
+			preds[to] = append(preds[to], from)
+		}
+	}
+
+	idom := make([]int32, entry+1)
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[entry] = entry
+	intersect := func(a, b int32) int32 {
+		for a != b {
+			for post[a] < post[b] {
+				a = idom[a]
+			}
+			for post[b] < post[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+
+	for changed := true; changed; {
+		changed = false
+		// Visit in reverse postorder, skipping the entry which comes last.
+		for i := len(order) - 2; i >= 0; i-- {
+			bin := order[i]
+			var newIdom int32 = -1
+			for _, p := range preds[bin] {
+				if idom[p] < 0 {
+					continue
+				}
+				if newIdom < 0 {
+					newIdom = p
+				} else {
+					newIdom = intersect(p, newIdom)
+				}
+			}
+			if idom[bin] != newIdom {
+				idom[bin] = newIdom
+				changed = true
+			}
+		}
+	}
+
+	byPath := make(map[string]string)
+	for bin, d := range idom[:entry] {
+		switch {
+		case d == entry:
+			byPath[g.paths[bin]] = ""
+		case d >= 0:
+			byPath[g.paths[bin]] = g.paths[d]
+		}
+	}
+	return byPath
+}
+
+// Dominated maps each direct dependency of the roots of g to the binaries
+// reachable only through it, in walk order.
+func (g *Graph) Dominated() map[string][]string {
+	idom := g.Dominators()
+	dominated := make(map[string][]string)
+	for _, bin := range g.Bins() {
+		if _, ok := idom[bin]; !ok || g.IsRoot(bin) {
+			continue
+		}
+		// Climb the dominator tree up to the binary just below a root.
+		d := bin
+		for !g.IsRoot(idom[d]) && idom[d] != "" {
+			d = idom[d]
+		}
+		if d != bin && g.IsRoot(idom[d]) {
+			dominated[d] = append(dominated[d], bin)
+		}
+	}
+	return dominated
+}
+
+// DirectDeps returns the direct dependencies of the roots of g.
+func (g *Graph) DirectDeps() []string {
+	seen := make(map[string]bool)
+	var deps []string
+	for _, root := range g.roots {
+		for _, to := range g.Dependencies(root) {
+			if !seen[to] && !g.IsRoot(to) {
+				seen[to] = true
+				deps = append(deps, to)
+			}
+		}
+	}
+	return deps
+}
+
+// Contributions maps each direct dependency of the roots of g to how many
+// binaries would leave the graph if the roots stopped depending on it,
+// including itself.  Dependencies also reachable through other binaries
+// contribute nothing.
+func (g *Graph) Contributions() map[string]int {
+	all := g.reachable(-1)
+	n := 0
+	for _, ok := range all {
+		if ok {
+			n++
+		}
+	}
+	contribs := make(map[string]int)
+	for _, d := range g.DirectDeps() {
+		left := 0
+		for _, ok := range g.reachable(g.id(d)) {
+			if ok {
+				left++
+			}
+		}
+		contribs[d] = n - left
+	}
+	return contribs
+}
+
+// reachable marks the binaries reachable from the roots of g with the edges
+// from the roots to cut removed, cut being -1 to keep all edges.
+func (g *Graph) reachable(cut int32) []bool {
+	seen := make([]bool, len(g.paths))
+	roots := g.rootIDs()
+	isRoot := make(map[int32]bool, len(roots))
+	for _, r := range roots {
+		isRoot[r] = true
+	}
+	stack := append([]int32(nil), roots...)
+	for _, r := range roots {
+		seen[r] = true
+	}
+	for len(stack) > 0 {
+		from := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, to := range g.deps[from] {
+			if seen[to] || (to == cut && isRoot[from]) {
+				continue
+			}
+			seen[to] = true
+			stack = append(stack, to)
+		}
+	}
+	return seen
+}
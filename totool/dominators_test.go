@@ -0,0 +1,48 @@
+package totool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContributions(t *testing.T) {
+	tests := []struct {
+		name  string
+		roots []string
+		edges [][2]string
+		want  map[string]int
+	}{
+		{
+			name:  "chain",
+			roots: []string{"R"},
+			edges: [][2]string{{"R", "A"}, {"A", "B"}},
+			want:  map[string]int{"A": 2},
+		},
+		{
+			name:  "diamond",
+			roots: []string{"R"},
+			edges: [][2]string{{"R", "A"}, {"A", "D"}, {"R", "D"}, {"D", "E"}},
+			want:  map[string]int{"A": 1, "D": 0},
+		},
+		{
+			name:  "shared by two roots",
+			roots: []string{"R1", "R2"},
+			edges: [][2]string{{"R1", "D"}, {"R2", "D"}, {"D", "E"}, {"R2", "F"}},
+			want:  map[string]int{"D": 2, "F": 1},
+		},
+	}
+	for _, tt := range tests {
+		g := NewGraph()
+		for _, r := range tt.roots {
+			g.AddRoot(r)
+			g.AddNode(Node{Path: r})
+		}
+		for _, e := range tt.edges {
+			g.AddNode(Node{Path: e[1], Parent: e[0]})
+			g.AddDep(e[0], e[1], "")
+		}
+		if got := g.Contributions(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Contributions() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
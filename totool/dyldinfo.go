@@ -0,0 +1,81 @@
+package totool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DyldInfoInspector finds dependencies by running "dyld_info -dependents",
+// available since macOS 13.  Only the first architecture of fat binaries is
+// considered.
+type DyldInfoInspector struct{}
+
+// Inspect implements Inspector.
+func (DyldInfoInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	r := dyldInfo(ctx, bin)
+	return r.deps, r.err
+}
+
+func (DyldInfoInspector) inspectBatch(ctx context.Context, bins []string) []inspection {
+	results := make([]inspection, len(bins))
+	for i, bin := range bins {
+		results[i] = dyldInfo(ctx, bin)
+	}
+	return results
+}
+
+// dyldInfo runs "dyld_info -dependents" on bin.
+func dyldInfo(ctx context.Context, bin string) inspection {
+	cmd := exec.CommandContext(ctx, tool("dyld_info"), "-dependents", bin)
+	out, warnings, err := outputWarnings(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return inspection{err: ctx.Err()}
+		}
+		return inspection{err: toolError("dyld_info", []string{bin}, err)}
+	}
+	return inspection{deps: parseDyldInfo(out), warnings: warnings}
+}
+
+// parseDyldInfo parses the output of "dyld_info -dependents":
+//
+//	/bin/ls [arm64e]:
+//	    -dependents:
+//	        attributes     load path
+//	                       /usr/lib/libutil.dylib
+//	        weak-link      /usr/lib/libncurses.5.4.dylib
+//
+// Attributes become the additional data of dependencies.
+func parseDyldInfo(out []byte) []Dependency {
+	defer addTime(&times.parse, time.Now())
+	countBackend("dyld_info")
+
+	var deps []Dependency
+	headers := 0
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if len(line) > 0 && line[0] != ' ' && line[0] != '\t' {
+			// Stop at the header of the second architecture.
+			if headers++; headers > 1 {
+				break
+			}
+			continue
+		}
+		line = strings.TrimSpace(line)
+		i := strings.IndexAny(line, "/@")
+		if i < 0 {
+			continue
+		}
+		var info string
+		if attrs := strings.Join(strings.Fields(line[:i]), ", "); attrs != "" {
+			info = "(" + attrs + ")"
+		}
+		deps = append(deps, Dependency{Bin: intern(line[i:]), Info: intern(info)})
+	}
+	return deps
+}
@@ -0,0 +1,391 @@
+package totool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"debug/elf"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ELFInspector finds the dependencies of the ELF binaries of Linux and other
+// Unix systems by parsing their DT_NEEDED entries.
+type ELFInspector struct{}
+
+// Inspect implements Inspector.
+func (ELFInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer addTime(&times.parse, time.Now())
+	countBackend("elf")
+
+	info, err := metadataOf(bin).elf()
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]Dependency, 0, len(info.needed))
+	for _, name := range info.needed {
+		deps = append(deps, Dependency{Bin: intern(name)})
+	}
+	return deps, nil
+}
+
+// elfInfo summarizes the dynamic section of an ELF binary.
+type elfInfo struct {
+	class   elf.Class
+	machine elf.Machine
+
+	// needed lists the DT_NEEDED entries.
+	needed []string
+
+	// rpath and runpath list the directories of the DT_RPATH and DT_RUNPATH
+	// entries, before expanding $ORIGIN and the like.
+	rpath, runpath []string
+}
+
+// readELF parses the ELF binary bin.
+func readELF(bin string) (*elfInfo, error) {
+	f, err := elf.Open(bin)
+	if err != nil {
+		if !isELF(bin) {
+			return nil, wrongFormat(bin, FormatELF)
+		}
+		return nil, err
+	}
+	defer f.Close()
+	info := &elfInfo{class: f.Class, machine: f.Machine}
+	// Binaries without dynamic section, static ones, have no entries.
+	info.needed, _ = f.DynString(elf.DT_NEEDED)
+	rpath, _ := f.DynString(elf.DT_RPATH)
+	runpath, _ := f.DynString(elf.DT_RUNPATH)
+	for _, p := range rpath {
+		info.rpath = append(info.rpath, filepath.SplitList(p)...)
+	}
+	for _, p := range runpath {
+		info.runpath = append(info.runpath, filepath.SplitList(p)...)
+	}
+	return info, nil
+}
+
+// isELF reports whether path is an ELF binary.
+func isELF(path string) bool {
+	return isFormat(path, FormatELF)
+}
+
+// ELFResolver resolves the dependencies of ELF binaries like the GNU dynamic
+// loader: paths with a slash are taken as is after expanding $ORIGIN, other
+// names are looked for in the DT_RPATH directories of the loader and the
+// executable when the loader has no DT_RUNPATH, then in LibraryPath, the
+// DT_RUNPATH directories of the loader, the directories listed by
+// /etc/ld.so.conf and the default directories.  Libraries of another class
+// or machine than the loader are skipped.
+type ELFResolver struct {
+	// LibraryPath lists directories searched like LD_LIBRARY_PATH.
+	LibraryPath []string
+
+	// ConfFile is the ld.so.conf file listing more directories to search,
+	// /etc/ld.so.conf below Root if empty.
+	ConfFile string
+
+	// Root, if not empty, is the directory standing for the root of the
+	// system the binaries run on, such as a copy of the file system of a
+	// Linux machine examined from macOS.  Absolute paths found in binaries
+	// and ld.so.conf files are looked up below it, symbolic links
+	// included, as are the default directories.  LibraryPath and ConfFile
+	// are taken as is.
+	Root string
+
+	once     sync.Once
+	confDirs []string
+}
+
+// NewELFResolver returns a resolver using the search paths of the environment.
+func NewELFResolver() *ELFResolver {
+	return &ELFResolver{LibraryPath: splitPathList(os.Getenv("LD_LIBRARY_PATH"))}
+}
+
+// Resolve implements Resolver.
+func (r *ELFResolver) Resolve(path, loader, executable string) string {
+	if strings.Contains(path, "/") {
+		return r.inRoot(r.searchPath(path, loader))
+	}
+	from, err := metadataOf(loader).elf()
+	if err != nil {
+		return path
+	}
+
+	var dirs []string
+	if len(from.runpath) == 0 {
+		for _, bin := range []string{loader, executable} {
+			if info, err := metadataOf(bin).elf(); err == nil {
+				for _, dir := range info.rpath {
+					dirs = append(dirs, r.searchPath(dir, bin))
+				}
+			}
+		}
+	}
+	dirs = append(dirs, r.LibraryPath...)
+	for _, dir := range from.runpath {
+		dirs = append(dirs, r.searchPath(dir, loader))
+	}
+	r.once.Do(r.readConf)
+	dirs = append(dirs, r.confDirs...)
+	if from.class == elf.ELFCLASS64 {
+		dirs = append(dirs, r.rooted("/lib64"), r.rooted("/usr/lib64"))
+	}
+	dirs = append(dirs, r.rooted("/lib"), r.rooted("/usr/lib"))
+
+	for _, dir := range dirs {
+		p := r.inRoot(filepath.Join(dir, path))
+		if info, err := metadataOf(p).elf(); err == nil && info.class == from.class && info.machine == from.machine {
+			return p
+		}
+	}
+	return path
+}
+
+// expandELFPath expands the $ORIGIN and $LIB tokens of path, found in a
+// binary loaded from loader.
+func expandELFPath(path, loader string) string {
+	if !strings.Contains(path, "$") {
+		return path
+	}
+	lib := "lib"
+	if info, err := metadataOf(loader).elf(); err == nil && info.class == elf.ELFCLASS64 {
+		lib = "lib64"
+	}
+	return strings.NewReplacer(
+		"${ORIGIN}", filepath.Dir(loader), "$ORIGIN", filepath.Dir(loader),
+		"${LIB}", lib, "$LIB", lib,
+	).Replace(path)
+}
+
+// searchPath returns path, a path or directory found in bin, with $ORIGIN and
+// the like expanded.  Paths not relative to the directory of bin are looked up
+// below Root.
+func (r *ELFResolver) searchPath(path, bin string) string {
+	if strings.Contains(path, "ORIGIN") {
+		return expandELFPath(path, bin)
+	}
+	return r.rooted(expandELFPath(path, bin))
+}
+
+// rooted returns the path standing for the absolute path p below Root.
+func (r *ELFResolver) rooted(p string) string {
+	if r.Root == "" || !filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(r.Root, p)
+}
+
+// inRoot returns InRoot(Root, p) if Root is set and p as is otherwise.  Paths
+// going through too many links are left unresolved below Root, where the
+// loader would fail to open them as well.
+func (r *ELFResolver) inRoot(p string) string {
+	if r.Root == "" {
+		return p
+	}
+	if q, ok := InRoot(r.Root, p); ok {
+		return q
+	}
+	return p
+}
+
+// InRoot returns p, a path below root, with its symbolic links resolved
+// within root: links to absolute paths are taken relative to root and ".."
+// does not climb above it.  Paths outside of root are returned as is.  It
+// returns false if p goes through too many links, a loop for instance, as what
+// is left of it could then lead anywhere.
+func InRoot(root, p string) (string, bool) {
+	root = filepath.Clean(root)
+	if !strings.HasPrefix(p, root+"/") {
+		return p, true
+	}
+	rest := strings.Split(strings.TrimPrefix(p, root+"/"), "/")
+	cur := root
+	// Bound the links followed in case of loops.
+	for links := 0; len(rest) > 0; {
+		part := rest[0]
+		rest = rest[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if cur != root {
+				cur = filepath.Dir(cur)
+			}
+			continue
+		}
+		next := filepath.Join(cur, part)
+		target, err := os.Readlink(next)
+		if err != nil {
+			cur = next
+			continue
+		}
+		if links++; links > 40 {
+			return "", false
+		}
+		if filepath.IsAbs(target) {
+			cur = root
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return cur, true
+}
+
+// readConf reads the directories ConfFile lists, following its include
+// directives.
+func (r *ELFResolver) readConf() {
+	conf := r.ConfFile
+	if conf == "" {
+		conf = r.rooted("/etc/ld.so.conf")
+	}
+	r.confDirs = r.readLdSoConf(conf, make(map[string]bool))
+}
+
+// readLdSoConf returns the directories listed in the ld.so.conf file path and
+// the files it includes, skipping those in seen.
+func (r *ELFResolver) readLdSoConf(path string, seen map[string]bool) []string {
+	if seen[path] {
+		return nil
+	}
+	seen[path] = true
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "include":
+			for _, pattern := range fields[1:] {
+				if filepath.IsAbs(pattern) {
+					pattern = r.rooted(pattern)
+				} else {
+					pattern = filepath.Join(filepath.Dir(path), pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, m := range matches {
+					dirs = append(dirs, r.readLdSoConf(m, seen)...)
+				}
+			}
+		case fields[0] == "hwcap":
+		default:
+			for _, dir := range fields {
+				dirs = append(dirs, r.rooted(dir))
+			}
+		}
+	}
+	return dirs
+}
+
+// AutoInspector inspects each binary with the inspector of its format, told
+// by DetectFormat: an ELFInspector for ELF binaries, a PEInspector for PE ones
+// and the inspector it wraps for mach-o ones.  Files of other formats fail
+// with a FormatError.  Binaries that do not exist are left to the wrapped
+// inspector, which may know them anyway.
+type AutoInspector struct {
+	in Inspector
+}
+
+// NewAutoInspector returns an inspector of ELF and PE binaries leaving mach-o
+// binaries to in.
+func NewAutoInspector(in Inspector) *AutoInspector {
+	return &AutoInspector{in: in}
+}
+
+// backend returns the inspector of bin, nil if it is a mach-o binary or does
+// not exist.
+func (a *AutoInspector) backend(bin string) (Inspector, error) {
+	format, err := DetectFormat(bin)
+	switch {
+	case errors.Is(err, ErrMissingFile):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case format == FormatELF:
+		debugf("%s: %v, inspected natively", bin, format)
+		return ELFInspector{}, nil
+	case format == FormatPE:
+		debugf("%s: %v, inspected natively", bin, format)
+		return PEInspector{}, nil
+	}
+	return nil, nil
+}
+
+// Inspect implements Inspector.
+func (a *AutoInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	in, err := a.backend(bin)
+	if err != nil {
+		return nil, err
+	}
+	if in == nil {
+		in = a.in
+	}
+	return in.Inspect(ctx, bin)
+}
+
+func (a *AutoInspector) inspectBatch(ctx context.Context, bins []string) []inspection {
+	results := make([]inspection, len(bins))
+	var others []string
+	var at []int
+	for i, bin := range bins {
+		in, err := a.backend(bin)
+		switch {
+		case err != nil:
+			results[i].err = err
+		case in != nil:
+			results[i].deps, results[i].err = in.Inspect(ctx, bin)
+		default:
+			others = append(others, bin)
+			at = append(at, i)
+		}
+	}
+	if len(others) > 0 {
+		for j, r := range inspectBatch(ctx, a.in, others) {
+			results[at[j]] = r
+		}
+	}
+	return results
+}
+
+// AutoResolver resolves the dependencies of ELF and PE binaries with ELF and
+// PE and those of others with the resolver it wraps.
+type AutoResolver struct {
+	r Resolver
+
+	ELF *ELFResolver
+	PE  *PEResolver
+}
+
+// NewAutoResolver returns a resolver of the dependencies of ELF binaries,
+// using the search paths of the environment, of PE binaries, searching the
+// directories of executables only, and of those r resolves.
+func NewAutoResolver(r Resolver) *AutoResolver {
+	return &AutoResolver{r: r, ELF: NewELFResolver(), PE: &PEResolver{}}
+}
+
+// Resolve implements Resolver.
+func (a *AutoResolver) Resolve(path, loader, executable string) string {
+	if _, err := metadataOf(loader).elf(); err == nil {
+		return a.ELF.Resolve(path, loader, executable)
+	}
+	if _, err := metadataOf(loader).pe(); err == nil {
+		return a.PE.Resolve(path, loader, executable)
+	}
+	return a.r.Resolve(path, loader, executable)
+}
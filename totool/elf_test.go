@@ -0,0 +1,61 @@
+package totool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInRoot(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"loop1": "loop2",
+		"loop2": "loop1",
+		"abs":   "/etc",
+		"up":    "../../../../etc",
+		"lib":   "usr/lib",
+	}
+	// A chain of links as long as allowed, then one more.
+	for i := 1; i < 41; i++ {
+		links[fmt.Sprintf("l%d", i)] = fmt.Sprintf("l%d", i+1)
+	}
+	links["l41"] = "../../../../../../../etc"
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		p    string
+		want string
+		ok   bool
+	}{
+		{"usr/lib/libc.so", "usr/lib/libc.so", true},
+		{"lib/libc.so", "usr/lib/libc.so", true},
+		{"a/../../../../etc/passwd", "etc/passwd", true},
+		{"abs/passwd", "etc/passwd", true},
+		{"up/passwd", "etc/passwd", true},
+		{"l2/passwd", "etc/passwd", true},
+		{"l1/passwd", "", false},
+		{"loop1/x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := InRoot(root, root+"/"+tt.p)
+		want := tt.want
+		if ok {
+			want = filepath.Join(root, tt.want)
+		}
+		if got != want || ok != tt.ok {
+			t.Errorf("InRoot(%q) = %q, %v, want %q, %v", tt.p, got, ok, want, tt.ok)
+		}
+	}
+
+	if got, ok := InRoot(root, "/etc/passwd"); got != "/etc/passwd" || !ok {
+		t.Errorf("InRoot(/etc/passwd) = %q, %v, want it as is", got, ok)
+	}
+}
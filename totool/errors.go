@@ -0,0 +1,113 @@
+package totool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// ErrMissingFile is returned for binaries that do not exist.
+	ErrMissingFile = errors.New("no such file")
+
+	// ErrNotMachO is returned for files that are not mach-o binaries.
+	ErrNotMachO = errors.New("not a mach-o binary")
+
+	// ErrToolFailed is returned when a tool run to inspect binaries fails.
+	// The actual error is a ToolError.
+	ErrToolFailed = errors.New("tool failed")
+)
+
+// A ToolError reports the failure of a tool run to inspect binaries.
+type ToolError struct {
+	// Tool is the name of the tool, "otool" for instance.
+	Tool string
+
+	// Bins lists the binaries the tool was run on.
+	Bins []string
+
+	// Stderr is what the tool printed on its standard error.
+	Stderr string
+
+	// Err is why running the tool failed.
+	Err error
+}
+
+func (e *ToolError) Error() string {
+	msg := fmt.Sprintf("%s error when processing %s", e.Tool, strings.Join(e.Bins, ", "))
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrToolFailed) hold for ToolErrors.
+func (e *ToolError) Is(target error) bool {
+	return target == ErrToolFailed
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// A BinError reports that a binary met during a walk could not be inspected.
+type BinError struct {
+	Bin string
+	Err error
+}
+
+func (e *BinError) Error() string {
+	// Tool errors name the binaries already.
+	if msg := e.Err.Error(); strings.Contains(msg, e.Bin) {
+		return msg
+	}
+	return e.Bin + ": " + e.Err.Error()
+}
+
+func (e *BinError) Unwrap() error {
+	return e.Err
+}
+
+// checkBin returns ErrMissingFile or ErrNotMachO if bin does not exist or is
+// not a mach-o binary and nil otherwise.
+func checkBin(bin string) error {
+	if _, err := os.Stat(bin); os.IsNotExist(err) {
+		return ErrMissingFile
+	}
+	if format, err := DetectFormat(bin); err != nil {
+		return err
+	} else if format != FormatMachO && format != FormatFat {
+		return wrongFormat(bin, FormatMachO)
+	}
+	return nil
+}
+
+// toolError returns the error reporting that tool failed with err on bins.
+// Failures on a single binary that is missing or not a mach-o binary report
+// that instead.
+func toolError(tool string, bins []string, err error) error {
+	if len(bins) == 1 {
+		if berr := checkBin(bins[0]); berr != nil {
+			return berr
+		}
+	}
+	e := &ToolError{Tool: tool, Bins: bins, Err: err}
+	if ee, ok := err.(*exec.ExitError); ok {
+		e.Stderr = string(ee.Stderr)
+	}
+	return e
+}
+
+// toolErrorStderr is like toolError for a tool run on bin alone whose standard
+// error was captured into stderr rather than by the error.
+func toolErrorStderr(tool, bin, stderr string, err error) error {
+	terr := toolError(tool, []string{bin}, err)
+	if e, ok := terr.(*ToolError); ok {
+		e.Stderr = stderr
+	}
+	return terr
+}
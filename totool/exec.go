@@ -0,0 +1,85 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// procSlots bounds how many subprocesses run at once, nil meaning no bound.
+var procSlots chan struct{}
+
+// SetMaxProcs bounds to n how many subprocesses run at once, 0 meaning no bound.
+func SetMaxProcs(n int) {
+	if n > 0 {
+		procSlots = make(chan struct{}, n)
+	} else {
+		procSlots = nil
+	}
+}
+
+// output runs cmd once a subprocess slot is available and returns its
+// standard output like cmd.Output.  It gives up waiting for a slot when ctx is
+// done, cmd itself being expected to be bound to ctx by exec.CommandContext.
+func output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	out, _, err := outputWarnings(ctx, cmd)
+	return out, err
+}
+
+// outputWarnings is like output but also returns the lines cmd printed on its
+// standard error when it succeeds, warnings the backends attach to binaries.
+func outputWarnings(ctx context.Context, cmd *exec.Cmd) ([]byte, []string, error) {
+	debugf("running %s", strings.Join(cmd.Args, " "))
+	countCommand(cmd.Path)
+	var stderr *bytes.Buffer
+	if cmd.Stderr == nil {
+		stderr = new(bytes.Buffer)
+		cmd.Stderr = stderr
+	}
+	var out []byte
+	err := withSlot(ctx, func() (err error) {
+		out, err = cmd.Output()
+		return err
+	})
+	if stderr == nil {
+		return out, nil, err
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		ee.Stderr = stderr.Bytes()
+	}
+	if err != nil {
+		return out, nil, err
+	}
+	var warnings []string
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			warnings = append(warnings, line)
+		}
+	}
+	return out, warnings, nil
+}
+
+// run is like output for commands whose output goes elsewhere, like cmd.Run.
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	debugf("running %s", strings.Join(cmd.Args, " "))
+	countCommand(cmd.Path)
+	return withSlot(ctx, cmd.Run)
+}
+
+// withSlot calls fn once a subprocess slot is available.
+func withSlot(ctx context.Context, fn func() error) error {
+	if procSlots != nil {
+		select {
+		case procSlots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-procSlots }()
+	}
+	defer addTime(&times.exec, time.Now())
+	return fn()
+}
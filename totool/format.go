@@ -0,0 +1,98 @@
+package totool
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PrinterOptions configures printers created with NewPrinter.  Printers ignore
+// the options that do not apply to them.
+type PrinterOptions struct {
+	// Verbose enables printing extra info about each binary.
+	Verbose bool
+
+	// FanIn enables printing the dependents of each binary.
+	FanIn bool
+
+	// Nodes and Edges select which parts of the graph are printed.
+	Nodes, Edges bool
+
+	// Merged is set when the walks of several roots go into a single graph.
+	Merged bool
+
+	// Hashes enables printing the SHA-256 digest of each binary.
+	Hashes bool
+
+	// Licenses enables printing the license of each binary.
+	Licenses bool
+
+	// Sizes enables printing the size of each binary where formats leave it
+	// out by default.
+	Sizes bool
+
+	// Color enables ANSI colors in output meant for terminals.
+	Color bool
+
+	// Label returns how to display path, nil meaning as is.  Structured
+	// formats keep paths whole, recording labels next to them.
+	Label func(path string) string
+
+	// Alias returns the name of the node standing for path in diagrams, if
+	// any, binaries with the same alias being merged into a single node.
+	Alias func(path string) (string, bool)
+
+	// Skipped lists the files scans of directories and bundles left out,
+	// which structured formats record.
+	Skipped []SkippedFile
+}
+
+// LabelOf returns how opts display path.
+func (opts PrinterOptions) LabelOf(path string) string {
+	if opts.Label == nil {
+		return path
+	}
+	return opts.Label(path)
+}
+
+// A PrinterFactory creates a printer configured by opts.
+type PrinterFactory func(opts PrinterOptions) Printer
+
+var formats = struct {
+	sync.Mutex
+	m map[string]PrinterFactory
+}{m: make(map[string]PrinterFactory)}
+
+// RegisterFormat makes the printers created by f available under name.  It
+// panics if name is already registered.
+func RegisterFormat(name string, f PrinterFactory) {
+	formats.Lock()
+	defer formats.Unlock()
+	if _, ok := formats.m[name]; ok {
+		panic(fmt.Sprintf("totool: format %q registered twice", name))
+	}
+	formats.m[name] = f
+}
+
+// NewPrinter returns a printer for the format registered under name.
+func NewPrinter(name string, opts PrinterOptions) (Printer, error) {
+	formats.Lock()
+	f, ok := formats.m[name]
+	formats.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return f(opts), nil
+}
+
+// Formats returns the names of the registered formats in alphabetical order.
+func Formats() []string {
+	formats.Lock()
+	defer formats.Unlock()
+	names := make([]string, 0, len(formats.m))
+	for name := range formats.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
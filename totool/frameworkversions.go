@@ -0,0 +1,88 @@
+package totool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FrameworkVersions describes how a binary of a versioned framework bundle,
+// one with a Versions directory as on macOS, goes through Versions/Current.
+type FrameworkVersions struct {
+	// Framework is the path of the .framework directory.
+	Framework string
+
+	// Version is the version directory the binary was found in, "Current"
+	// when found through Versions/Current or the top-level symbolic link.
+	Version string
+
+	// Current is what Versions/Current links to, empty when it is missing
+	// or not a symbolic link.
+	Current string
+
+	// Problem tells what is wrong with Versions/Current, if anything.
+	Problem string
+}
+
+// Chain returns how the binary resolves through the framework, such as
+// "Versions/Current -> A".
+func (v FrameworkVersions) Chain() string {
+	if v.Current == "" {
+		return "Versions/Current unresolved"
+	}
+	return "Versions/Current -> " + v.Current
+}
+
+// FrameworkVersionsOf returns how bin, a binary of a versioned framework,
+// goes through Versions/Current.  It returns false for other binaries.
+// Versions/Current is expected to be a symbolic link to a version directory
+// holding the binary, which frameworks copied without their symbolic links
+// lack.
+func FrameworkVersionsOf(bin string) (FrameworkVersions, bool) {
+	i := strings.LastIndex(bin, ".framework/")
+	if i < 0 {
+		return FrameworkVersions{}, false
+	}
+	v := FrameworkVersions{Framework: bin[:i+len(".framework")], Version: "Current"}
+	rest := bin[i+len(".framework/"):]
+	versions := filepath.Join(v.Framework, "Versions")
+	if fi, err := os.Stat(versions); err != nil || !fi.IsDir() {
+		return FrameworkVersions{}, false
+	}
+	if parts := strings.Split(rest, "/"); len(parts) > 2 && parts[0] == "Versions" {
+		v.Version = parts[1]
+	}
+
+	current := filepath.Join(versions, "Current")
+	fi, err := os.Lstat(current)
+	switch {
+	case err != nil:
+		v.Problem = "Versions/Current is missing"
+		return v, true
+	case fi.Mode()&os.ModeSymlink == 0:
+		v.Problem = "Versions/Current is not a symbolic link, the framework was probably copied without its symbolic links"
+		return v, true
+	}
+	target, err := os.Readlink(current)
+	if err != nil {
+		v.Problem = fmt.Sprintf("cannot read Versions/Current: %v", err)
+		return v, true
+	}
+	v.Current = target
+	switch {
+	case strings.Contains(target, "/"):
+		v.Problem = fmt.Sprintf("Versions/Current points at %s rather than at a directory of Versions", target)
+	case !isDir(filepath.Join(versions, target)):
+		v.Problem = fmt.Sprintf("Versions/Current points at missing version %s", target)
+	case v.Version != "Current" && v.Version != target:
+		v.Problem = fmt.Sprintf("Versions/Current points at %s but version %s is loaded", target, v.Version)
+	}
+	return v, true
+}
+
+// isDir reports whether path is a directory.
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
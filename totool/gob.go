@@ -0,0 +1,36 @@
+package totool
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// graphData is how graphs are serialized.
+type graphData struct {
+	Roots []string
+	Nodes []Node
+	Edges []Edge
+}
+
+// GobEncode implements gob.GobEncoder.
+func (g *Graph) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(graphData{Roots: g.roots, Nodes: g.Nodes(), Edges: g.Edges()})
+	return buf.Bytes(), err
+}
+
+// GobDecode implements gob.GobDecoder.
+func (g *Graph) GobDecode(b []byte) error {
+	var data graphData
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return err
+	}
+	*g = *NewGraph(data.Roots...)
+	for _, n := range data.Nodes {
+		g.AddNode(n)
+	}
+	for _, e := range data.Edges {
+		g.AddNamedDep(e.From, e.To, e.Name, e.Info)
+	}
+	return nil
+}
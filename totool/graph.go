@@ -0,0 +1,839 @@
+package totool
+
+import (
+	"math/big"
+	"path/filepath"
+	"strings"
+)
+
+// Graph records the binaries and dependency edges discovered while walking one
+// or more root binaries.
+//
+// Binaries are identified internally by small integers indexing per-binary
+// slices, which keeps graphs of tens of thousands of binaries compact.
+type Graph struct {
+	roots []string
+
+	// paths maps binary IDs to paths and index maps paths back to IDs.
+	paths []string
+	index map[string]int32
+
+	// order lists visited binaries in the order they were visited.
+	order   []int32
+	visited []bool
+
+	// meta holds what the walk found out about each binary.
+	meta []nodeMeta
+
+	// deps lists the direct dependencies of each binary, infos the
+	// additional data (versions...) recorded about each of them and names
+	// the install names they are recorded under.
+	deps  [][]int32
+	infos [][]string
+	names [][]string
+
+	// rdeps lists the direct dependents of each binary.
+	rdeps [][]int32
+
+	// self holds the additional data binaries report about themselves.
+	self []string
+
+	// truncated marks binaries whose dependencies were not walked.
+	truncated  []bool
+	nTruncated int
+
+	// errs holds why binaries could not be inspected, in walks going on
+	// nonetheless, and failed lists them in the order they failed.
+	errs   map[int32]error
+	failed []int32
+
+	// warnings holds what the backends printed about binaries.
+	warnings map[int32][]string
+}
+
+// nodeMeta is what the walk found out about a binary.
+type nodeMeta struct {
+	depth  int32
+	parent int32
+	name   string
+	origin Origin
+	size   int64
+}
+
+// A Node is a binary of a graph.
+type Node struct {
+	// path to binary
+	Path string
+
+	// additional data the binary reports about itself
+	ID string
+
+	// length of the shortest dependency chain from a root
+	Depth int
+
+	// binary that first brought this one in during the walk
+	Parent string
+
+	// path to binary as recorded in Parent, before resolving @rpath and the
+	// like
+	Name string
+
+	// where the binary comes from
+	Origin Origin
+
+	// set when the walk stopped before expanding this binary
+	Truncated bool
+
+	// size of the binary file in bytes, 0 when unknown or not read yet, as
+	// walks leave it to BinSize
+	Size int64
+
+	// what the backend printed about the binary while inspecting it
+	Warnings []string
+}
+
+// An Edge is a direct dependency between two binaries of a graph.
+type Edge struct {
+	From, To string
+
+	// additional data (versions...) From records about To
+	Info string
+
+	// install name From records To under, empty if unknown
+	Name string
+}
+
+// NewGraph returns an empty graph of the dependencies of roots.
+func NewGraph(roots ...string) *Graph {
+	return &Graph{roots: roots, index: make(map[string]int32)}
+}
+
+// id returns the ID of bin, allocating one if needed.
+func (g *Graph) id(bin string) int32 {
+	if id, ok := g.index[bin]; ok {
+		return id
+	}
+	id := int32(len(g.paths))
+	g.index[bin] = id
+	g.paths = append(g.paths, bin)
+	g.visited = append(g.visited, false)
+	g.meta = append(g.meta, nodeMeta{parent: -1})
+	g.deps = append(g.deps, nil)
+	g.infos = append(g.infos, nil)
+	g.names = append(g.names, nil)
+	g.rdeps = append(g.rdeps, nil)
+	g.self = append(g.self, "")
+	g.truncated = append(g.truncated, false)
+	return id
+}
+
+// lookup returns the ID of bin if g knows about it.
+func (g *Graph) lookup(bin string) (int32, bool) {
+	id, ok := g.index[bin]
+	return id, ok
+}
+
+// AddRoot records that the dependencies of root are walked.
+func (g *Graph) AddRoot(root string) {
+	g.roots = append(g.roots, root)
+}
+
+// Roots returns the roots of g.
+func (g *Graph) Roots() []string {
+	return g.roots
+}
+
+// IsRoot reports whether bin is one of the roots of g.
+func (g *Graph) IsRoot(bin string) bool {
+	for _, root := range g.roots {
+		if bin == root {
+			return true
+		}
+	}
+	return false
+}
+
+// Name is a human-readable description of the roots of g.
+func (g *Graph) Name() string {
+	return strings.Join(g.roots, ", ")
+}
+
+// AddBin records that bin was visited.  It returns false if it already was.
+func (g *Graph) AddBin(bin string) bool {
+	return g.AddNode(Node{Path: bin})
+}
+
+// AddNode records that n was visited.  It returns false if it already was, in
+// which case what was recorded about it is left alone.
+func (g *Graph) AddNode(n Node) bool {
+	id := g.id(n.Path)
+	if g.visited[id] {
+		return false
+	}
+	g.visited[id] = true
+	g.order = append(g.order, id)
+	g.meta[id] = nodeMeta{depth: int32(n.Depth), parent: -1, name: n.Name, origin: n.Origin, size: n.Size}
+	if n.Parent != "" {
+		g.meta[id].parent = g.id(n.Parent)
+	}
+	g.SetID(n.Path, n.ID)
+	if n.Truncated {
+		g.Truncate(n.Path)
+	}
+	for _, w := range n.Warnings {
+		g.AddWarning(n.Path, w)
+	}
+	return true
+}
+
+// Node returns what g records about bin and whether bin was visited.
+func (g *Graph) Node(bin string) (Node, bool) {
+	id, ok := g.lookup(bin)
+	if !ok || !g.visited[id] {
+		return Node{}, false
+	}
+	return g.node(id), true
+}
+
+// node returns what g records about the binary id.
+func (g *Graph) node(id int32) Node {
+	m := g.meta[id]
+	n := Node{
+		Path:      g.paths[id],
+		ID:        g.self[id],
+		Depth:     int(m.depth),
+		Name:      m.name,
+		Origin:    m.origin,
+		Truncated: g.truncated[id],
+		Size:      g.meta[id].size,
+		Warnings:  g.warnings[id],
+	}
+	if m.parent >= 0 {
+		n.Parent = g.paths[m.parent]
+	}
+	return n
+}
+
+// Nodes returns the visited binaries in the order they were visited.
+func (g *Graph) Nodes() []Node {
+	nodes := make([]Node, len(g.order))
+	for i, id := range g.order {
+		nodes[i] = g.node(id)
+	}
+	return nodes
+}
+
+// Edges returns the direct dependencies of g, grouped by dependent in the order
+// dependents were visited.
+func (g *Graph) Edges() []Edge {
+	edges := make([]Edge, 0, g.EdgeCount())
+	for _, f := range g.order {
+		for i, t := range g.deps[f] {
+			edges = append(edges, Edge{From: g.paths[f], To: g.paths[t], Info: g.infos[f][i], Name: g.names[f][i]})
+		}
+	}
+	return edges
+}
+
+// Size returns how many binaries were visited.
+func (g *Graph) Size() int {
+	return len(g.order)
+}
+
+// Bins returns the visited binaries in the order they were visited.
+func (g *Graph) Bins() []string {
+	bins := make([]string, len(g.order))
+	for i, id := range g.order {
+		bins[i] = g.paths[id]
+	}
+	return bins
+}
+
+// AddDep records a direct dependency between from and to binaries.
+func (g *Graph) AddDep(from, to, info string) {
+	g.AddNamedDep(from, to, "", info)
+}
+
+// AddNamedDep is like AddDep for a dependency from records under the install
+// name name, empty if unknown.
+func (g *Graph) AddNamedDep(from, to, name, info string) {
+	f, t := g.id(from), g.id(to)
+	g.deps[f] = append(g.deps[f], t)
+	g.infos[f] = append(g.infos[f], info)
+	g.names[f] = append(g.names[f], name)
+	g.rdeps[t] = append(g.rdeps[t], f)
+}
+
+// Dependencies returns the direct dependencies of bin.
+func (g *Graph) Dependencies(bin string) []string {
+	if id, ok := g.lookup(bin); ok {
+		return g.pathsOf(g.deps[id])
+	}
+	return nil
+}
+
+// Dependents returns the binaries directly depending on bin.
+func (g *Graph) Dependents(bin string) []string {
+	if id, ok := g.lookup(bin); ok {
+		return g.pathsOf(g.rdeps[id])
+	}
+	return nil
+}
+
+// Paths returns all dependency chains from from to to that go through each
+// binary at most once.  Their number may grow exponentially with the size of
+// densely connected graphs.
+func (g *Graph) Paths(from, to string) [][]string {
+	f, ok := g.lookup(from)
+	if !ok {
+		return nil
+	}
+	t, ok := g.lookup(to)
+	if !ok {
+		return nil
+	}
+
+	var paths [][]string
+	onPath := make([]bool, len(g.paths))
+	var path []int32
+	var visit func(bin int32)
+	visit = func(bin int32) {
+		path = append(path, bin)
+		if bin == t {
+			paths = append(paths, g.pathsOf(path))
+		} else {
+			onPath[bin] = true
+			for _, next := range g.deps[bin] {
+				if !onPath[next] {
+					visit(next)
+				}
+			}
+			onPath[bin] = false
+		}
+		path = path[:len(path)-1]
+	}
+	visit(f)
+	return paths
+}
+
+// Info returns the additional data from recorded about its dependency to.
+func (g *Graph) Info(from, to string) string {
+	f, ok := g.lookup(from)
+	if !ok {
+		return ""
+	}
+	t, ok := g.lookup(to)
+	if !ok {
+		return ""
+	}
+	for i, dep := range g.deps[f] {
+		if dep == t && g.infos[f][i] != "" {
+			return g.infos[f][i]
+		}
+	}
+	return ""
+}
+
+// InstallName returns the install name from records its dependency to under,
+// @rpath/libA.dylib for instance.  Graphs loaded from formats lacking it fall
+// back on the name recorded by the binary that first brought to in, if from.
+func (g *Graph) InstallName(from, to string) string {
+	f, ok := g.lookup(from)
+	if !ok {
+		return ""
+	}
+	t, ok := g.lookup(to)
+	if !ok {
+		return ""
+	}
+	for i, dep := range g.deps[f] {
+		if dep == t && g.names[f][i] != "" {
+			return g.names[f][i]
+		}
+	}
+	if g.meta[t].parent == f {
+		return g.meta[t].name
+	}
+	return ""
+}
+
+// SetID records the additional data bin reports about itself.
+func (g *Graph) SetID(bin, info string) {
+	if info != "" {
+		g.self[g.id(bin)] = info
+	}
+}
+
+// SelfInfo returns the additional data bin reports about itself.
+func (g *Graph) SelfInfo(bin string) string {
+	if id, ok := g.lookup(bin); ok {
+		return g.self[id]
+	}
+	return ""
+}
+
+// Truncate records that the dependencies of bin were not walked.
+func (g *Graph) Truncate(bin string) {
+	id := g.id(bin)
+	if !g.truncated[id] {
+		g.truncated[id] = true
+		g.nTruncated++
+	}
+}
+
+// untruncate records that the dependencies of bin are walked after all.
+func (g *Graph) untruncate(bin string) {
+	if id, ok := g.lookup(bin); ok && g.truncated[id] {
+		g.truncated[id] = false
+		g.nTruncated--
+	}
+}
+
+// Truncated returns how many binaries were not expanded.
+func (g *Graph) Truncated() int {
+	return g.nTruncated
+}
+
+// IsTruncated reports whether the dependencies of bin were not walked.
+func (g *Graph) IsTruncated(bin string) bool {
+	id, ok := g.lookup(bin)
+	return ok && g.truncated[id]
+}
+
+// SetError records that bin could not be inspected because of err.
+func (g *Graph) SetError(bin string, err error) {
+	id := g.id(bin)
+	if g.errs == nil {
+		g.errs = make(map[int32]error)
+	}
+	if _, ok := g.errs[id]; !ok {
+		g.failed = append(g.failed, id)
+	}
+	g.errs[id] = err
+}
+
+// Err returns why bin could not be inspected, nil if it could.
+func (g *Graph) Err(bin string) error {
+	if id, ok := g.lookup(bin); ok {
+		return g.errs[id]
+	}
+	return nil
+}
+
+// AddWarning records w, what the backend printed about bin while inspecting
+// it.
+func (g *Graph) AddWarning(bin, w string) {
+	id := g.id(bin)
+	if g.warnings == nil {
+		g.warnings = make(map[int32][]string)
+	}
+	g.warnings[id] = append(g.warnings[id], w)
+}
+
+// Warnings returns what the backend printed about bin while inspecting it.
+func (g *Graph) Warnings(bin string) []string {
+	if id, ok := g.lookup(bin); ok {
+		return g.warnings[id]
+	}
+	return nil
+}
+
+// Failed returns the binaries that could not be inspected, in the order they
+// failed.
+func (g *Graph) Failed() []string {
+	return g.pathsOf(g.failed)
+}
+
+// rootIDs returns the IDs of the roots of g.
+func (g *Graph) rootIDs() []int32 {
+	ids := make([]int32, len(g.roots))
+	for i, root := range g.roots {
+		ids[i] = g.id(root)
+	}
+	return ids
+}
+
+// pathsOf converts binary IDs to paths.
+func (g *Graph) pathsOf(ids []int32) []string {
+	paths := make([]string, len(ids))
+	for i, id := range ids {
+		paths[i] = g.paths[id]
+	}
+	return paths
+}
+
+// Cycles returns one dependency cycle per strongly connected component of g.
+// Each cycle starts and ends with the same binary.
+func (g *Graph) Cycles() [][]string {
+	var cycles [][]string
+	for _, scc := range g.sccs() {
+		if len(scc) > 1 {
+			cycles = append(cycles, g.pathsOf(g.cycleIn(scc)))
+		}
+	}
+	return cycles
+}
+
+// sccs computes the strongly connected components of g with Tarjan's algorithm.
+func (g *Graph) sccs() [][]int32 {
+	n := len(g.paths)
+	index := make([]int32, n)
+	low := make([]int32, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var next int32
+	var stack []int32
+	var sccs [][]int32
+
+	var connect func(bin int32)
+	connect = func(bin int32) {
+		index[bin] = next
+		low[bin] = next
+		next++
+		stack = append(stack, bin)
+		onStack[bin] = true
+
+		for _, to := range g.deps[bin] {
+			if index[to] < 0 {
+				connect(to)
+				if low[to] < low[bin] {
+					low[bin] = low[to]
+				}
+			} else if onStack[to] && index[to] < low[bin] {
+				low[bin] = index[to]
+			}
+		}
+
+		if low[bin] == index[bin] {
+			var scc []int32
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				scc = append(scc, top)
+				if top == bin {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+
+	for _, bin := range g.order {
+		if index[bin] < 0 {
+			connect(bin)
+		}
+	}
+	return sccs
+}
+
+// cycleIn returns the shortest cycle going through the first binary of scc,
+// which must be a strongly connected component of g.
+func (g *Graph) cycleIn(scc []int32) []int32 {
+	inScc := make(map[int32]bool)
+	for _, bin := range scc {
+		inScc[bin] = true
+	}
+
+	start := scc[0]
+	parent := make(map[int32]int32)
+	queue := []int32{start}
+	for len(queue) > 0 {
+		var from int32
+		from, queue = queue[0], queue[1:]
+		for _, to := range g.deps[from] {
+			if to == start {
+				cycle := []int32{start}
+				for bin := from; bin != start; bin = parent[bin] {
+					cycle = append(cycle, bin)
+				}
+				cycle = append(cycle, start)
+				// Reverse to get the cycle in dependency order.
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return cycle
+			}
+			if _, seen := parent[to]; !seen && inScc[to] {
+				parent[to] = from
+				queue = append(queue, to)
+			}
+		}
+	}
+	return nil
+}
+
+// TopoSort returns the binaries of g in topological order, dependencies before
+// their dependents.  Binaries belonging to the same cycle are grouped together.
+func (g *Graph) TopoSort() []string {
+	// Tarjan's algorithm emits components after all components they can reach.
+	var bins []string
+	for _, scc := range g.sccs() {
+		bins = append(bins, g.pathsOf(scc)...)
+	}
+	return bins
+}
+
+// Depths returns the length of the shortest dependency chain from a root to
+// each binary of g.
+func (g *Graph) Depths() map[string]int {
+	depths := make(map[string]int)
+	for id, d := range g.depthsByID() {
+		if d >= 0 {
+			depths[g.paths[id]] = int(d)
+		}
+	}
+	return depths
+}
+
+// depthsByID returns the length of the shortest dependency chain from a root
+// to each binary of g, -1 for unreachable binaries.
+func (g *Graph) depthsByID() []int32 {
+	depths := make([]int32, len(g.paths))
+	for i := range depths {
+		depths[i] = -1
+	}
+	queue := g.rootIDs()
+	for _, root := range queue {
+		depths[root] = 0
+	}
+	for len(queue) > 0 {
+		var from int32
+		from, queue = queue[0], queue[1:]
+		for _, to := range g.deps[from] {
+			if depths[to] < 0 {
+				depths[to] = depths[from] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+	return depths
+}
+
+// FanIn returns how many direct dependents each binary of g has.
+func (g *Graph) FanIn() map[string]int {
+	fanIn := make(map[string]int)
+	for _, tos := range g.deps {
+		for _, to := range tos {
+			fanIn[g.paths[to]]++
+		}
+	}
+	return fanIn
+}
+
+// EdgeCount returns the number of direct dependencies recorded in g.
+func (g *Graph) EdgeCount() int {
+	n := 0
+	for _, tos := range g.deps {
+		n += len(tos)
+	}
+	return n
+}
+
+// components returns the strongly connected components of g, leaves first, and
+// maps each binary to the index of its component, -1 for binaries not reached.
+func (g *Graph) components() ([][]int32, []int) {
+	sccs := g.sccs()
+	comp := make([]int, len(g.paths))
+	for i := range comp {
+		comp[i] = -1
+	}
+	for i, scc := range sccs {
+		for _, bin := range scc {
+			comp[bin] = i
+		}
+	}
+	return sccs, comp
+}
+
+// PathCounts returns how many distinct dependency chains lead from the roots of
+// g to each binary.  Chains going round a cycle more than once are not counted
+// so that counts are finite: all binaries of a cycle get the number of chains
+// entering it, which is then passed on along the dependencies leaving it.
+func (g *Graph) PathCounts() map[string]*big.Int {
+	sccs, comp := g.components()
+	totals := make([]*big.Int, len(sccs))
+	for i := range totals {
+		totals[i] = new(big.Int)
+	}
+	for _, root := range g.rootIDs() {
+		if c := comp[root]; c >= 0 {
+			totals[c].Add(totals[c], big.NewInt(1))
+		}
+	}
+	// Visit components roots first so that all chains to a component are
+	// counted before they are propagated further.
+	for i := len(sccs) - 1; i >= 0; i-- {
+		for _, from := range sccs[i] {
+			for _, to := range g.deps[from] {
+				if c := comp[to]; c >= 0 && c != i {
+					totals[c].Add(totals[c], totals[i])
+				}
+			}
+		}
+	}
+
+	byPath := make(map[string]*big.Int)
+	for id, c := range comp {
+		if c >= 0 {
+			byPath[g.paths[id]] = new(big.Int).Set(totals[c])
+		}
+	}
+	return byPath
+}
+
+// LongestChain returns the longest dependency chain starting at a root of g.
+// Dependencies between binaries of the same cycle are ignored so that the chain
+// is finite.
+func (g *Graph) LongestChain() []string {
+	sccs, comp := g.components()
+
+	length := make([]int, len(g.paths))
+	next := make([]int32, len(g.paths))
+	// Components come leaves first so dependencies are processed before dependents.
+	for _, scc := range sccs {
+		for _, bin := range scc {
+			length[bin] = 1
+			next[bin] = -1
+			for _, to := range g.deps[bin] {
+				if c := comp[to]; c >= 0 && c != comp[bin] && length[to]+1 > length[bin] {
+					length[bin] = length[to] + 1
+					next[bin] = to
+				}
+			}
+		}
+	}
+
+	var start int32 = -1
+	for _, root := range g.rootIDs() {
+		if start < 0 || length[root] > length[start] {
+			start = root
+		}
+	}
+
+	var chain []string
+	for bin := start; bin >= 0; bin = next[bin] {
+		chain = append(chain, g.paths[bin])
+	}
+	return chain
+}
+
+// Duplicates returns groups of distinct binaries of g sharing the same base
+// name, which usually means several copies of a library get loaded.
+func (g *Graph) Duplicates() [][]string {
+	byName := make(map[string][]string)
+	var names []string
+	for _, id := range g.order {
+		bin := g.paths[id]
+		name := filepath.Base(bin)
+		if len(byName[name]) == 0 {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], bin)
+	}
+
+	var dups [][]string
+	for _, name := range names {
+		if len(byName[name]) > 1 {
+			dups = append(dups, byName[name])
+		}
+	}
+	return dups
+}
+
+// Chain returns the shortest dependency chain from a root of g to bin, or nil
+// if bin is not reachable.
+func (g *Graph) Chain(bin string) []string {
+	target, ok := g.lookup(bin)
+	if !ok {
+		return nil
+	}
+
+	parent := make([]int32, len(g.paths))
+	for i := range parent {
+		parent[i] = -2
+	}
+	queue := g.rootIDs()
+	for _, root := range queue {
+		parent[root] = -1
+	}
+	for len(queue) > 0 && parent[target] == -2 {
+		var from int32
+		from, queue = queue[0], queue[1:]
+		for _, to := range g.deps[from] {
+			if parent[to] == -2 {
+				parent[to] = from
+				queue = append(queue, to)
+			}
+		}
+	}
+	if parent[target] == -2 {
+		return nil
+	}
+
+	var chain []string
+	for id := target; id >= 0; id = parent[id] {
+		chain = append([]string{g.paths[id]}, chain...)
+	}
+	return chain
+}
+
+// ChainString formats the shortest chain from a root of g to from, followed
+// by its direct dependency to.
+func (g *Graph) ChainString(from, to string) string {
+	return strings.Join(append(g.Chain(from), to), " -> ")
+}
+
+// IsLeaf reports whether bin depends on system binaries only.
+func (g *Graph) IsLeaf(bin string) bool {
+	if g.IsTruncated(bin) {
+		return false
+	}
+	for _, to := range g.Dependencies(bin) {
+		if !IsSystemBin(to) {
+			return false
+		}
+	}
+	return true
+}
+
+// Origin returns the origin of bin, considering it embedded if it ships along
+// with any root of g.
+func (g *Graph) Origin(bin string) Origin {
+	o := OriginOther
+	for _, root := range g.roots {
+		if o = Classify(root, bin); o == OriginEmbedded {
+			break
+		}
+	}
+	return o
+}
+
+// FanOut returns how many distinct direct dependencies bin has.
+func (g *Graph) FanOut(bin string) int {
+	id, ok := g.lookup(bin)
+	if !ok {
+		return 0
+	}
+	seen := make(map[int32]bool)
+	for _, to := range g.deps[id] {
+		seen[to] = true
+	}
+	return len(seen)
+}
+
+// FirstInfos maps each binary of g to the additional data its first dependent
+// recorded about it.
+func (g *Graph) FirstInfos() map[string]string {
+	infos := make(map[string]string)
+	for _, from := range g.order {
+		for i, to := range g.deps[from] {
+			if _, ok := infos[g.paths[to]]; !ok {
+				infos[g.paths[to]] = g.infos[from][i]
+			}
+		}
+	}
+	return infos
+}
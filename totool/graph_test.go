@@ -0,0 +1,58 @@
+package totool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathCounts(t *testing.T) {
+	tests := []struct {
+		name  string
+		roots []string
+		edges [][2]string
+		want  map[string]int64
+	}{
+		{
+			name:  "diamond",
+			roots: []string{"R"},
+			edges: [][2]string{{"R", "A"}, {"R", "B"}, {"A", "C"}, {"B", "C"}, {"C", "D"}},
+			want:  map[string]int64{"R": 1, "A": 1, "B": 1, "C": 2, "D": 2},
+		},
+		{
+			name:  "cycle",
+			roots: []string{"R"},
+			edges: [][2]string{{"R", "A"}, {"A", "B"}, {"B", "A"}, {"B", "C"}},
+			want:  map[string]int64{"R": 1, "A": 1, "B": 1, "C": 1},
+		},
+		{
+			name:  "cycle entered twice",
+			roots: []string{"R"},
+			edges: [][2]string{{"R", "A"}, {"R", "B"}, {"A", "B"}, {"B", "A"}, {"A", "C"}},
+			want:  map[string]int64{"R": 1, "A": 2, "B": 2, "C": 2},
+		},
+		{
+			name:  "two roots",
+			roots: []string{"R1", "R2"},
+			edges: [][2]string{{"R1", "D"}, {"R2", "D"}, {"R2", "R1"}},
+			want:  map[string]int64{"R1": 2, "R2": 1, "D": 3},
+		},
+	}
+	for _, tt := range tests {
+		g := NewGraph()
+		for _, r := range tt.roots {
+			g.AddRoot(r)
+			g.AddNode(Node{Path: r})
+		}
+		for _, e := range tt.edges {
+			g.AddNode(Node{Path: e[1], Parent: e[0]})
+			g.AddDep(e[0], e[1], "")
+		}
+		got := make(map[string]int64)
+		for bin, c := range g.PathCounts() {
+			got[bin] = c.Int64()
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: PathCounts() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
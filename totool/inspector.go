@@ -0,0 +1,82 @@
+package totool
+
+import (
+	"context"
+	"fmt"
+)
+
+// An Inspector finds the direct dependencies of binaries.
+type Inspector interface {
+	// Inspect returns the direct dependencies of bin as recorded in bin, that
+	// is before resolving their paths.  Dependencies on bin itself carry the
+	// additional data bin reports about itself.  The returned slice must not
+	// be modified.
+	Inspect(ctx context.Context, bin string) ([]Dependency, error)
+}
+
+// batchInspector is implemented by inspectors finding the dependencies of
+// several binaries at once more efficiently than one by one.
+type batchInspector interface {
+	// inspectBatch finds the direct dependencies of bins, which must be
+	// distinct.
+	inspectBatch(ctx context.Context, bins []string) []inspection
+}
+
+// inspectBatch finds with in the direct dependencies of bins.
+func inspectBatch(ctx context.Context, in Inspector, bins []string) []inspection {
+	if b, ok := in.(batchInspector); ok {
+		return b.inspectBatch(ctx, bins)
+	}
+	results := make([]inspection, len(bins))
+	for i, bin := range bins {
+		results[i].deps, results[i].err = in.Inspect(ctx, bin)
+	}
+	return results
+}
+
+// Backends lists the names NewInspector accepts.
+var Backends = []string{"otool", "dyld_info", "macho", "elf", "pe"}
+
+// NewInspector returns the inspector named backend: "otool" runs "otool -L",
+// "dyld_info" runs "dyld_info -dependents", "macho" parses binaries itself,
+// "elf" parses ELF binaries and "pe" Windows binaries.
+func NewInspector(backend string) (Inspector, error) {
+	switch backend {
+	case "otool":
+		return OtoolInspector{}, nil
+	case "dyld_info":
+		return DyldInfoInspector{}, nil
+	case "macho":
+		return MachOInspector{}, nil
+	case "elf":
+		return ELFInspector{}, nil
+	case "pe":
+		return PEInspector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+}
+
+// Inspect returns the direct dependencies of bin found by in, resolved by r as
+// if bin was the executable, and the additional data bin reports about itself.
+// Nil in and r stand for an OtoolInspector and a DyldResolver without search
+// paths.
+func Inspect(bin string, in Inspector, r Resolver) ([]Dependency, string, error) {
+	return InspectContext(context.Background(), bin, in, r)
+}
+
+// InspectContext is like Inspect but gives up when ctx is done.
+func InspectContext(ctx context.Context, bin string, in Inspector, r Resolver) ([]Dependency, string, error) {
+	if in == nil {
+		in = OtoolInspector{}
+	}
+	if r == nil {
+		r = &DyldResolver{}
+	}
+	raw, err := in.Inspect(ctx, bin)
+	if err != nil {
+		return nil, "", err
+	}
+	deps, id := resolveDeps(r, bin, bin, raw)
+	return deps, id, nil
+}
@@ -0,0 +1,48 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+// ChangeInstallName makes bin refer to the dependency it records as old by
+// new instead.  It runs install_name_tool, which invalidates the code
+// signature of bin.
+func ChangeInstallName(ctx context.Context, bin, old, new string) error {
+	return installNameTool(ctx, bin, "-change", old, new)
+}
+
+// SetInstallID sets the install name bin, a library, records for itself.
+func SetInstallID(ctx context.Context, bin, id string) error {
+	return installNameTool(ctx, bin, "-id", id)
+}
+
+// AddRpath adds path to the run path search paths of bin unless it is
+// already one of them.
+func AddRpath(ctx context.Context, bin, path string) error {
+	if HasRpath(bin, path) {
+		return nil
+	}
+	return installNameTool(ctx, bin, "-add_rpath", path)
+}
+
+// HasRpath reports whether path is among the run path search paths of bin.
+func HasRpath(bin, path string) bool {
+	info, err := metadataOf(bin).machO()
+	return err == nil && contains(info.rpaths, path)
+}
+
+// installNameTool runs install_name_tool with args on bin.
+func installNameTool(ctx context.Context, bin string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tool("install_name_tool"), append(args, bin)...)
+	cmd.Stderr = &stderr
+	if _, err := output(ctx, cmd); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return toolErrorStderr("install_name_tool", bin, stderr.String(), err)
+	}
+	return nil
+}
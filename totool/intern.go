@@ -0,0 +1,21 @@
+package totool
+
+import "sync"
+
+// interned holds a single copy of each string passed to intern.
+var interned = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// intern returns a canonical copy of s so that paths and version strings
+// reported by many binaries share their storage.
+func intern(s string) string {
+	interned.Lock()
+	defer interned.Unlock()
+	if c, ok := interned.m[s]; ok {
+		return c
+	}
+	interned.m[s] = s
+	return s
+}
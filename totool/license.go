@@ -0,0 +1,140 @@
+package totool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// License returns the SPDX license expression of bin if it can tell, or ""
+// otherwise.  It tries in turn the license declared by the Homebrew formula
+// that installed bin, the license files of its keg and license texts embedded
+// in bin itself.  The latter two are guesses as texts seldom say everything an
+// SPDX identifier does.
+func License(bin string) string {
+	m := metadataOf(bin)
+	m.licenseOnce.Do(func() {
+		m.license = findLicense(bin)
+	})
+	return m.license
+}
+
+func findLicense(bin string) string {
+	if keg := Keg(bin); keg != "" {
+		if id := formulaLicense(keg); id != "" {
+			return id
+		}
+		if id := kegLicense(keg); id != "" {
+			return id
+		}
+	}
+	data, err := ioutil.ReadFile(bin)
+	if err != nil {
+		return ""
+	}
+	return matchLicense(data)
+}
+
+// formulaDeclRe matches license declarations in Homebrew formulae.
+//
+//	license any_of: ["MIT", "Apache-2.0"]
+var formulaDeclRe = regexp.MustCompile(`(?m)^\s*license\s+(.+)$`)
+
+// quotedRe matches the quoted strings of Ruby source.
+var quotedRe = regexp.MustCompile(`"([^"]+)"`)
+
+// formulaLicense returns the license declared by the formula Homebrew saves in
+// the .brew directory of keg.
+func formulaLicense(keg string) string {
+	name := filepath.Base(filepath.Dir(keg))
+	src, err := ioutil.ReadFile(filepath.Join(keg, ".brew", name+".rb"))
+	if err != nil {
+		return ""
+	}
+	sm := formulaDeclRe.FindSubmatch(src)
+	if sm == nil {
+		return ""
+	}
+	decl := string(sm[1])
+	var ids []string
+	for _, q := range quotedRe.FindAllStringSubmatch(decl, -1) {
+		ids = append(ids, q[1])
+	}
+	switch {
+	case strings.HasPrefix(decl, "any_of:"):
+		return strings.Join(ids, " OR ")
+	case strings.HasPrefix(decl, "all_of:"):
+		return strings.Join(ids, " AND ")
+	case len(ids) == 2 && strings.Contains(decl, "with:"):
+		return ids[0] + " WITH " + ids[1]
+	case len(ids) == 1:
+		return ids[0]
+	case strings.HasPrefix(decl, ":public_domain"):
+		return "LicenseRef-public-domain"
+	}
+	return ""
+}
+
+// kegLicense returns the license of the first license file at the root of keg
+// matchLicense recognizes.
+func kegLicense(keg string) string {
+	for _, pattern := range []string{"LICENSE*", "LICENCE*", "COPYING*", "COPYRIGHT*"} {
+		files, _ := filepath.Glob(filepath.Join(keg, pattern))
+		for _, f := range files {
+			data, err := ioutil.ReadFile(f)
+			if err != nil {
+				continue
+			}
+			if id := matchLicense(data); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// licenseTexts lists phrases identifying license texts, lower case with
+// single spaces, most specific first.  GPL texts recommend licensing under
+// later versions as well, which most projects do.
+var licenseTexts = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0-or-later", []string{"gnu affero general public license version 3"}},
+	{"LGPL-3.0-or-later", []string{"gnu lesser general public license version 3"}},
+	{"LGPL-2.1-or-later", []string{"gnu lesser general public license version 2.1"}},
+	{"LGPL-2.0-or-later", []string{"gnu library general public license version 2"}},
+	{"GPL-3.0-or-later", []string{"gnu general public license version 3"}},
+	{"GPL-2.0-or-later", []string{"gnu general public license version 2"}},
+	{"Apache-2.0", []string{"apache license version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license version 2.0"}},
+	{"OpenSSL", []string{"openssl license", "original ssleay license"}},
+	{"BSL-1.0", []string{"boost software license - version 1.0"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"MIT", []string{"permission is hereby granted, free of charge, to any person obtaining a copy"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{"Zlib", []string{"this software is provided 'as-is', without any express or implied warranty", "altered source versions must be plainly marked as such"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+// spaceRe matches runs of white space.
+var spaceRe = regexp.MustCompile(`\s+`)
+
+// matchLicense returns the identifier of the first license in licenseTexts
+// whose phrases all appear in text, or "".
+func matchLicense(text []byte) string {
+	text = spaceRe.ReplaceAll(bytes.ToLower(text), []byte(" "))
+	for _, l := range licenseTexts {
+		found := true
+		for _, p := range l.phrases {
+			found = found && bytes.Contains(text, []byte(p))
+		}
+		if found {
+			return l.id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package totool
+
+import "strings"
+
+// LoadInfo is what the additional data of a dependency tells about the way its
+// dependent loads it.
+type LoadInfo struct {
+	// Compat and Current are the compatibility and current versions of the
+	// dependency the dependent was linked against, if recorded.
+	Compat, Current string
+
+	// Weak is set for dependencies whose absence the dynamic loader tolerates
+	// and Upward for the upward dependencies breaking cycles.
+	Weak, Upward bool
+
+	// Attrs lists the other attributes, reexport, lazy or delay-load for
+	// instance.
+	Attrs []string
+}
+
+// ParseInfo parses info, the additional data of a dependency as otool or
+// dyld_info report it or as inspectors make it up.
+//
+//	(compatibility version 1.0.0, current version 228.0.0, upward)
+//	(weak-link)
+func ParseInfo(info string) LoadInfo {
+	var li LoadInfo
+	info = strings.TrimSuffix(strings.TrimPrefix(info, "("), ")")
+	if info == "" {
+		return li
+	}
+	for _, attr := range strings.Split(info, ", ") {
+		switch {
+		case strings.HasPrefix(attr, "compatibility version "):
+			li.Compat = strings.TrimPrefix(attr, "compatibility version ")
+		case strings.HasPrefix(attr, "current version "):
+			li.Current = strings.TrimPrefix(attr, "current version ")
+		case attr == "weak" || attr == "weak-link":
+			li.Weak = true
+		case attr == "upward" || attr == "upward-link":
+			li.Upward = true
+		default:
+			li.Attrs = append(li.Attrs, attr)
+		}
+	}
+	return li
+}
+
+// Fields returns the parts of li worth displaying.
+//
+//	compat 1.0.0, current 228.0.0, upward
+func (li LoadInfo) Fields() []string {
+	var fields []string
+	if li.Compat != "" {
+		fields = append(fields, "compat "+li.Compat)
+	}
+	if li.Current != "" {
+		fields = append(fields, "current "+li.Current)
+	}
+	if li.Weak {
+		fields = append(fields, "weak")
+	}
+	if li.Upward {
+		fields = append(fields, "upward")
+	}
+	return append(fields, li.Attrs...)
+}
@@ -0,0 +1,91 @@
+package totool
+
+import (
+	"context"
+	"debug/macho"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MachOInspector finds dependencies by parsing the load commands of binaries
+// itself, which works where no developer tools are installed.  Only the first
+// architecture of fat binaries is considered.
+type MachOInspector struct{}
+
+// Load commands referencing dylibs that debug/macho does not name.
+const (
+	loadCmdIDDylib       macho.LoadCmd = 0xd
+	loadCmdLoadWeakDylib macho.LoadCmd = 0x80000018
+	loadCmdReexportDylib macho.LoadCmd = 0x8000001f
+	loadCmdLazyLoadDylib macho.LoadCmd = 0x20
+	loadCmdUpwardDylib   macho.LoadCmd = 0x80000023
+)
+
+// Inspect implements Inspector.
+func (MachOInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer addTime(&times.parse, time.Now())
+	countBackend("macho")
+
+	var f *macho.File
+	if ff, err := macho.OpenFat(bin); err == nil {
+		defer ff.Close()
+		f = ff.Arches[0].File
+	} else {
+		if f, err = macho.Open(bin); err != nil {
+			if berr := checkBin(bin); berr != nil {
+				return nil, berr
+			}
+			return nil, err
+		}
+		defer f.Close()
+	}
+
+	var deps []Dependency
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 24 {
+			continue
+		}
+		var attr string
+		switch macho.LoadCmd(f.ByteOrder.Uint32(raw)) {
+		case macho.LoadCmdDylib, loadCmdIDDylib:
+		case loadCmdLoadWeakDylib:
+			attr = "weak"
+		case loadCmdReexportDylib:
+			attr = "reexport"
+		case loadCmdLazyLoadDylib:
+			attr = "lazy"
+		case loadCmdUpwardDylib:
+			attr = "upward"
+		default:
+			continue
+		}
+
+		// struct dylib_command: cmd, cmdsize, name offset, timestamp,
+		// current version, compatibility version, name.
+		off := f.ByteOrder.Uint32(raw[8:])
+		if int(off) >= len(raw) {
+			return nil, fmt.Errorf("%s: invalid dylib load command", bin)
+		}
+		name := string(raw[off:])
+		if i := strings.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		info := fmt.Sprintf("compatibility version %s, current version %s",
+			dylibVersion(f.ByteOrder.Uint32(raw[20:])), dylibVersion(f.ByteOrder.Uint32(raw[16:])))
+		if attr != "" {
+			info += ", " + attr
+		}
+		deps = append(deps, Dependency{Bin: intern(name), Info: intern("(" + info + ")")})
+	}
+	return deps, nil
+}
+
+// dylibVersion formats a version packed as xxxx.yy.zz like otool does.
+func dylibVersion(v uint32) string {
+	return fmt.Sprintf("%d.%d.%d", v>>16, (v>>8)&0xff, v&0xff)
+}
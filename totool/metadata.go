@@ -0,0 +1,296 @@
+package totool
+
+import (
+	"crypto/sha256"
+	"debug/macho"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// binMeta gives access to data about a binary that is expensive to get and
+// that the walk itself does not need.  Each piece of data is fetched the first
+// time it is asked for only, so that it costs nothing unless the active printer
+// or check uses it.
+type binMeta struct {
+	bin string
+
+	statOnce sync.Once
+	stat     os.FileInfo
+	statErr  error
+
+	machoOnce sync.Once
+	macho     *machoInfo
+	machoErr  error
+
+	elfOnce sync.Once
+	elfInfo *elfInfo
+	elfErr  error
+
+	peOnce sync.Once
+	peInfo *peInfo
+	peErr  error
+
+	hashOnce sync.Once
+	hash     string
+	hashErr  error
+
+	licenseOnce sync.Once
+	license     string
+
+	exportsOnce sync.Once
+	exports     map[string]bool
+	exportsErr  error
+}
+
+// machoInfo summarizes the load commands of a mach-o binary.
+type machoInfo struct {
+	// archs lists the architectures of the binary, several for fat binaries.
+	archs []machoArch
+
+	// rpaths lists the run path search paths of the binary, from all
+	// architectures.
+	rpaths []string
+
+	// executable is set for main executables as opposed to libraries.
+	executable bool
+
+	// currentVersion is the current version of the library as recorded in
+	// its LC_ID_DYLIB load command, empty for other binaries.
+	currentVersion string
+}
+
+// machoArch summarizes a single architecture slice of a binary.
+type machoArch struct {
+	cpu  macho.Cpu
+	name string
+
+	// minOS is the minimum OS version the slice runs on, empty if unknown.
+	minOS string
+}
+
+// Load commands recording the minimum OS version, not decoded by debug/macho.
+const (
+	loadCmdVersionMinMacOSX macho.LoadCmd = 0x24
+	loadCmdBuildVersion     macho.LoadCmd = 0x32
+)
+
+var metas = struct {
+	sync.Mutex
+	m map[string]*binMeta
+}{m: make(map[string]*binMeta)}
+
+// metadataOf returns the metadata of bin.
+func metadataOf(bin string) *binMeta {
+	metas.Lock()
+	defer metas.Unlock()
+	m, ok := metas.m[bin]
+	if !ok {
+		m = &binMeta{bin: bin}
+		metas.m[bin] = m
+	}
+	return m
+}
+
+// fileInfo returns the file system information about the binary.  Failures
+// are not memoized, missing binaries being looked for again each time as they
+// may have been created since.
+func (m *binMeta) fileInfo() (os.FileInfo, error) {
+	m.statOnce.Do(func() {
+		m.stat, m.statErr = os.Stat(m.bin)
+	})
+	if m.statErr != nil {
+		return os.Stat(m.bin)
+	}
+	return m.stat, nil
+}
+
+// machO returns the summary of the load commands of the binary.
+func (m *binMeta) machO() (*machoInfo, error) {
+	m.machoOnce.Do(func() {
+		m.macho, m.machoErr = readMachO(m.bin)
+	})
+	return m.macho, m.machoErr
+}
+
+// elf returns the summary of the dynamic section of the binary.
+func (m *binMeta) elf() (*elfInfo, error) {
+	m.elfOnce.Do(func() {
+		m.elfInfo, m.elfErr = readELF(m.bin)
+	})
+	return m.elfInfo, m.elfErr
+}
+
+// pe returns the summary of the imports of the binary.
+func (m *binMeta) pe() (*peInfo, error) {
+	m.peOnce.Do(func() {
+		m.peInfo, m.peErr = readPE(m.bin)
+	})
+	return m.peInfo, m.peErr
+}
+
+// SHA256 returns the hex-encoded SHA-256 digest of the contents of bin.
+func SHA256(bin string) (string, error) {
+	m := metadataOf(bin)
+	m.hashOnce.Do(func() {
+		m.hash, m.hashErr = hashFile(bin)
+	})
+	return m.hash, m.hashErr
+}
+
+// hashFile returns the hex-encoded SHA-256 digest of the contents of path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// IsExecutable reports whether bin is a main executable, which
+// @executable_path refers to the directory of, rather than a library.
+func IsExecutable(bin string) bool {
+	info, err := metadataOf(bin).machO()
+	return err == nil && info.executable
+}
+
+// CurrentVersion returns the current version of bin, a library, as otool
+// prints it, or an empty string for other binaries.
+func CurrentVersion(bin string) (string, error) {
+	info, err := metadataOf(bin).machO()
+	if err != nil {
+		return "", err
+	}
+	return info.currentVersion, nil
+}
+
+// Archs returns the names of the architectures of bin as printed by lipo,
+// several for fat binaries.
+func Archs(bin string) ([]string, error) {
+	info, err := metadataOf(bin).machO()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(info.archs))
+	for i, a := range info.archs {
+		names[i] = a.name
+	}
+	return names, nil
+}
+
+// MinOS returns the minimum OS version each architecture of bin runs on, by
+// architecture name.  Architectures not recording it are left out.
+func MinOS(bin string) (map[string]string, error) {
+	info, err := metadataOf(bin).machO()
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	for _, a := range info.archs {
+		if a.minOS != "" {
+			versions[a.name] = a.minOS
+		}
+	}
+	return versions, nil
+}
+
+// readMachO parses the thin or fat mach-o binary bin.
+func readMachO(bin string) (*machoInfo, error) {
+	if ff, err := macho.OpenFat(bin); err == nil {
+		defer ff.Close()
+		info := &machoInfo{}
+		for _, a := range ff.Arches {
+			info.add(a.File)
+		}
+		return info, nil
+	}
+
+	f, err := macho.Open(bin)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info := &machoInfo{}
+	info.add(f)
+	return info, nil
+}
+
+// add adds the architecture f of a binary to info.
+func (info *machoInfo) add(f *macho.File) {
+	info.archs = append(info.archs, newMachoArch(f))
+	info.executable = info.executable || f.Type == macho.TypeExec
+	for _, l := range f.Loads {
+		if r, ok := l.(*macho.Rpath); ok && !contains(info.rpaths, r.Path) {
+			info.rpaths = append(info.rpaths, r.Path)
+		}
+		raw := l.Raw()
+		if info.currentVersion == "" && len(raw) >= 24 && macho.LoadCmd(f.ByteOrder.Uint32(raw)) == loadCmdIDDylib {
+			info.currentVersion = dylibVersion(f.ByteOrder.Uint32(raw[16:]))
+		}
+	}
+}
+
+// contains reports whether s is one of ss.
+func contains(ss []string, s string) bool {
+	for _, t := range ss {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+func newMachoArch(f *macho.File) machoArch {
+	a := machoArch{cpu: f.Cpu, name: cpuName(f.Cpu)}
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 16 {
+			continue
+		}
+		// LC_BUILD_VERSION stores the platform before the version.
+		switch macho.LoadCmd(f.ByteOrder.Uint32(raw)) {
+		case loadCmdVersionMinMacOSX:
+			a.minOS = formatOSVersion(f.ByteOrder.Uint32(raw[8:]))
+		case loadCmdBuildVersion:
+			a.minOS = formatOSVersion(f.ByteOrder.Uint32(raw[12:]))
+		}
+	}
+	return a
+}
+
+// formatOSVersion formats v, encoded as xxxx.yy.zz in nibbles, as a dotted
+// version without trailing zero patch.
+func formatOSVersion(v uint32) string {
+	s := fmt.Sprintf("%d.%d", v>>16, (v>>8)&0xff)
+	if patch := v & 0xff; patch != 0 {
+		s += fmt.Sprintf(".%d", patch)
+	}
+	return s
+}
+
+// cpuName returns the name otool and lipo use for cpu.
+func cpuName(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.Cpu386:
+		return "i386"
+	case macho.CpuAmd64:
+		return "x86_64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuPpc:
+		return "ppc"
+	case macho.CpuPpc64:
+		return "ppc64"
+	default:
+		return cpu.String()
+	}
+}
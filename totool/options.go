@@ -0,0 +1,63 @@
+package totool
+
+import "context"
+
+// An Option sets a field of Options.
+type Option func(*Options)
+
+// NewOptions returns the options set by opts, on top of defaults.
+func NewOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WalkWith is like WalkContext with the options set by opts.
+func WalkWith(ctx context.Context, root string, pt Printer, opts ...Option) (*Graph, error) {
+	return WalkContext(ctx, root, pt, NewOptions(opts...))
+}
+
+// WithMaxNodes bounds how many binaries are expanded.
+func WithMaxNodes(n int) Option {
+	return func(o *Options) { o.MaxNodes = n }
+}
+
+// WithMaxDepth bounds the depth of expanded binaries.
+func WithMaxDepth(n int) Option {
+	return func(o *Options) { o.MaxDepth = n }
+}
+
+// WithFilter leaves out of the graph the dependencies for which keep returns
+// false.  Filters set by several options must all keep a dependency.
+func WithFilter(keep func(d *Dependency) bool) Option {
+	return func(o *Options) {
+		prev := o.Filter
+		if prev == nil {
+			o.Filter = keep
+			return
+		}
+		o.Filter = func(d *Dependency) bool { return prev(d) && keep(d) }
+	}
+}
+
+// WithJobs bounds how many binaries are inspected concurrently.
+func WithJobs(n int) Option {
+	return func(o *Options) { o.Jobs = n }
+}
+
+// WithLeaves adds binaries not to expand, as accepted by MatchesLeaf.
+func WithLeaves(leaves ...string) Option {
+	return func(o *Options) { o.Leaves = append(o.Leaves, leaves...) }
+}
+
+// WithResolver sets the resolver finding the binaries dependencies refer to.
+func WithResolver(r Resolver) Option {
+	return func(o *Options) { o.Resolver = r }
+}
+
+// WithInspector sets the inspector finding the dependencies of binaries.
+func WithInspector(in Inspector) Option {
+	return func(o *Options) { o.Inspector = in }
+}
@@ -0,0 +1,150 @@
+package totool
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Origin classifies binaries after where they come from.
+type Origin int
+
+const (
+	// OriginOther is any binary not falling in another class.
+	OriginOther Origin = iota
+
+	// OriginSystem binaries are shipped with macOS.
+	OriginSystem
+
+	// OriginPackage binaries are installed by Homebrew, MacPorts or Fink.
+	OriginPackage
+
+	// OriginEmbedded binaries are shipped along with the root.
+	OriginEmbedded
+)
+
+// Origins lists all origins in display order.
+var Origins = []Origin{OriginSystem, OriginPackage, OriginEmbedded, OriginOther}
+
+func (o Origin) String() string {
+	switch o {
+	case OriginSystem:
+		return "system"
+	case OriginPackage:
+		return "package"
+	case OriginEmbedded:
+		return "embedded"
+	default:
+		return "other"
+	}
+}
+
+// ParseOrigin returns the origin named s as by Origin.String.
+func ParseOrigin(s string) (Origin, bool) {
+	for _, o := range Origins {
+		if o.String() == s {
+			return o, true
+		}
+	}
+	return OriginOther, false
+}
+
+// systemPrefixes are the directories holding binaries shipped with macOS, the
+// base system of Linux distributions and Windows.
+var systemPrefixes = []string{"/usr/lib/", "/System/Library/", "/lib/", "/lib64/", "/usr/lib64/", WindowsSystemDir}
+
+// AddSystemRoot makes the binaries below the system directories of root, a
+// copy of the file system of a Linux machine, count as shipped with the
+// system.  It must be called before walking.
+func AddSystemRoot(root string) {
+	for _, dir := range []string{"/usr/lib/", "/lib/", "/lib64/", "/usr/lib64/"} {
+		systemPrefixes = append(systemPrefixes, filepath.Join(root, dir)+"/")
+	}
+}
+
+// packagePrefixes are the directories where package managers install binaries.
+var packagePrefixes = []string{"/opt/homebrew/", "/usr/local/", "/opt/local/", "/sw/"}
+
+// IsSystemBin reports whether bin is shipped with macOS.
+func IsSystemBin(bin string) bool {
+	return hasAnyPrefix(bin, systemPrefixes)
+}
+
+// IsPackageBin reports whether bin is installed by a package manager such as
+// Homebrew, MacPorts or Fink.
+func IsPackageBin(bin string) bool {
+	return hasAnyPrefix(bin, packagePrefixes)
+}
+
+// Classify returns the origin of bin, a dependency of root.
+func Classify(root, bin string) Origin {
+	switch {
+	case IsSystemBin(bin):
+		return OriginSystem
+	case strings.HasPrefix(bin, bundleDir(root)+string(filepath.Separator)):
+		return OriginEmbedded
+	case IsPackageBin(bin):
+		return OriginPackage
+	default:
+		return OriginOther
+	}
+}
+
+// bundleDir returns the directory holding what is shipped along with root:
+// the enclosing app bundle if any or the directory containing root otherwise.
+func bundleDir(root string) string {
+	if app := AppBundle(root); app != "" {
+		return app
+	}
+	return filepath.Dir(root)
+}
+
+// AppBundle returns the path of the app bundle enclosing bin, or the empty
+// string if there is none.
+func AppBundle(bin string) string {
+	const ext = ".app/"
+	if i := strings.Index(bin, ext); i >= 0 {
+		return bin[:i+len(ext)-1]
+	}
+	return ""
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SystemLeaves lists system binaries that are present on all macOS systems and
+// are therefore not worth expanding.  Entries ending with a slash match all
+// binaries in the directory.
+var SystemLeaves = []string{
+	WindowsSystemDir,
+	"/usr/lib/libSystem.B.dylib",
+	"/usr/lib/libc++.1.dylib",
+	"/usr/lib/libc++abi.dylib",
+	"/usr/lib/libobjc.A.dylib",
+	"/usr/lib/system/",
+	"/System/Library/Frameworks/AppKit.framework/",
+	"/System/Library/Frameworks/ApplicationServices.framework/",
+	"/System/Library/Frameworks/Carbon.framework/",
+	"/System/Library/Frameworks/Cocoa.framework/",
+	"/System/Library/Frameworks/CoreFoundation.framework/",
+	"/System/Library/Frameworks/CoreGraphics.framework/",
+	"/System/Library/Frameworks/CoreServices.framework/",
+	"/System/Library/Frameworks/Foundation.framework/",
+	"/System/Library/Frameworks/IOKit.framework/",
+	"/System/Library/Frameworks/Security.framework/",
+}
+
+// MatchesLeaf reports whether bin matches one of the entries of leaves.
+func MatchesLeaf(bin string, leaves []string) bool {
+	for _, leaf := range leaves {
+		if bin == leaf || (strings.HasSuffix(leaf, "/") && strings.HasPrefix(bin, leaf)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+)
+
+// A Vulnerability is a known vulnerability as reported by OSV.
+type Vulnerability struct {
+	ID      string   `json:"id"`
+	Summary string   `json:"summary,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// DefaultOSVURL is the endpoint of the OSV query API.
+const DefaultOSVURL = "https://api.osv.dev/v1/query"
+
+// An OSVClient looks up the known vulnerabilities of packages in the OSV
+// database, caching answers in the cache directory set by SetCacheDir.
+type OSVClient struct {
+	// URL is the endpoint of the query API, DefaultOSVURL if empty.
+	URL string
+
+	// Ecosystem is the OSV ecosystem package names are looked up in.  How
+	// well package names match depends on it as there is no ecosystem for
+	// Homebrew.
+	Ecosystem string
+
+	// Offline restricts lookups to cached answers.
+	Offline bool
+}
+
+// osvCacheExt is the extension of the files caching OSV answers.
+const osvCacheExt = ".osv"
+
+// Query returns the known vulnerabilities of p.  Offline lookups of packages
+// not cached return no vulnerabilities.
+func (c *OSVClient) Query(ctx context.Context, p Package) ([]Vulnerability, error) {
+	var query struct {
+		Package struct {
+			Name      string `json:"name"`
+			Ecosystem string `json:"ecosystem,omitempty"`
+		} `json:"package"`
+		Version string `json:"version"`
+	}
+	query.Package.Name = osvName(p.Name)
+	query.Package.Ecosystem = c.Ecosystem
+	query.Version = p.Version
+	body, err := json.Marshal(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := ""
+	if diskCacheDir != "" {
+		key := sha256.Sum256(body)
+		cache = filepath.Join(diskCacheDir, hex.EncodeToString(key[:])+osvCacheExt)
+	}
+	var data []byte
+	if cache != "" {
+		data, _ = ioutil.ReadFile(cache)
+	}
+	if data == nil {
+		if c.Offline {
+			return nil, nil
+		}
+		if data, err = c.post(ctx, body); err != nil {
+			return nil, err
+		}
+		if cache != "" {
+			if err := writeCacheFile(cache, data); err != nil {
+				log.Printf("cannot cache OSV answer: %v", err)
+			}
+		}
+	}
+
+	var answer struct {
+		Vulns []Vulnerability `json:"vulns"`
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return nil, fmt.Errorf("cannot decode OSV answer about %s: %v", p, err)
+	}
+	return answer.Vulns, nil
+}
+
+// post sends query to the OSV API and returns its answer.
+func (c *OSVClient) post(ctx context.Context, query []byte) ([]byte, error) {
+	url := c.URL
+	if url == "" {
+		url = DefaultOSVURL
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSV query failed: %s", resp.Status)
+	}
+	return data, nil
+}
+
+// formulaVersionRe matches the version suffix of versioned Homebrew formulae.
+var formulaVersionRe = regexp.MustCompile(`@[0-9.]+$`)
+
+// osvName returns the name OSV likely knows the package name under, without
+// the version suffix of versioned formulae such as openssl@3.
+func osvName(name string) string {
+	return formulaVersionRe.ReplaceAllString(name, "")
+}
@@ -0,0 +1,115 @@
+package totool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// depRe matches on otool output line.
+//
+//	/usr/lib/libobjc.A.dylib (compatibility version 1.0.0, current version 228.0.0, upward)
+var depRe = regexp.MustCompile(`\s*(.*)\s+(\(.*\))`)
+
+// OtoolInspector finds dependencies by running "otool -L", several binaries at
+// a time.  Its output is memoized for the duration of the process and cached
+// on disk as set by SetCacheDir.
+type OtoolInspector struct{}
+
+// Inspect implements Inspector.
+func (OtoolInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	r := inspectBins(ctx, []string{bin})[0]
+	return r.deps, r.err
+}
+
+func (OtoolInspector) inspectBatch(ctx context.Context, bins []string) []inspection {
+	return inspectBins(ctx, bins)
+}
+
+// otool runs "otool -L" on bins.  When passed several binaries, otool prints
+// their dependencies one after the other.  It also returns the warnings otool
+// printed about each binary, as splitWarnings maps them.
+func otool(ctx context.Context, bins ...string) ([]byte, map[string][]string, error) {
+	cmd := exec.CommandContext(ctx, tool("otool"), append([]string{"-L"}, bins...)...)
+	out, warnings, err := outputWarnings(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, toolError("otool", bins, err)
+	}
+	return out, splitWarnings(bins, warnings), nil
+}
+
+// splitWarnings maps each of bins to the warnings naming it.  Warnings go to
+// the binary if there is only one, otherwise those naming none of bins are
+// mapped to the empty string as they cannot be told apart.
+func splitWarnings(bins, warnings []string) map[string][]string {
+	if len(warnings) == 0 {
+		return nil
+	}
+	m := make(map[string][]string)
+	for _, w := range warnings {
+		named := false
+		for _, bin := range bins {
+			if len(bins) == 1 || strings.Contains(w, bin) {
+				m[bin] = append(m[bin], w)
+				named = true
+			}
+		}
+		if !named {
+			m[""] = append(m[""], w)
+		}
+	}
+	return m
+}
+
+// splitOtoolOutput splits the output of otool run on several binaries into the
+// output it would have printed for each.
+func splitOtoolOutput(out []byte) map[string][]byte {
+	outs := make(map[string][]byte)
+	var bin string
+	for _, line := range bytes.SplitAfter(out, []byte("\n")) {
+		// Headers name the binary, possibly followed by an architecture.
+		// 	/usr/bin/file (architecture arm64e):
+		if len(line) > 0 && line[0] != '\t' && line[0] != ' ' {
+			header := strings.TrimSuffix(strings.TrimSpace(string(line)), ":")
+			if i := strings.Index(header, " (architecture "); i >= 0 {
+				header = header[:i]
+			}
+			bin = header
+		}
+		if bin != "" {
+			outs[bin] = append(outs[bin], line...)
+		}
+	}
+	return outs
+}
+
+// appendDirectDeps parses out, the output of otool on bin, and appends the
+// dependencies of bin to deps as recorded in bin, that is before resolving
+// their paths.  It returns the augmented slice.
+func appendDirectDeps(deps []Dependency, bin string, out []byte) ([]Dependency, error) {
+	defer addTime(&times.parse, time.Now())
+	countBackend("otool")
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+
+	// Skip first line (the binary we are inspecting)
+	s.Scan()
+
+	for s.Scan() {
+		sms := depRe.FindStringSubmatch(s.Text())
+		if len(sms) != 3 {
+			return nil, &ToolError{Tool: "otool", Bins: []string{bin}, Err: fmt.Errorf("unexpected output %q", s.Text())}
+		}
+		deps = append(deps, Dependency{Bin: intern(sms[1]), Info: intern(sms[2])})
+	}
+
+	return deps, s.Err()
+}
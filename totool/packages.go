@@ -0,0 +1,166 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// A Package is the unit of software a binary was installed with.
+type Package struct {
+	// Name is the name of the package, a Homebrew formula for instance.
+	Name string
+
+	// Version is the installed version of the package.
+	Version string
+}
+
+func (p Package) String() string {
+	return p.Name + " " + p.Version
+}
+
+// IdentifyPackage returns the package bin belongs to if it can tell.  Homebrew
+// binaries are recognized from the layout of the Cellar they live in once
+// symbolic links such as /opt/homebrew/opt/openssl@3 are followed:
+//
+//	/opt/homebrew/Cellar/openssl@3/3.1.4/lib/libssl.3.dylib
+func IdentifyPackage(bin string) (Package, bool) {
+	keg := Keg(bin)
+	if keg == "" {
+		return Package{}, false
+	}
+	return Package{Name: filepath.Base(filepath.Dir(keg)), Version: filepath.Base(keg)}, true
+}
+
+// Keg returns the directory of the Homebrew keg bin was installed in, such as
+// /opt/homebrew/Cellar/openssl@3/3.1.4, or "" if it was not installed by
+// Homebrew.
+func Keg(bin string) string {
+	if real, err := filepath.EvalSymlinks(bin); err == nil {
+		bin = real
+	}
+	return kegOf(bin)
+}
+
+// kegOf returns the keg directory path leads into, without following symbolic
+// links, or "" if it does not lead into the Cellar.
+func kegOf(path string) string {
+	const cellar = "/Cellar/"
+	i := strings.Index(path, cellar)
+	if i < 0 {
+		return ""
+	}
+	parts := strings.SplitN(path[i+len(cellar):], "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return ""
+	}
+	return path[:i+len(cellar)] + parts[0] + "/" + parts[1]
+}
+
+// KegDrift tells when bin, a path into a versioned keg such as
+// /opt/homebrew/Cellar/foo/1.2.3/lib/libfoo.dylib rather than into the opt
+// directory of the formula, is or is about to be missing since the formula
+// was upgraded or uninstalled.  It returns false for other binaries.
+func KegDrift(bin string) (string, bool) {
+	keg := kegOf(bin)
+	if keg == "" {
+		return "", false
+	}
+	name := filepath.Base(filepath.Dir(keg))
+	version := filepath.Base(keg)
+	current := ""
+	if k := Keg(filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(keg))), "opt", name)); k != "" {
+		current = filepath.Base(k)
+	}
+	if _, err := os.Stat(keg); err != nil {
+		if current == "" {
+			return fmt.Sprintf("missing since %s was uninstalled", name), true
+		}
+		return fmt.Sprintf("missing since %s was upgraded to %s", name, current), true
+	}
+	if current != "" && current != version {
+		return fmt.Sprintf("missing after the next brew cleanup since %s was upgraded to %s", name, current), true
+	}
+	return "", false
+}
+
+// homebrewPrefixes lists where Homebrew installs on Apple silicon, on Intel
+// and on Linux.
+var homebrewPrefixes = []string{"/opt/homebrew/", "/usr/local/", "/home/linuxbrew/.linuxbrew/"}
+
+// HomebrewFormula returns the Homebrew formula that installed bin along with
+// its version.  The formula is found from the Cellar layout as IdentifyPackage
+// does, then for binaries that cannot be resolved into the Cellar, from the
+// opt directory their path goes through or by looking for the latest keg
+// holding a binary of the same name.  Versions not told by the layout are asked to brew.
+// The name of the returned package is empty if bin was not installed by
+// Homebrew.
+func HomebrewFormula(ctx context.Context, bin string) (Package, error) {
+	if p, ok := IdentifyPackage(bin); ok {
+		return p, nil
+	}
+	prefix := ""
+	for _, p := range homebrewPrefixes {
+		if strings.HasPrefix(bin, p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return Package{}, nil
+	}
+
+	// /opt/homebrew/opt/openssl@3/lib/libssl.3.dylib
+	rel := strings.TrimPrefix(bin, prefix)
+	if strings.HasPrefix(rel, "opt/") {
+		name := strings.SplitN(strings.TrimPrefix(rel, "opt/"), "/", 2)[0]
+		if keg := Keg(prefix + "opt/" + name); keg != "" {
+			return Package{Name: name, Version: filepath.Base(keg)}, nil
+		}
+		version, err := brewVersion(ctx, name)
+		return Package{Name: name, Version: version}, err
+	}
+
+	// /opt/homebrew/lib/libssl.3.dylib
+	kegs, _ := filepath.Glob(filepath.Join(prefix, "Cellar", "*", "*", rel))
+	if len(kegs) == 0 {
+		return Package{}, nil
+	}
+	var latest Package
+	for _, k := range kegs {
+		if p, ok := IdentifyPackage(k); ok && (latest.Name == "" || CompareVersions(p.Version, latest.Version) > 0) {
+			latest = p
+		}
+	}
+	return latest, nil
+}
+
+// brewVersion returns the latest installed version of the Homebrew formula
+// name as told by "brew list --versions", or the empty string if it is not
+// installed.
+func brewVersion(ctx context.Context, name string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "brew", "list", "--versions", name)
+	cmd.Stderr = &stderr
+	out, err := output(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if _, ok := err.(*exec.ExitError); ok {
+			// brew fails on formulae that are not installed.
+			return "", nil
+		}
+		return "", &ToolError{Tool: "brew", Bins: []string{name}, Err: err, Stderr: stderr.String()}
+	}
+	//	openssl@3 3.1.4 3.2.0
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return "", nil
+	}
+	return fields[len(fields)-1], nil
+}
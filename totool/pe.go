@@ -0,0 +1,221 @@
+package totool
+
+import (
+	"context"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PEInspector finds the dependencies of Windows executables and DLLs by
+// parsing their import and delay-load import tables.
+type PEInspector struct{}
+
+// Inspect implements Inspector.
+func (PEInspector) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer addTime(&times.parse, time.Now())
+	countBackend("pe")
+
+	info, err := metadataOf(bin).pe()
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]Dependency, 0, len(info.imports)+len(info.delayed))
+	for _, name := range info.imports {
+		deps = append(deps, Dependency{Bin: intern(name)})
+	}
+	for _, name := range info.delayed {
+		deps = append(deps, Dependency{Bin: intern(name), Info: "(delay-load)"})
+	}
+	return deps, nil
+}
+
+// peInfo summarizes the imports of a PE binary.
+type peInfo struct {
+	machine uint16
+
+	// imports and delayed list the DLLs of the import and delay-load
+	// import tables, as named there.
+	imports, delayed []string
+}
+
+// readPE parses the PE binary bin.
+func readPE(bin string) (*peInfo, error) {
+	f, err := pe.Open(bin)
+	if err != nil {
+		if !isPE(bin) {
+			return nil, wrongFormat(bin, FormatPE)
+		}
+		return nil, err
+	}
+	defer f.Close()
+	info := &peInfo{machine: f.Machine}
+	if info.imports, err = peImports(f, peImportDir, 20, 12); err != nil {
+		return nil, fmt.Errorf("%s: %v", bin, err)
+	}
+	if info.delayed, err = peImports(f, peDelayImportDir, 32, 4); err != nil {
+		return nil, fmt.Errorf("%s: %v", bin, err)
+	}
+	return info, nil
+}
+
+// Indexes of the data directories of PE optional headers.
+const (
+	peImportDir      = 1
+	peDelayImportDir = 13
+)
+
+// peImports returns the names of the DLLs of the import table in data
+// directory dir of f, made of descriptors of size bytes ending with a null
+// one, with the address of the name at offset nameOff.
+func peImports(f *pe.File, dir, size, nameOff int) ([]string, error) {
+	var dd pe.DataDirectory
+	switch h := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if int(h.NumberOfRvaAndSizes) <= dir {
+			return nil, nil
+		}
+		dd = h.DataDirectory[dir]
+	case *pe.OptionalHeader64:
+		if int(h.NumberOfRvaAndSizes) <= dir {
+			return nil, nil
+		}
+		dd = h.DataDirectory[dir]
+	default:
+		return nil, nil
+	}
+	if dd.VirtualAddress == 0 {
+		return nil, nil
+	}
+	var names []string
+	for addr := dd.VirtualAddress; ; addr += uint32(size) {
+		desc, err := peRead(f, addr, size)
+		if err != nil {
+			return nil, err
+		}
+		nameAddr := binary.LittleEndian.Uint32(desc[nameOff:])
+		if nameAddr == 0 {
+			return names, nil
+		}
+		name, err := peString(f, nameAddr)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+}
+
+// peRead reads n bytes of f at relative virtual address addr.
+func peRead(f *pe.File, addr uint32, n int) ([]byte, error) {
+	for _, s := range f.Sections {
+		if addr < s.VirtualAddress || addr >= s.VirtualAddress+s.VirtualSize {
+			continue
+		}
+		buf := make([]byte, n)
+		if _, err := s.ReadAt(buf, int64(addr-s.VirtualAddress)); err != nil {
+			return nil, fmt.Errorf("cannot read import table: %v", err)
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("import table address %#x outside of sections", addr)
+}
+
+// peString reads the null-terminated string of f at relative virtual address
+// addr.
+func peString(f *pe.File, addr uint32) (string, error) {
+	var name []byte
+	for {
+		b, err := peRead(f, addr+uint32(len(name)), 1)
+		if err != nil {
+			return "", err
+		}
+		if b[0] == 0 {
+			return string(name), nil
+		}
+		name = append(name, b[0])
+	}
+}
+
+// isPE reports whether path is a PE binary.
+func isPE(path string) bool {
+	return isFormat(path, FormatPE)
+}
+
+// WindowsSystemDir is where DLLs shipped with Windows are assumed to live.
+const WindowsSystemDir = "C:/Windows/System32/"
+
+// knownDLLs lists DLLs shipped with Windows, which the loader takes from the
+// system directory whatever the search path.
+var knownDLLs = map[string]bool{
+	"advapi32.dll": true, "bcrypt.dll": true, "cfgmgr32.dll": true,
+	"comctl32.dll": true, "comdlg32.dll": true, "crypt32.dll": true,
+	"d3d9.dll": true, "d3d11.dll": true, "d3d12.dll": true, "dbghelp.dll": true,
+	"dwmapi.dll": true, "dxgi.dll": true, "gdi32.dll": true, "hid.dll": true,
+	"imm32.dll": true, "iphlpapi.dll": true, "kernel32.dll": true,
+	"kernelbase.dll": true, "mpr.dll": true, "msvcrt.dll": true,
+	"netapi32.dll": true, "normaliz.dll": true, "ntdll.dll": true,
+	"ole32.dll": true, "oleaut32.dll": true, "opengl32.dll": true,
+	"powrprof.dll": true, "psapi.dll": true, "rpcrt4.dll": true,
+	"secur32.dll": true, "setupapi.dll": true, "shcore.dll": true,
+	"shell32.dll": true, "shlwapi.dll": true, "ucrtbase.dll": true,
+	"user32.dll": true, "userenv.dll": true, "uxtheme.dll": true,
+	"version.dll": true, "winhttp.dll": true, "wininet.dll": true,
+	"winmm.dll": true, "ws2_32.dll": true, "wtsapi32.dll": true,
+}
+
+// PEResolver resolves the dependencies of PE binaries like the Windows loader
+// does in safe DLL search mode: DLLs shipped with Windows and API sets resolve
+// to WindowsSystemDir, others are looked for in the directory of the
+// executable then in SearchPath, ignoring case.  DLLs built for another
+// machine than the loader are skipped.
+type PEResolver struct {
+	// SearchPath lists directories searched like the system directories
+	// and PATH.
+	SearchPath []string
+}
+
+// Resolve implements Resolver.
+func (r *PEResolver) Resolve(path, loader, executable string) string {
+	name := strings.ToLower(path)
+	if knownDLLs[name] || strings.HasPrefix(name, "api-ms-") || strings.HasPrefix(name, "ext-ms-") {
+		return WindowsSystemDir + name
+	}
+	from, err := metadataOf(loader).pe()
+	if err != nil {
+		return path
+	}
+	for _, dir := range append([]string{filepath.Dir(executable)}, r.SearchPath...) {
+		p, ok := findFold(dir, path)
+		if !ok {
+			continue
+		}
+		if info, err := metadataOf(p).pe(); err == nil && info.machine == from.machine {
+			return p
+		}
+	}
+	return path
+}
+
+// findFold returns the path of the file of dir named name ignoring case.
+func findFold(dir, name string) (string, bool) {
+	if p := filepath.Join(dir, name); exists(p) {
+		return p, true
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, fi := range fis {
+		if strings.EqualFold(fi.Name(), name) {
+			return filepath.Join(dir, fi.Name()), true
+		}
+	}
+	return "", false
+}
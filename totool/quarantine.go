@@ -0,0 +1,32 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// quarantineAttr is the extended attribute Gatekeeper checks before letting
+// downloaded files run or load.
+const quarantineAttr = "com.apple.quarantine"
+
+// Quarantine returns the com.apple.quarantine extended attribute of bin, such as
+// "0083;5f1e3b2c;Safari;", or the empty string if bin is not quarantined.  It
+// runs xattr.
+func Quarantine(ctx context.Context, bin string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "xattr", "-p", quarantineAttr, bin)
+	cmd.Stderr = &stderr
+	out, err := output(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if _, ok := err.(*exec.ExitError); ok && strings.Contains(stderr.String(), "No such xattr") {
+			return "", nil
+		}
+		return "", toolErrorStderr("xattr", bin, stderr.String(), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
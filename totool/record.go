@@ -0,0 +1,236 @@
+package totool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// recording is what an inspector found about a binary, as saved in a
+// recording directory.
+type recording struct {
+	Bin  string           `json:"bin"`
+	Deps []recordedDep    `json:"deps,omitempty"`
+	Err  *recordedFailure `json:"error,omitempty"`
+}
+
+type recordedDep struct {
+	Bin  string `json:"bin"`
+	Info string `json:"info,omitempty"`
+}
+
+type recordedFailure struct {
+	Msg string `json:"msg"`
+
+	// Kind is the message of the sentinel error the failure matched, if any.
+	Kind string `json:"kind,omitempty"`
+}
+
+// resolution is a path resolved while recording.
+type resolution struct {
+	Path       string `json:"path"`
+	Loader     string `json:"loader"`
+	Executable string `json:"executable"`
+	Result     string `json:"result"`
+}
+
+// resolutionsFile is the name of the file recording resolved paths.
+const resolutionsFile = "resolutions.json"
+
+// sentinels lists the errors whose identity recordings preserve.
+var sentinels = []error{ErrMissingFile, ErrNotMachO, ErrToolFailed}
+
+// recordingPath returns where the recording of bin is saved in dir.
+func recordingPath(dir, bin string) string {
+	key := sha256.Sum256([]byte(bin))
+	return filepath.Join(dir, hex.EncodeToString(key[:])+".json")
+}
+
+// A Recorder is an Inspector and a Resolver saving in a directory what the
+// ones it wraps find so that walks can be replayed later by a Replayer.
+type Recorder struct {
+	dir string
+	in  Inspector
+	r   Resolver
+
+	mu       sync.Mutex
+	resolved []resolution
+	seen     map[resolution]bool
+}
+
+// NewRecorder returns a recorder saving in dir what in and r find.  Nil in and
+// r stand for an OtoolInspector and a DyldResolver without search paths.
+func NewRecorder(dir string, in Inspector, r Resolver) (*Recorder, error) {
+	if in == nil {
+		in = OtoolInspector{}
+	}
+	if r == nil {
+		r = &DyldResolver{}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, in: in, r: r, seen: make(map[resolution]bool)}, nil
+}
+
+// Inspect implements Inspector.
+func (rec *Recorder) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	deps, err := rec.in.Inspect(ctx, bin)
+	rec.record(ctx, bin, deps, err)
+	return deps, err
+}
+
+func (rec *Recorder) inspectBatch(ctx context.Context, bins []string) []inspection {
+	results := inspectBatch(ctx, rec.in, bins)
+	for i, bin := range bins {
+		rec.record(ctx, bin, results[i].deps, results[i].err)
+	}
+	return results
+}
+
+// record saves what inspecting bin returned unless the inspection was cut
+// short by ctx.
+func (rec *Recorder) record(ctx context.Context, bin string, deps []Dependency, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	r := recording{Bin: bin}
+	for _, d := range deps {
+		r.Deps = append(r.Deps, recordedDep{Bin: d.Bin, Info: d.Info})
+	}
+	if err != nil {
+		r.Err = &recordedFailure{Msg: err.Error()}
+		for _, s := range sentinels {
+			if errors.Is(err, s) {
+				r.Err.Kind = s.Error()
+				break
+			}
+		}
+	}
+	if err := writeJSON(recordingPath(rec.dir, bin), r); err != nil {
+		log.Printf("cannot record %s: %v", bin, err)
+	}
+}
+
+// Resolve implements Resolver.
+func (rec *Recorder) Resolve(path, loader, executable string) string {
+	res := resolution{Path: path, Loader: loader, Executable: executable}
+	res.Result = rec.r.Resolve(path, loader, executable)
+	rec.mu.Lock()
+	if !rec.seen[res] {
+		rec.seen[res] = true
+		rec.resolved = append(rec.resolved, res)
+	}
+	rec.mu.Unlock()
+	return res.Result
+}
+
+// Save saves the paths resolved so far.  It must be called once done walking.
+func (rec *Recorder) Save() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return writeJSON(filepath.Join(rec.dir, resolutionsFile), rec.resolved)
+}
+
+// writeJSON atomically writes v as JSON into path.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeCacheFile(path, append(data, '\n'))
+}
+
+// A Replayer is an Inspector and a Resolver returning what a Recorder saved in
+// a directory, without looking at binaries at all.
+type Replayer struct {
+	dir string
+
+	once     sync.Once
+	resolved map[resolution]string
+}
+
+// NewReplayer returns a replayer of what was recorded in dir.
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// Inspect implements Inspector.  Binaries that were not recorded are reported
+// as missing.
+func (rp *Replayer) Inspect(ctx context.Context, bin string) ([]Dependency, error) {
+	data, err := ioutil.ReadFile(recordingPath(rp.dir, bin))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("not recorded: %w", ErrMissingFile)
+	} else if err != nil {
+		return nil, err
+	}
+	var r recording
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("cannot decode recording of %s: %v", bin, err)
+	}
+	if r.Err != nil {
+		return nil, r.Err.error()
+	}
+	deps := make([]Dependency, 0, len(r.Deps))
+	for _, d := range r.Deps {
+		deps = append(deps, Dependency{Bin: intern(d.Bin), Info: intern(d.Info)})
+	}
+	return deps, nil
+}
+
+// Resolve implements Resolver.  Paths that were not recorded are left alone.
+func (rp *Replayer) Resolve(path, loader, executable string) string {
+	rp.once.Do(rp.load)
+	if p, ok := rp.resolved[resolution{Path: path, Loader: loader, Executable: executable}]; ok {
+		return p
+	}
+	return path
+}
+
+// load reads the recorded resolutions.
+func (rp *Replayer) load() {
+	rp.resolved = make(map[resolution]string)
+	data, err := ioutil.ReadFile(filepath.Join(rp.dir, resolutionsFile))
+	if err != nil {
+		log.Printf("cannot replay resolutions: %v", err)
+		return
+	}
+	var rs []resolution
+	if err := json.Unmarshal(data, &rs); err != nil {
+		log.Printf("cannot replay resolutions: %v", err)
+		return
+	}
+	for _, r := range rs {
+		p := r.Result
+		r.Result = ""
+		rp.resolved[r] = p
+	}
+}
+
+// replayedError is a recorded inspection failure.
+type replayedError struct {
+	msg  string
+	kind error
+}
+
+func (e *replayedError) Error() string { return e.msg }
+func (e *replayedError) Unwrap() error { return e.kind }
+
+// error returns the error f records.
+func (f *recordedFailure) error() error {
+	e := &replayedError{msg: f.Msg}
+	for _, s := range sentinels {
+		if f.Kind == s.Error() {
+			e.kind = s
+		}
+	}
+	return e
+}
@@ -0,0 +1,40 @@
+package totool
+
+// Render notifies pt of the binaries and dependencies of g as if g was being
+// walked again, so that any printer can format a graph loaded from elsewhere.
+func (g *Graph) Render(pt Printer) {
+	pt.PrintPrologue()
+	defer pt.PrintEpilogue()
+
+	// Binaries are notified a level at a time, each level followed by the
+	// dependencies of its binaries, as when walking.
+	nodes := g.Nodes()
+	for start := 0; start < len(nodes); {
+		end := start + 1
+		for end < len(nodes) && nodes[end].Depth == nodes[start].Depth {
+			end++
+		}
+		for _, n := range nodes[start:end] {
+			if n.Depth == 0 && g.IsRoot(n.Path) {
+				pt.PrintRootBin(n.Path)
+				continue
+			}
+			pt.PrintDepBin(&Dependency{
+				Bin:       n.Path,
+				Name:      n.Name,
+				Info:      g.Info(n.Parent, n.Path),
+				Depth:     n.Depth,
+				Parent:    n.Parent,
+				Truncated: n.Truncated,
+				Origin:    n.Origin,
+				Size:      n.Size,
+			})
+		}
+		for _, n := range nodes[start:end] {
+			for _, to := range g.Dependencies(n.Path) {
+				pt.PrintDep(n.Path, to)
+			}
+		}
+		start = end
+	}
+}
@@ -0,0 +1,152 @@
+package totool
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Resolver finds the binaries the dependencies recorded in binaries refer to.
+type Resolver interface {
+	// Resolve returns the path of the binary path refers to when loaded by
+	// loader on behalf of executable, or path itself if it cannot tell.
+	Resolve(path, loader, executable string) string
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(path, loader, executable string) string
+
+// Resolve calls f.
+func (f ResolverFunc) Resolve(path, loader, executable string) string {
+	return f(path, loader, executable)
+}
+
+// DyldResolver resolves paths like the macOS dynamic loader: @executable_path,
+// @loader_path and @rpath are expanded and search paths are looked into.
+type DyldResolver struct {
+	// LibraryPath lists directories searched before the path of dependencies
+	// like DYLD_LIBRARY_PATH.
+	LibraryPath []string
+
+	// FallbackLibraryPath lists directories searched when dependencies do not
+	// exist like DYLD_FALLBACK_LIBRARY_PATH.
+	FallbackLibraryPath []string
+}
+
+// NewDyldResolver returns a resolver using the search paths of the environment.
+func NewDyldResolver() *DyldResolver {
+	return &DyldResolver{
+		LibraryPath:         splitPathList(os.Getenv("DYLD_LIBRARY_PATH")),
+		FallbackLibraryPath: splitPathList(os.Getenv("DYLD_FALLBACK_LIBRARY_PATH")),
+	}
+}
+
+// splitPathList splits a colon-separated list of directories.
+func splitPathList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return filepath.SplitList(list)
+}
+
+const (
+	executablePrefix = "@executable_path/"
+	loaderPrefix     = "@loader_path/"
+	rpathPrefix      = "@rpath/"
+)
+
+// Resolve implements Resolver.
+func (r *DyldResolver) Resolve(path, loader, executable string) string {
+	if strings.HasPrefix(path, rpathPrefix) {
+		for _, p := range RpathCandidates(path, []string{loader, executable}, executable) {
+			if exists(p) {
+				return p
+			}
+		}
+		return path
+	}
+
+	path = expandRelative(path, loader, executable)
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	if p, ok := searchDirs(r.LibraryPath, filepath.Base(path)); ok {
+		return p
+	}
+	if !exists(path) {
+		if p, ok := searchDirs(r.FallbackLibraryPath, filepath.Base(path)); ok {
+			return p
+		}
+	}
+	return path
+}
+
+// RpathCandidates returns the paths an @rpath-prefixed path may refer to given
+// the run path search paths of loaders, binaries brought in on behalf of
+// executable, in the order the dynamic loader tries them.
+func RpathCandidates(path string, loaders []string, executable string) []string {
+	if !strings.HasPrefix(path, rpathPrefix) {
+		return nil
+	}
+	rest := strings.TrimPrefix(path, rpathPrefix)
+	var candidates []string
+	for _, bin := range loaders {
+		info, err := metadataOf(bin).machO()
+		if err != nil {
+			continue
+		}
+		for _, rpath := range info.rpaths {
+			candidates = append(candidates, filepath.Join(expandRelative(rpath, bin, executable), rest))
+		}
+	}
+	return candidates
+}
+
+// expandRelative expands the @executable_path and @loader_path prefixes of
+// path.
+func expandRelative(path, loader, executable string) string {
+	switch {
+	case strings.HasPrefix(path, executablePrefix):
+		return filepath.Join(filepath.Dir(executable), strings.TrimPrefix(path, executablePrefix))
+	case strings.HasPrefix(path, loaderPrefix):
+		return filepath.Join(filepath.Dir(loader), strings.TrimPrefix(path, loaderPrefix))
+	default:
+		return path
+	}
+}
+
+// searchDirs returns the first existing file named name in dirs.
+func searchDirs(dirs []string, name string) (string, bool) {
+	for _, dir := range dirs {
+		if p := filepath.Join(dir, name); exists(p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// exists reports whether path names an existing file.
+func exists(path string) bool {
+	_, err := metadataOf(path).fileInfo()
+	return err == nil
+}
+
+// resolveDeps resolves with r the dependencies of bin as recorded in bin,
+// loaded on behalf of executable.  It returns the resolved dependencies and
+// the additional data otool reports about bin itself, if any.
+func resolveDeps(r Resolver, bin, executable string, raw []Dependency) ([]Dependency, string) {
+	var deps []Dependency
+	var id string
+	for _, d := range raw {
+		d.Name = d.Bin
+		d.Bin = intern(r.Resolve(d.Bin, bin, executable))
+		if d.Bin != bin {
+			deps = append(deps, d)
+		} else {
+			// The first dependency is the binary itself probably to display extra info about it.
+			// Filter it out to avoid displaying self-edges in the graph.
+			id = d.Info
+		}
+	}
+	return deps, id
+}
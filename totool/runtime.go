@@ -0,0 +1,77 @@
+package totool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// LoadedLibraries runs bin with args, dyld printing the images it loads as
+// DYLD_PRINT_LIBRARIES makes it do, and returns their paths in load order,
+// starting with bin itself.  bin is left running until it exits or ctx is done,
+// the images loaded so far being returned in the latter case along with the
+// error of ctx.  bin exiting with a failure is not an error.
+func LoadedLibraries(ctx context.Context, bin string, args []string) ([]string, error) {
+	// Standard error goes to a file rather than a pipe so that children bin
+	// leaves behind do not keep the wait going on once it is killed.  Its
+	// standard output is discarded for the same reason.
+	f, err := ioutil.TempFile("", "totool-dyld-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Env = append(os.Environ(), "DYLD_PRINT_LIBRARIES=1")
+	cmd.Stderr = f
+	err = run(ctx, cmd)
+	stderr, rerr := ioutil.ReadFile(f.Name())
+	if rerr != nil {
+		return nil, rerr
+	}
+	libs := parseLoadedLibraries(stderr)
+	if ctx.Err() != nil {
+		return libs, ctx.Err()
+	}
+	if _, ok := err.(*exec.ExitError); ok || err == nil {
+		return libs, nil
+	}
+	return libs, toolErrorStderr(bin, bin, string(stderr), err)
+}
+
+// parseLoadedLibraries extracts the paths of loaded images from what dyld
+// prints with DYLD_PRINT_LIBRARIES, mixed with what the program prints itself.
+// macOS 12 and later and earlier releases respectively print:
+//
+//	dyld[1234]: <2D7D...> /usr/lib/libSystem.B.dylib
+//	dyld: loaded: <2D7D...> /usr/lib/libSystem.B.dylib
+func parseLoadedLibraries(out []byte) []string {
+	var libs []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "dyld") {
+			continue
+		}
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			continue
+		}
+		rest := strings.TrimPrefix(line[i+2:], "loaded: ")
+		// The UUID of the image, if any, comes first.
+		if strings.HasPrefix(rest, "<") {
+			if j := strings.Index(rest, "> "); j >= 0 {
+				rest = rest[j+2:]
+			}
+		}
+		if strings.HasPrefix(rest, "/") {
+			libs = append(libs, rest)
+		}
+	}
+	return libs
+}
@@ -0,0 +1,225 @@
+package totool
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scanIndexExt is the extension of the files in diskCacheDir recording the
+// result of previous directory scans.  It changed when ELF and then PE
+// binaries started being found, for indexes predating them not to hide them,
+// and when it started recording why other files are skipped.
+const scanIndexExt = ".scan4"
+
+// Reasons for skipping files.  Binaries are not skipped for their
+// architectures, which checks compare with those of the roots, nor for being
+// encrypted, as their load commands can still be read.
+const (
+	// SkipNotBinary files are not binaries of a format DetectFormat knows.
+	SkipNotBinary = "not a binary"
+
+	// SkipUnreadable files and directories cannot be read.
+	SkipUnreadable = "unreadable"
+
+	// SkipWrongFormat files are binaries of a format the scan does not
+	// walk, such as ELF binaries in app bundles.
+	SkipWrongFormat = "wrong format"
+)
+
+// A SkippedFile is a file a directory or bundle scan left out.
+type SkippedFile struct {
+	Path string
+
+	// Reason is SkipNotBinary, SkipUnreadable or SkipWrongFormat.
+	Reason string
+
+	// Detail tells what the file looks like or what failed reading it, if
+	// known.
+	Detail string
+}
+
+func (s SkippedFile) String() string {
+	if s.Detail == "" {
+		return s.Reason
+	}
+	return s.Reason + ": " + s.Detail
+}
+
+// skippedFile returns how to report path, which DetectFormat failed on with
+// err.  It returns false for files removed since they were found.
+func skippedFile(path string, err error) (SkippedFile, bool) {
+	var fe *FormatError
+	var pe *os.PathError
+	switch {
+	case errors.Is(err, ErrMissingFile), errors.Is(err, os.ErrNotExist):
+		return SkippedFile{}, false
+	case errors.As(err, &fe):
+		return SkippedFile{Path: path, Reason: SkipNotBinary, Detail: fe.Kind}, true
+	case errors.As(err, &pe):
+		return SkippedFile{Path: path, Reason: SkipUnreadable, Detail: pe.Err.Error()}, true
+	}
+	return SkippedFile{Path: path, Reason: SkipUnreadable, Detail: err.Error()}, true
+}
+
+// scanEntry records what a directory scan found out about a file.
+type scanEntry struct {
+	mtime, size int64
+
+	// binary is set for binaries of the formats DetectFormat knows.
+	binary bool
+
+	// reason and detail tell why other files were skipped.
+	reason, detail string
+}
+
+// ScanDir returns the mach-o, ELF and PE binaries found below dir, mixed in any
+// way.  Files unchanged since the previous scan of dir are not read again and
+// the previous scan is compared against to report what changed.
+func ScanDir(dir string) ([]string, error) {
+	bins, _, err := ScanDirSkipped(dir)
+	return bins, err
+}
+
+// ScanDirSkipped is like ScanDir but also returns the other files below dir,
+// in walk order, along with why they were skipped.  Unreadable files are read
+// again by the next scan, even if unchanged.
+func ScanDirSkipped(dir string) ([]string, []SkippedFile, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot get %q absolute path: %v", dir, err)
+	}
+	indexPath := scanIndexPath(dir)
+	prev := readScanIndex(indexPath)
+	cur := make(map[string]scanEntry)
+
+	var bins []string
+	var skipped []SkippedFile
+	changed := 0
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if s, ok := skippedFile(path, err); ok {
+				log.Printf("%v", err)
+				skipped = append(skipped, s)
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		e := scanEntry{mtime: fi.ModTime().UnixNano(), size: fi.Size()}
+		if p, ok := prev[path]; ok && p.mtime == e.mtime && p.size == e.size {
+			e.binary, e.reason, e.detail = p.binary, p.reason, p.detail
+		} else {
+			_, err := DetectFormat(path)
+			e.binary = err == nil
+			if err != nil {
+				debugf("%s: skipped, %v", path, err)
+				s, ok := skippedFile(path, err)
+				if !ok {
+					return nil
+				}
+				if s.Reason == SkipUnreadable {
+					skipped = append(skipped, s)
+					return nil
+				}
+				e.reason, e.detail = s.Reason, s.Detail
+			}
+			if e.binary {
+				changed++
+			}
+		}
+		cur[path] = e
+		if e.binary {
+			bins = append(bins, path)
+		} else {
+			skipped = append(skipped, SkippedFile{Path: path, Reason: e.reason, Detail: e.detail})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	removed := 0
+	for path, p := range prev {
+		if _, ok := cur[path]; !ok && p.binary {
+			removed++
+		}
+	}
+	if prev != nil {
+		log.Printf("%s: %d binaries, %d new or changed, %d removed since last scan", dir, len(bins), changed, removed)
+	}
+	writeScanIndex(indexPath, cur)
+	return bins, skipped, nil
+}
+
+// isMachO reports whether path is a thin or fat mach-o binary.
+func isMachO(path string) bool {
+	return isFormat(path, FormatMachO, FormatFat)
+}
+
+// scanIndexPath returns where the result of scanning dir is recorded or the
+// empty string if it cannot be.
+func scanIndexPath(dir string) string {
+	if diskCacheDir == "" {
+		return ""
+	}
+	key := sha256.Sum256([]byte(dir))
+	return filepath.Join(diskCacheDir, hex.EncodeToString(key[:])+scanIndexExt)
+}
+
+// readScanIndex returns the files recorded in the index at path, nil if there
+// is none.
+func readScanIndex(path string) map[string]scanEntry {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	index := make(map[string]scanEntry)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Paths come last as they may contain tabs.
+		fields := strings.SplitN(s.Text(), "\t", 6)
+		if len(fields) != 6 {
+			continue
+		}
+		e := scanEntry{reason: fields[3], detail: fields[4]}
+		if _, err := fmt.Sscanf(strings.Join(fields[:3], " "), "%d %d %t", &e.mtime, &e.size, &e.binary); err == nil {
+			index[fields[5]] = e
+		}
+	}
+	return index
+}
+
+// writeScanIndex records index at path for the next scan.
+func writeScanIndex(path string, index map[string]scanEntry) {
+	if path == "" {
+		return
+	}
+	var buf bytes.Buffer
+	for p, e := range index {
+		detail := strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' {
+				return ' '
+			}
+			return r
+		}, e.detail)
+		fmt.Fprintf(&buf, "%d\t%d\t%t\t%s\t%s\t%s\n", e.mtime, e.size, e.binary, e.reason, detail, p)
+	}
+	if err := writeCacheFile(path, buf.Bytes()); err != nil {
+		log.Printf("cannot record scan: %v", err)
+	}
+}
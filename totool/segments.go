@@ -0,0 +1,33 @@
+package totool
+
+import "debug/macho"
+
+// A Segment is a segment of a Mach-O binary.
+type Segment struct {
+	Name string
+
+	// size of the segment in the file in bytes
+	Size uint64
+}
+
+// Segments returns the segments of bin, a Mach-O binary, in the order of its
+// load commands.  Only the first architecture of fat binaries is considered.
+func Segments(bin string) ([]Segment, error) {
+	var f *macho.File
+	if ff, err := macho.OpenFat(bin); err == nil {
+		defer ff.Close()
+		f = ff.Arches[0].File
+	} else {
+		if f, err = macho.Open(bin); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+	var segs []Segment
+	for _, l := range f.Loads {
+		if s, ok := l.(*macho.Segment); ok {
+			segs = append(segs, Segment{Name: s.Name, Size: s.Filesz})
+		}
+	}
+	return segs, nil
+}
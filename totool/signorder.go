@@ -0,0 +1,95 @@
+package totool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// bundleExts lists the extensions of the directories codesign signs as
+// bundles.
+var bundleExts = map[string]bool{
+	".app": true, ".appex": true, ".bundle": true, ".framework": true,
+	".plugin": true, ".xpc": true, ".systemextension": true, ".kext": true,
+}
+
+// SigningOrder returns the code of app in the order codesign must sign it,
+// inside out: mach-o binaries that are not the main executable of their
+// bundle and nested bundles first, deepest first, app itself last.
+// Frameworks are signed through their current version directory.
+func SigningOrder(app string) ([]string, error) {
+	app, err := filepath.Abs(app)
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	mains := make(map[string]bool)
+	err = filepath.Walk(app, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.IsDir() && bundleExts[filepath.Ext(path)]:
+			item, main := bundleCode(path)
+			items = append(items, item)
+			mains[main] = true
+		case fi.Mode().IsRegular() && isMachO(path):
+			items = append(items, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order := items[:0]
+	for _, item := range items {
+		if !mains[item] {
+			order = append(order, item)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return strings.Count(order[i], "/") > strings.Count(order[j], "/")
+	})
+	return order, nil
+}
+
+// bundleCode returns what to sign of the bundle dir and the path of its main
+// executable, which signing the bundle signs.
+func bundleCode(dir string) (item, main string) {
+	name := strings.TrimSuffix(filepath.Base(dir), filepath.Ext(dir))
+	if filepath.Ext(dir) == ".framework" {
+		item = dir
+		if cur, err := filepath.EvalSymlinks(filepath.Join(dir, "Versions", "Current")); err == nil {
+			item = filepath.Join(dir, "Versions", filepath.Base(cur))
+		}
+		if exe := bundleExecutable(filepath.Join(item, "Resources", "Info.plist")); exe != "" {
+			name = exe
+		}
+		return item, filepath.Join(item, name)
+	}
+	if exe := bundleExecutable(filepath.Join(dir, "Contents", "Info.plist")); exe != "" {
+		name = exe
+	}
+	return dir, filepath.Join(dir, "Contents", "MacOS", name)
+}
+
+// bundleExecutableRe matches the main executable of bundles in XML property
+// lists.
+var bundleExecutableRe = regexp.MustCompile(`<key>CFBundleExecutable</key>\s*<string>([^<]*)</string>`)
+
+// bundleExecutable returns the CFBundleExecutable entry of the XML property
+// list plist, empty if not found.
+func bundleExecutable(plist string) string {
+	data, err := ioutil.ReadFile(plist)
+	if err != nil {
+		return ""
+	}
+	if sms := bundleExecutableRe.FindSubmatch(data); sms != nil {
+		return string(sms[1])
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package totool
+
+// FileSize returns the size of the file path in bytes, 0 if it cannot be read.
+// The file system is asked once per path.
+func FileSize(path string) int64 {
+	fi, err := metadataOf(path).fileInfo()
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// sizeOf returns the size of the binary id, read from the file system unless
+// g records it.
+func (g *Graph) sizeOf(id int32) int64 {
+	if size := g.meta[id].size; size != 0 {
+		return size
+	}
+	return FileSize(g.paths[id])
+}
+
+// BinSize returns n.Size if known and the size of the binary file otherwise,
+// read the first time it is asked for.
+func (n *Node) BinSize() int64 {
+	if n.Size != 0 {
+		return n.Size
+	}
+	return FileSize(n.Path)
+}
+
+// ClosureSize returns the total size of bin and of the binaries it depends on,
+// directly or not.
+func (g *Graph) ClosureSize(bin string) int64 {
+	id, ok := g.lookup(bin)
+	if !ok {
+		return 0
+	}
+	seen := map[int32]bool{id: true}
+	queue := []int32{id}
+	var size int64
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		size += g.sizeOf(id)
+		for _, to := range g.deps[id] {
+			if !seen[to] {
+				seen[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	return size
+}
+
+// TotalSize returns the total size of the binaries of g.
+func (g *Graph) TotalSize() int64 {
+	var size int64
+	for _, id := range g.order {
+		size += g.sizeOf(id)
+	}
+	return size
+}
@@ -0,0 +1,67 @@
+package totool
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// An Assessment is the verdict of Gatekeeper about a binary as reported by
+// spctl.
+type Assessment struct {
+	Accepted bool
+
+	// Source is what the verdict is based on, "Notarized Developer ID" for
+	// instance.
+	Source string
+
+	// Reason is why the binary was rejected if no source is reported.
+	Reason string
+}
+
+// Assess returns whether Gatekeeper would let bin run, or be loaded if it is a
+// library, on a machine where it was downloaded, by running spctl.
+func Assess(ctx context.Context, bin string, library bool) (Assessment, error) {
+	args := []string{"--assess", "-vv", "--type", "execute"}
+	if library {
+		args = []string{"--assess", "-vv", "--type", "open", "--context", "context:primary-signature"}
+	}
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, "spctl", append(args, bin)...)
+	cmd.Stderr = &stderr
+	_, err := output(ctx, cmd)
+	if ctx.Err() != nil {
+		return Assessment{}, ctx.Err()
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return Assessment{}, toolErrorStderr("spctl", bin, stderr.String(), err)
+	}
+	a := parseSpctl(bin, stderr.String())
+	a.Accepted = err == nil && a.Accepted
+	return a, nil
+}
+
+// parseSpctl parses the output of "spctl --assess -vv" on bin:
+//
+//	/Applications/Foo.app: accepted
+//	source=Notarized Developer ID
+//	origin=Developer ID Application: Some One (TEAMID)
+func parseSpctl(bin, out string) Assessment {
+	var a Assessment
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case strings.HasPrefix(line, bin+": "):
+			verdict := strings.TrimPrefix(line, bin+": ")
+			a.Accepted = verdict == "accepted"
+			if !a.Accepted {
+				a.Reason = verdict
+			}
+		case strings.HasPrefix(line, "source="):
+			a.Source = strings.TrimPrefix(line, "source=")
+		}
+	}
+	return a
+}
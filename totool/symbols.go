@@ -0,0 +1,197 @@
+package totool
+
+import (
+	"debug/macho"
+	"sort"
+	"strings"
+)
+
+// An Import is a symbol a binary expects one of its dependencies to define.
+type Import struct {
+	Name string
+
+	// Weak is set for weak imports, which may lack a definition at run time.
+	Weak bool
+
+	// Library is the install name of the dependency expected to define the
+	// symbol, empty if any may, as in binaries using a flat namespace.
+	Library string
+
+	// DynamicLookup is set for the symbols of two-level namespace binaries
+	// looked up in all loaded libraries, as with -undefined dynamic_lookup,
+	// and Executable for those bound to the main executable.
+	DynamicLookup bool
+	Executable    bool
+}
+
+// Special library ordinals of two-level namespace binaries.
+const (
+	dynamicLookupOrdinal = 0xfe
+	executableOrdinal    = 0xff
+)
+
+// A Namespace tells how dyld looks up the undefined symbols of a binary.
+type Namespace struct {
+	// TwoLevel is set when symbols are bound to specific libraries rather
+	// than looked up in all loaded libraries, in a flat namespace.
+	TwoLevel bool
+
+	// ForceFlat is set when the binary forces a flat namespace on all
+	// binaries loaded along with it.
+	ForceFlat bool
+}
+
+// String describes ns.
+func (ns Namespace) String() string {
+	s := "flat namespace"
+	if ns.TwoLevel {
+		s = "two-level namespace"
+	}
+	if ns.ForceFlat {
+		s += ", forcing a flat namespace"
+	}
+	return s
+}
+
+// NamespaceOf returns the namespace of bin, from its first architecture.
+func NamespaceOf(bin string) (Namespace, error) {
+	var ns Namespace
+	first := true
+	err := forEachArch(bin, func(f *macho.File) {
+		if first {
+			first = false
+			ns = Namespace{TwoLevel: f.Flags&macho.FlagTwoLevel != 0, ForceFlat: f.Flags&macho.FlagForceFlat != 0}
+		}
+	})
+	return ns, err
+}
+
+// Bits of the type and description of symbol table entries.
+const (
+	symStab    = 0xe0
+	symPrivExt = 0x10
+	symExt     = 0x01
+	symType    = 0x0e
+	symUndef   = 0x00
+	symAbs     = 0x02
+	symIndir   = 0x0a
+	symSect    = 0x0e
+	symWeakRef = 0x0040
+)
+
+// Imports returns the symbols bin leaves undefined, from all its architectures,
+// sorted by name.  The library expected to define each symbol is the one of
+// the first architecture importing it.
+func Imports(bin string) ([]Import, error) {
+	byName := make(map[string]*Import)
+	err := forEachArch(bin, func(f *macho.File) {
+		if f.Symtab == nil {
+			return
+		}
+		libs := dylibNames(f)
+		for _, s := range f.Symtab.Syms {
+			// Undefined symbols with a value are common symbols, which
+			// the binary allocates itself.
+			if s.Type&symStab != 0 || s.Type&symExt == 0 || s.Type&symType != symUndef || s.Value != 0 {
+				continue
+			}
+			weak := s.Desc&symWeakRef != 0
+			if imp, ok := byName[s.Name]; ok {
+				imp.Weak = imp.Weak && weak
+				continue
+			}
+			imp := &Import{Name: s.Name, Weak: weak}
+			// Two-level namespace binaries record the 1-based index of
+			// the library in the high byte of the description.
+			if ord := int(s.Desc >> 8); f.Flags&macho.FlagTwoLevel != 0 {
+				switch {
+				case ord >= 1 && ord <= len(libs):
+					imp.Library = libs[ord-1]
+				case ord == dynamicLookupOrdinal:
+					imp.DynamicLookup = true
+				case ord == executableOrdinal:
+					imp.Executable = true
+				}
+			}
+			byName[s.Name] = imp
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	imports := make([]Import, 0, len(byName))
+	for _, imp := range byName {
+		imports = append(imports, *imp)
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].Name < imports[j].Name })
+	return imports, nil
+}
+
+// dylibNames returns the install names of the libraries f loads, in the order
+// symbol library ordinals refer to them.
+func dylibNames(f *macho.File) []string {
+	var names []string
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 24 {
+			continue
+		}
+		switch macho.LoadCmd(f.ByteOrder.Uint32(raw)) {
+		case macho.LoadCmdDylib, loadCmdLoadWeakDylib, loadCmdReexportDylib, loadCmdLazyLoadDylib, loadCmdUpwardDylib:
+		default:
+			continue
+		}
+		name := ""
+		if off := f.ByteOrder.Uint32(raw[8:]); int(off) < len(raw) {
+			name = string(raw[off:])
+			if i := strings.IndexByte(name, 0); i >= 0 {
+				name = name[:i]
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Exports returns the set of external symbols bin defines, from all its
+// architectures.
+func Exports(bin string) (map[string]bool, error) {
+	m := metadataOf(bin)
+	m.exportsOnce.Do(func() {
+		exports := make(map[string]bool)
+		m.exportsErr = forEachArch(bin, func(f *macho.File) {
+			if f.Symtab == nil {
+				return
+			}
+			for _, s := range f.Symtab.Syms {
+				if s.Type&symStab != 0 || s.Type&symExt == 0 || s.Type&symPrivExt != 0 {
+					continue
+				}
+				switch s.Type & symType {
+				case symSect, symAbs, symIndir:
+					exports[s.Name] = true
+				}
+			}
+		})
+		m.exports = exports
+	})
+	return m.exports, m.exportsErr
+}
+
+// forEachArch calls fn with each architecture of bin.
+func forEachArch(bin string, fn func(f *macho.File)) error {
+	if ff, err := macho.OpenFat(bin); err == nil {
+		defer ff.Close()
+		for _, a := range ff.Arches {
+			fn(a.File)
+		}
+		return nil
+	}
+	f, err := macho.Open(bin)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fn(f)
+	return nil
+}
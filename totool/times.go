@@ -0,0 +1,67 @@
+package totool
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// times accumulates the time spent in each phase of walks, in nanoseconds.
+var times struct {
+	exec, parse int64
+}
+
+// addTime adds the time elapsed since start to the phase counter d.
+func addTime(d *int64, start time.Time) {
+	atomic.AddInt64(d, int64(time.Since(start)))
+}
+
+// Times returns the time spent so far running otool and parsing its output.
+// As binaries are inspected concurrently, it may exceed the elapsed time.
+func Times() (exec, parse time.Duration) {
+	return time.Duration(atomic.LoadInt64(&times.exec)), time.Duration(atomic.LoadInt64(&times.parse))
+}
+
+// counts counts the binaries each backend inspected and the subprocesses run,
+// by name.
+var counts struct {
+	mu                 sync.Mutex
+	backends, commands map[string]int64
+}
+
+// countBackend records that the backend name inspected a binary.
+func countBackend(name string) {
+	counts.mu.Lock()
+	defer counts.mu.Unlock()
+	if counts.backends == nil {
+		counts.backends = make(map[string]int64)
+	}
+	counts.backends[name]++
+}
+
+// countCommand records that the command path was run.
+func countCommand(path string) {
+	counts.mu.Lock()
+	defer counts.mu.Unlock()
+	if counts.commands == nil {
+		counts.commands = make(map[string]int64)
+	}
+	counts.commands[filepath.Base(path)]++
+}
+
+// Counts returns how many binaries each backend inspected so far, otool
+// counting those whose output the disk cache held, also counted as cache, and
+// how many subprocesses ran, by command name.
+func Counts() (backends, commands map[string]int64) {
+	counts.mu.Lock()
+	defer counts.mu.Unlock()
+	backends, commands = make(map[string]int64), make(map[string]int64)
+	for name, n := range counts.backends {
+		backends[name] = n
+	}
+	for name, n := range counts.commands {
+		commands[name] = n
+	}
+	return backends, commands
+}
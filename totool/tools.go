@@ -0,0 +1,70 @@
+package totool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// DeveloperTools lists the tools of Xcode the package runs, which SetTool and
+// SetToolchain can pin.
+var DeveloperTools = []string{"otool", "dyld_info", "install_name_tool", "codesign"}
+
+// toolPaths maps the names of DeveloperTools to the commands run in their
+// place.
+var toolPaths = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// SetTool makes the package run path in place of the developer tool name.
+func SetTool(name, path string) {
+	toolPaths.Lock()
+	defer toolPaths.Unlock()
+	toolPaths.m[name] = path
+}
+
+// tool returns the command to run for the developer tool name.
+func tool(name string) string {
+	toolPaths.Lock()
+	defer toolPaths.Unlock()
+	if p, ok := toolPaths.m[name]; ok {
+		return p
+	}
+	return name
+}
+
+// SetToolchain makes the package run the developer tools xcrun finds in
+// toolchain, either the directory of an Xcode install or of the command line
+// tools, used as DEVELOPER_DIR, or a toolchain identifier or name as accepted
+// by xcrun --toolchain.  Tools the toolchain lacks are run from PATH, but otool.
+func SetToolchain(ctx context.Context, toolchain string) error {
+	args := []string{"--toolchain", toolchain}
+	var env []string
+	if fi, err := os.Stat(toolchain); err == nil && fi.IsDir() {
+		args, env = nil, append(os.Environ(), "DEVELOPER_DIR="+toolchain)
+	}
+	for _, name := range DeveloperTools {
+		cmd := exec.CommandContext(ctx, "xcrun", append(args, "--find", name)...)
+		cmd.Env = env
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if name == "otool" {
+				if msg := strings.TrimSpace(stderr.String()); msg != "" {
+					return fmt.Errorf("toolchain %s: %s", toolchain, msg)
+				}
+				return fmt.Errorf("toolchain %s: xcrun: %v", toolchain, err)
+			}
+			debugf("toolchain %s: no %s, running the one in PATH", toolchain, name)
+			continue
+		}
+		SetTool(name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package totool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionsRe matches the versions in the additional data otool prints about a dependency.
+//
+//	(compatibility version 1.0.0, current version 228.0.0, upward)
+var versionsRe = regexp.MustCompile(`compatibility version ([0-9.]+), current version ([0-9.]+)`)
+
+// ParseVersions extracts the compatibility and current versions from info.
+func ParseVersions(info string) (compat, current string, ok bool) {
+	sms := versionsRe.FindStringSubmatch(info)
+	if sms == nil {
+		return "", "", false
+	}
+	return sms[1], sms[2], true
+}
+
+// CompareVersions compares dotted versions a and b.  It returns a negative
+// number if a < b, zero if they are equal and a positive number otherwise.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// VersionConflicts describes binaries of g whose dependents require
+// incompatible versions of them.
+func (g *Graph) VersionConflicts() []string {
+	type requirement struct{ from, compat, current string }
+	reqs := make(map[string][]requirement)
+	var tos []string
+	for _, f := range g.order {
+		from := g.paths[f]
+		for i, t := range g.deps[f] {
+			compat, current, ok := ParseVersions(g.infos[f][i])
+			if !ok {
+				continue
+			}
+			to := g.paths[t]
+			if len(reqs[to]) == 0 {
+				tos = append(tos, to)
+			}
+			reqs[to] = append(reqs[to], requirement{from, compat, current})
+		}
+	}
+
+	var conflicts []string
+	for _, to := range tos {
+		first := reqs[to][0]
+		for _, r := range reqs[to][1:] {
+			if CompareVersions(r.compat, first.compat) != 0 {
+				conflicts = append(conflicts, fmt.Sprintf(
+					"%s: compatibility version %s required via %s but %s via %s",
+					to, first.compat, g.ChainString(first.from, to),
+					r.compat, g.ChainString(r.from, to)))
+				break
+			}
+		}
+
+		_, installed, ok := ParseVersions(g.SelfInfo(to))
+		if !ok {
+			continue
+		}
+		for _, r := range reqs[to] {
+			// Lower than the compatibility version is reported by
+			// VersionMismatches.
+			if CompareVersions(installed, r.current) < 0 && CompareVersions(installed, r.compat) >= 0 {
+				conflicts = append(conflicts, fmt.Sprintf(
+					"%s: installed current version %s is lower than %s required via %s",
+					to, installed, r.current, g.ChainString(r.from, to)))
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// A VersionMismatch is a dependency whose installed current version is lower
+// than the compatibility version its dependent was linked against, which the
+// dynamic loader refuses to load.
+type VersionMismatch struct {
+	From, To string
+
+	// Compat is the compatibility version From requires.
+	Compat string
+
+	// Installed is the current version of To.
+	Installed string
+}
+
+func (m VersionMismatch) String() string {
+	return fmt.Sprintf("installed current version %s is lower than compatibility version %s required by %s", m.Installed, m.Compat, m.From)
+}
+
+// VersionMismatches returns the dependencies of g whose installed version does
+// not satisfy the compatibility version of their dependents, in walk order.
+func (g *Graph) VersionMismatches() []VersionMismatch {
+	var mismatches []VersionMismatch
+	for _, f := range g.order {
+		for i, t := range g.deps[f] {
+			compat, _, ok := ParseVersions(g.infos[f][i])
+			if !ok {
+				continue
+			}
+			_, installed, ok := ParseVersions(g.self[t])
+			if ok && CompareVersions(installed, compat) < 0 {
+				mismatches = append(mismatches, VersionMismatch{From: g.paths[f], To: g.paths[t], Compat: compat, Installed: installed})
+			}
+		}
+	}
+	return mismatches
+}
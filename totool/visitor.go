@@ -0,0 +1,61 @@
+package totool
+
+import "context"
+
+// A Visitor holds the functions a walk calls as it discovers binaries.  Nil
+// functions are not called.
+type Visitor struct {
+	// OnNode is called when walking into a new binary, roots included.
+	OnNode func(d *Dependency)
+
+	// OnEdge is called for each direct dependency between from and to.
+	OnEdge func(from, to string)
+
+	// OnError is called when bin cannot be inspected.  The walk goes on
+	// without the dependencies of bin if it returns nil and stops with the
+	// error it returns otherwise.  Without OnError, walks stop on the first
+	// error.
+	OnError func(bin string, err error) error
+}
+
+// Visit traverses the graph of dependencies of the root binary in breadth-first
+// order, calls v for each one and returns the graph it discovered.
+func Visit(root string, v *Visitor, opts Options) (*Graph, error) {
+	return VisitContext(context.Background(), root, v, opts)
+}
+
+// VisitContext is like Visit but stops when ctx is done, returning the graph
+// discovered so far along with the error of ctx.
+func VisitContext(ctx context.Context, root string, v *Visitor, opts Options) (*Graph, error) {
+	w := NewWalker(visitorPrinter{v}, opts)
+	if v.OnError != nil {
+		w.onError = v.OnError
+	}
+	err := w.WalkContext(ctx, root)
+	return w.g, err
+}
+
+// visitorPrinter forwards printer calls to a visitor.
+type visitorPrinter struct{ v *Visitor }
+
+func (p visitorPrinter) PrintPrologue() {}
+
+func (p visitorPrinter) PrintEpilogue() {}
+
+func (p visitorPrinter) PrintRootBin(bin string) {
+	if p.v.OnNode != nil {
+		p.v.OnNode(&Dependency{Bin: bin, Origin: Classify(bin, bin)})
+	}
+}
+
+func (p visitorPrinter) PrintDepBin(d *Dependency) {
+	if p.v.OnNode != nil {
+		p.v.OnNode(d)
+	}
+}
+
+func (p visitorPrinter) PrintDep(from, to string) {
+	if p.v.OnEdge != nil {
+		p.v.OnEdge(from, to)
+	}
+}
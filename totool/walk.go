@@ -0,0 +1,376 @@
+// Package totool walks the direct and transitive dependencies of macOS mach-o
+// binaries as reported by "otool -L".
+package totool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Dependency stores a single dependency found by otool.
+type Dependency struct {
+	// path to binary
+	Bin string
+
+	// path to binary as recorded in the dependent binary, before resolving
+	// @rpath and the like
+	Name string
+
+	// additional data (versions...)
+	Info string
+
+	// length of the shortest dependency chain from the root
+	Depth int
+
+	// binary that first brought this one in during the walk
+	Parent string
+
+	// set when the walk stopped before expanding this binary
+	Truncated bool
+
+	// where the binary comes from
+	Origin Origin
+
+	// size of the binary file in bytes, 0 when unknown or not read yet, as
+	// walks leave it to BinSize
+	Size int64
+}
+
+// BinSize returns d.Size if known and the size of the binary file otherwise,
+// read the first time it is asked for.
+func (d *Dependency) BinSize() int64 {
+	if d.Size != 0 {
+		return d.Size
+	}
+	return FileSize(d.Bin)
+}
+
+// A Printer is notified of the binaries and dependencies found during a walk.
+type Printer interface {
+	// PrintPrologue is called before walking the dependency graph.
+	PrintPrologue()
+
+	// PrintRootBin is called to print the binary we want to print dependencies of.
+	PrintRootBin(bin string)
+
+	// PrintDepBin is called when walking into a new binary.
+	PrintDepBin(d *Dependency)
+
+	// PrintDep is called to print a direct dependency between from and to binaries.
+	PrintDep(from, to string)
+
+	// PrintEpilogue is called after walking all nodes in the dependency graph.
+	PrintEpilogue()
+}
+
+// nopPrinter is the printer of walks only interested in the resulting graph.
+type nopPrinter struct{}
+
+func (nopPrinter) PrintPrologue()            {}
+func (nopPrinter) PrintRootBin(bin string)   {}
+func (nopPrinter) PrintDepBin(d *Dependency) {}
+func (nopPrinter) PrintDep(from, to string)  {}
+func (nopPrinter) PrintEpilogue()            {}
+
+// Walk traverses the graph of dependencies of the root binary in breadth-first
+// order, calls printer for each one and returns the graph it discovered.  pt
+// may be nil when only the graph is needed.
+func Walk(root string, pt Printer, opts Options) (*Graph, error) {
+	return WalkContext(context.Background(), root, pt, opts)
+}
+
+// WalkContext is like Walk but stops when ctx is done, returning the graph
+// discovered so far along with the error of ctx.  pt is notified of the end of
+// the walk in all cases.
+func WalkContext(ctx context.Context, root string, pt Printer, opts Options) (*Graph, error) {
+	w := NewWalker(pt, opts)
+	w.pt.PrintPrologue()
+	defer w.pt.PrintEpilogue()
+
+	err := w.WalkContext(ctx, root)
+	return w.g, err
+}
+
+// Options configures how dependency graphs are walked.
+type Options struct {
+	// MaxNodes bounds how many binaries are expanded, 0 meaning no limit.
+	MaxNodes int
+
+	// MaxDepth bounds the depth of expanded binaries, 0 meaning no limit.
+	MaxDepth int
+
+	// Filter leaves out of the graph the dependencies for which it returns
+	// false, if not nil.  Roots are always kept.
+	Filter func(d *Dependency) bool
+
+	// Jobs bounds how many binaries are inspected concurrently.
+	Jobs int
+
+	// Leaves lists binaries not to expand, as accepted by MatchesLeaf.
+	Leaves []string
+
+	// Resolver finds the binaries dependencies refer to, a DyldResolver
+	// without search paths if nil.
+	Resolver Resolver
+
+	// Inspector finds the dependencies of binaries, an OtoolInspector if nil.
+	Inspector Inspector
+
+	// KeepGoing makes walks go on without the dependencies of the binaries
+	// that cannot be inspected, recording the errors in the graph, rather
+	// than stop on the first one.
+	KeepGoing bool
+
+	// Progress is called, if not nil, before inspecting each batch of
+	// binaries with how many binaries were inspected so far, how many wait
+	// to be and the first binary of the batch.  Calls are serialized.
+	Progress func(inspected, queued int, current string)
+}
+
+// A Walker accumulates the dependency graphs of one or more roots into a
+// single graph, inspecting each binary only once.
+type Walker struct {
+	pt   Printer
+	opts Options
+	g    *Graph
+
+	// expanded counts binaries whose dependencies were inspected.
+	expanded int
+
+	// inspected counts the binaries expanded whose inspection is over, for
+	// Options.Progress.
+	inspected  int
+	progressMu sync.Mutex
+
+	// onError decides whether the walk goes on when a binary cannot be
+	// inspected, as Visitor.OnError.
+	onError func(bin string, err error) error
+
+	// stopped lists the binaries left unexpanded by canceled walks, which
+	// walks of later roots expand.
+	stopped map[string]bool
+}
+
+// NewWalker returns a walker notifying pt, if not nil, of what it finds.
+func NewWalker(pt Printer, opts Options) *Walker {
+	if pt == nil {
+		pt = nopPrinter{}
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = &DyldResolver{}
+	}
+	if opts.Inspector == nil {
+		opts.Inspector = OtoolInspector{}
+	}
+	w := &Walker{pt: pt, opts: opts, g: NewGraph(), onError: stopOnError}
+	if opts.KeepGoing {
+		w.onError = w.recordError
+	}
+	return w
+}
+
+// recordError records err in the graph of w and lets the walk go on.
+func (w *Walker) recordError(bin string, err error) error {
+	w.g.SetError(bin, err)
+	return nil
+}
+
+// stopOnError stops walks on the first error, reported as a BinError.
+func stopOnError(bin string, err error) error {
+	return &BinError{Bin: bin, Err: err}
+}
+
+// Graph returns the graph accumulated so far.
+func (w *Walker) Graph() *Graph {
+	return w.g
+}
+
+// Walk traverses the dependencies of root not visited yet in breadth-first
+// order and calls printer for each one.  The binaries at a given depth are
+// inspected concurrently.
+func (w *Walker) Walk(root string) error {
+	return w.WalkContext(context.Background(), root)
+}
+
+// WalkContext is like Walk but stops when ctx is done, leaving in the graph of
+// w what was discovered so far.
+func (w *Walker) WalkContext(ctx context.Context, root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("cannot get %q absolute path: %v", root, err)
+	}
+
+	w.g.AddRoot(root)
+
+	toVisit := []Dependency{{Bin: root}}
+
+	for len(toVisit) > 0 {
+		var toExpand []Dependency
+		for _, from := range toVisit {
+			from.Origin = Classify(root, from.Bin)
+			from.Truncated = (w.opts.MaxNodes > 0 && w.expanded >= w.opts.MaxNodes) ||
+				(w.opts.MaxDepth > 0 && from.Depth >= w.opts.MaxDepth)
+			if !w.g.AddNode(Node{
+				Path:      from.Bin,
+				Depth:     from.Depth,
+				Parent:    from.Parent,
+				Name:      from.Name,
+				Origin:    from.Origin,
+				Truncated: from.Truncated,
+			}) {
+				if w.stopped[from.Bin] && !from.Truncated {
+					delete(w.stopped, from.Bin)
+					w.g.untruncate(from.Bin)
+					w.expanded++
+					toExpand = append(toExpand, from)
+				}
+				continue
+			}
+			if from.Bin == root {
+				w.pt.PrintRootBin(root)
+			} else {
+				w.pt.PrintDepBin(&from)
+			}
+			if !from.Truncated && (from.Bin == root || !MatchesLeaf(from.Bin, w.opts.Leaves)) {
+				w.expanded++
+				toExpand = append(toExpand, from)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			w.stop(toExpand, nil)
+			return err
+		}
+		results := w.inspect(ctx, toExpand)
+
+		toVisit = nil
+		for i, from := range toExpand {
+			r := &results[i]
+			for _, warning := range r.warnings {
+				w.g.AddWarning(from.Bin, warning)
+			}
+			if r.err != nil {
+				if ctx.Err() != nil {
+					w.stop(toExpand[i:], toVisit)
+					return ctx.Err()
+				}
+				// Absent weak dependencies are fine.
+				if IsWeak(from.Info) && errors.Is(r.err, ErrMissingFile) {
+					continue
+				}
+				if err := w.onError(from.Bin, r.err); err != nil {
+					return err
+				}
+				continue
+			}
+			deps, id := resolveDeps(w.opts.Resolver, from.Bin, root, r.deps)
+			w.g.SetID(from.Bin, id)
+			for _, to := range deps {
+				to.Depth = from.Depth + 1
+				to.Parent = from.Bin
+				to.Origin = Classify(root, to.Bin)
+				if w.opts.Filter != nil && !w.opts.Filter(&to) {
+					continue
+				}
+				w.g.AddNamedDep(from.Bin, to.Bin, to.Name, to.Info)
+				w.pt.PrintDep(from.Bin, to.Bin)
+				toVisit = append(toVisit, to)
+			}
+		}
+	}
+
+	return nil
+}
+
+// stop records that the walk stopped before expanding pending, binaries in
+// the graph, and queued, dependencies found but not added to it yet.
+func (w *Walker) stop(pending, queued []Dependency) {
+	if w.stopped == nil {
+		w.stopped = make(map[string]bool)
+	}
+	for _, d := range pending {
+		w.g.Truncate(d.Bin)
+		w.stopped[d.Bin] = true
+	}
+	for _, d := range queued {
+		d.Truncated = true
+		if w.g.AddNode(Node{Path: d.Bin, Depth: d.Depth, Parent: d.Parent, Name: d.Name, Origin: d.Origin, Truncated: true}) {
+			w.pt.PrintDepBin(&d)
+			w.stopped[d.Bin] = true
+		}
+	}
+}
+
+// inspection is the outcome of inspecting a binary.  Dependencies are as
+// recorded in the binary, before resolving their paths.
+type inspection struct {
+	deps []Dependency
+	err  error
+
+	// warnings are what the backend printed about the binary.
+	warnings []string
+}
+
+// maxBatch bounds how many binaries are inspected together.
+const maxBatch = 32
+
+// progress records that n more binaries were inspected and reports progress
+// before inspecting current, if not empty.
+func (w *Walker) progress(n int, current string) {
+	if w.opts.Progress == nil {
+		return
+	}
+	w.progressMu.Lock()
+	defer w.progressMu.Unlock()
+	w.inspected += n
+	if current != "" {
+		w.opts.Progress(w.inspected, w.expanded-w.inspected, current)
+	}
+}
+
+// inspect finds the direct dependencies of bins.  Binaries are split into
+// batches inspected together, by a single otool run each for instance, running
+// up to opts.Jobs batches concurrently.
+func (w *Walker) inspect(ctx context.Context, bins []Dependency) []inspection {
+	results := make([]inspection, len(bins))
+	jobs := w.opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	size := (len(bins) + jobs - 1) / jobs
+	if size > maxBatch {
+		size = maxBatch
+	}
+
+	batches := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < jobs; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range batches {
+				end := start + size
+				if end > len(bins) {
+					end = len(bins)
+				}
+				paths := make([]string, 0, end-start)
+				for _, d := range bins[start:end] {
+					paths = append(paths, d.Bin)
+				}
+				w.progress(0, paths[0])
+				copy(results[start:end], inspectBatch(ctx, w.opts.Inspector, paths))
+				w.progress(len(paths), "")
+			}
+		}()
+	}
+	for start := 0; start < len(bins); start += size {
+		batches <- start
+	}
+	close(batches)
+	wg.Wait()
+
+	return results
+}
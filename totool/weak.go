@@ -0,0 +1,10 @@
+package totool
+
+// IsWeak reports whether info, the additional data of a dependency, marks it
+// as weak.  The dynamic loader leaves the symbols of absent weak dependencies
+// NULL instead of failing.
+//
+//	(compatibility version 1.0.0, current version 1.0.0, weak)
+func IsWeak(info string) bool {
+	return ParseInfo(info).Weak
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printUndefined prints, for each root of g, its undefined symbols along with
+// the libraries of its closure that define them, r finding the libraries
+// symbols are bound to.  Symbols bound to a library are looked up in it and
+// in the libraries it depends on, which it may re-export.  Libraries that
+// cannot be read, system libraries living in the dyld shared cache only for
+// instance, are not checked.  It returns how many symbols no library defines
+// that are not imported weakly.
+func printUndefined(g *totool.Graph, r totool.Resolver) int {
+	fmt.Printf("%s:\n", g.Name())
+	n := 0
+	for _, root := range g.Roots() {
+		imports, err := totool.Imports(root)
+		if err != nil {
+			log.Printf("%s: %v", root, err)
+			continue
+		}
+		fmt.Printf("\t%s:\n", root)
+		closure := closureOf(g, root)[1:]
+		for _, imp := range imports {
+			libs := closure
+			if imp.Library != "" {
+				lib := r.Resolve(imp.Library, root, root)
+				libs = []string{lib}
+				if _, ok := g.Node(lib); ok {
+					libs = closureOf(g, lib)
+				}
+			}
+			by, unchecked := definedBy(imp.Name, libs)
+			switch {
+			case len(by) > 0:
+				fmt.Printf("\t\t%s: %s\n", imp.Name, strings.Join(by, ", "))
+			case len(unchecked) > 0 && (imp.Library == "" || unchecked[0] == libs[0]):
+				fmt.Printf("\t\t%s: not checked, cannot read %s\n", imp.Name, unchecked[0])
+			case imp.Weak:
+				fmt.Printf("\t\t%s: not defined, imported weakly\n", imp.Name)
+			default:
+				n++
+				if imp.Library != "" {
+					fmt.Printf("\t\t%s: not defined by %s\n", imp.Name, libs[0])
+				} else {
+					fmt.Printf("\t\t%s: not defined\n", imp.Name)
+				}
+			}
+		}
+	}
+	return n
+}
+
+// definedBy returns the libraries among libs that define sym, and those that
+// could not be read.
+func definedBy(sym string, libs []string) (by, unchecked []string) {
+	for _, lib := range libs {
+		exports, err := totool.Exports(lib)
+		if err != nil {
+			unchecked = append(unchecked, lib)
+			continue
+		}
+		if exports[sym] {
+			by = append(by, lib)
+		}
+	}
+	return by, unchecked
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/nthery/totool/totool"
+)
+
+// Verbosity levels, set by -q, -v and -vv.
+const (
+	levelQuiet   = iota // errors only
+	levelNormal         // warnings about the graph too
+	levelVerbose        // versions and other info about each binary too
+	levelDebug          // resolution decisions and backend diagnostics too
+)
+
+// verbosity returns the level selected by -q, -v and -vv.
+func verbosity(quiet, verbose, debug bool) (int, error) {
+	switch {
+	case quiet && (verbose || debug):
+		return 0, errors.New("-q is incompatible with -v and -vv")
+	case quiet:
+		return levelQuiet, nil
+	case debug:
+		return levelDebug, nil
+	case verbose:
+		return levelVerbose, nil
+	default:
+		return levelNormal, nil
+	}
+}
+
+// debugResolver logs how the resolver it wraps resolves each dependency.
+type debugResolver struct {
+	totool.Resolver
+}
+
+func (r debugResolver) Resolve(path, loader, executable string) string {
+	p := r.Resolver.Resolve(path, loader, executable)
+	switch {
+	case p == loader:
+		// The binary itself, not a dependency.
+	case p == path && !fileExists(p):
+		logAt(logDebug, "", loader, "%s: %s left unresolved", loader, path)
+	case p == path:
+		logAt(logDebug, "", loader, "%s: %s used as is", loader, path)
+	default:
+		logAt(logDebug, "", loader, "%s: %s resolved to %s", loader, path, p)
+	}
+	return p
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// mntLocal is the statfs flag of file systems stored locally.
+const mntLocal = 0x1000
+
+// volumeHazard tells when path lives on a network volume, an external volume
+// or a disk image, all of which may be unmounted when it is loaded.
+func volumeHazard(path string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", false
+	}
+	fstype, mnt := cString(st.Fstypename[:]), cString(st.Mntonname[:])
+	switch {
+	case st.Flags&mntLocal == 0:
+		return fmt.Sprintf("on %s network volume %s", fstype, mnt), true
+	case strings.HasPrefix(mnt, "/Volumes/"):
+		return fmt.Sprintf("on external volume or disk image %s", mnt), true
+	}
+	return "", false
+}
+
+// cString returns the NUL-terminated string s.
+func cString(s []int8) string {
+	b := make([]byte, 0, len(s))
+	for _, c := range s {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
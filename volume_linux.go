@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// networkFS maps the statfs magic numbers of network file systems to their
+// names.
+var networkFS = map[int64]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xfe534d42: "smb2",
+	0xff534d42: "cifs",
+	0x5346414f: "afs",
+	0x00c36400: "ceph",
+	0x01021997: "9p",
+}
+
+// removablePrefixes are where Linux desktops mount removable and external
+// disks.
+var removablePrefixes = []string{"/media/", "/run/media/"}
+
+// volumeHazard tells when path lives on a network volume or an external
+// volume, which may be unmounted when it is loaded.
+func volumeHazard(path string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", false
+	}
+	if fs, ok := networkFS[int64(st.Type)]; ok {
+		return fmt.Sprintf("on %s network volume", fs), true
+	}
+	for _, prefix := range removablePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return "on external volume " + prefix + strings.SplitN(strings.TrimPrefix(path, prefix), "/", 2)[0], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package main
+
+// volumeHazard tells when path lives on a volume which may be unmounted when
+// it is loaded, which cannot be told on this system.
+func volumeHazard(path string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printVulns prints the known vulnerabilities of the binaries of g that belong
+// to packages it recognizes, as reported by c.  It returns how many it found.
+func printVulns(ctx context.Context, g *totool.Graph, c *totool.OSVClient) int {
+	fmt.Printf("%s:\n", g.Name())
+	n := 0
+	known := 0
+	queried := make(map[totool.Package][]totool.Vulnerability)
+	for _, bin := range g.Bins() {
+		p, err := totool.HomebrewFormula(ctx, bin)
+		if err != nil {
+			log.Print(err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if p.Version == "" {
+			continue
+		}
+		known++
+		vulns, done := queried[p]
+		if !done {
+			if vulns, err = c.Query(ctx, p); err != nil {
+				log.Printf("%s: %v", p, err)
+				if ctx.Err() != nil {
+					break
+				}
+			}
+			queried[p] = vulns
+		}
+		for _, v := range vulns {
+			n++
+			line := v.ID
+			if len(v.Aliases) > 0 {
+				line += " (" + strings.Join(v.Aliases, ", ") + ")"
+			}
+			if v.Summary != "" {
+				line += ": " + v.Summary
+			}
+			fmt.Printf("\t%s (%s): %s\n", bin, p, line)
+		}
+	}
+	if n == 0 {
+		fmt.Printf("\tno known vulnerabilities in %d recognized binaries\n", known)
+	}
+	return n
+}
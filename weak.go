@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nthery/totool/totool"
+)
+
+// printWeak prints the weak dependencies of g telling those present on this
+// system from those absent, whose symbols the dynamic loader leaves NULL.
+// Missing system libraries may live in the dyld shared cache, which is not
+// looked into.
+func printWeak(g *totool.Graph) {
+	fmt.Printf("%s:\n", g.Name())
+	n, absent := 0, 0
+	for _, e := range g.Edges() {
+		if !totool.IsWeak(e.Info) {
+			continue
+		}
+		n++
+		state := "present"
+		if _, err := os.Stat(e.To); err != nil {
+			if totool.IsSystemBin(e.To) {
+				state = "not on disk, maybe in the dyld shared cache"
+			} else {
+				absent++
+				state = "absent"
+			}
+		}
+		fmt.Printf("\t%s -> %s: %s\n", e.From, e.To, state)
+	}
+	if n == 0 {
+		fmt.Printf("\tno weak dependencies\n")
+	} else {
+		fmt.Printf("\t%d of %d weak dependencies absent\n", absent, n)
+	}
+}
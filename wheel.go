@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nthery/totool/totool"
+)
+
+// wheelMain implements the wheel subcommand, walking the native extension
+// modules of Python wheels and environments, such as site-packages
+// directories, and printing the libraries each one needs outside the system.
+// Libraries outside the wheel or directory, which delocate and auditwheel
+// would have copied into it, and missing ones are problems.
+func wheelMain(ctx context.Context, args []string, opts totool.Options) error {
+	if len(args) == 0 {
+		return usagef("usage: totool wheel file.whl|site-packages...")
+	}
+	opts.KeepGoing = true
+	var total int
+	var first error
+	for _, arg := range args {
+		dir := arg
+		if strings.EqualFold(filepath.Ext(arg), ".whl") {
+			tmp, err := ioutil.TempDir("", "totool-wheel")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmp)
+			if err := extractZip(arg, tmp); err != nil {
+				return fmt.Errorf("%s: %v", arg, err)
+			}
+			dir = tmp
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		mods, err := extensionModules(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", arg, err)
+		}
+		rel := func(bin string) string {
+			if r, err := filepath.Rel(dir, bin); err == nil && !strings.HasPrefix(r, "..") {
+				return r
+			}
+			return bin
+		}
+
+		fmt.Printf("%s:\n", arg)
+		var escaping, missing int
+		for _, mod := range mods {
+			g, err := totool.WalkContext(ctx, mod, nullPrinter{}, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %v", rel(mod), err)
+			}
+			fmt.Printf("\t%s:\n", rel(mod))
+			var problems []error
+			for _, bin := range g.Bins() {
+				if g.IsRoot(bin) || totool.IsSystemBin(bin) {
+					continue
+				}
+				var note string
+				switch err := g.Err(bin); {
+				case errors.Is(err, totool.ErrMissingFile):
+					note = " (missing)"
+					missing++
+					problems = append(problems, fmt.Errorf("%s: %w", bin, err))
+				case !strings.HasPrefix(bin, dir+"/"):
+					note = " (outside the wheel)"
+					escaping++
+					problems = append(problems, fmt.Errorf("%s: outside the wheel, needed by %s", bin, rel(g.Dependents(bin)[0])))
+				}
+				fmt.Printf("\t\t%s%s\n", rel(bin), note)
+			}
+			if len(problems) > 0 && first == nil {
+				first = problems[0]
+			}
+			total += len(problems)
+		}
+		fmt.Printf("\t%s, %s outside the wheel, %d missing\n",
+			plural(len(mods), "extension module", "extension modules"),
+			plural(escaping, "library", "libraries"), missing)
+	}
+	if total > 0 {
+		return checkError{fmt.Errorf("%d problems found: %w", total, first)}
+	}
+	return nil
+}
+
+// extensionModules returns the native extension modules below dir, the .so
+// binaries Python imports, leaving out the libraries delocate and auditwheel
+// vendor in .dylibs and .libs directories.
+func extensionModules(dir string) ([]string, error) {
+	var mods []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := fi.Name()
+		if fi.IsDir() && path != dir && (name == "__pycache__" || name == ".dylibs" || strings.HasSuffix(name, ".libs")) {
+			return filepath.SkipDir
+		}
+		if fi.Mode().IsRegular() && strings.HasSuffix(name, ".so") {
+			if _, err := totool.DetectFormat(path); err == nil {
+				mods = append(mods, path)
+			}
+		}
+		return nil
+	})
+	sort.Strings(mods)
+	return mods, err
+}